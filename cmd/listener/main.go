@@ -5,17 +5,31 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"syscall"
 
 	libapp "github.com/KyberNetwork/evmlistener/internal/app"
+	"github.com/KyberNetwork/evmlistener/pkg/admin"
+	"github.com/KyberNetwork/evmlistener/pkg/errors"
+	"github.com/KyberNetwork/evmlistener/pkg/health"
+	pkglistener "github.com/KyberNetwork/evmlistener/pkg/listener"
 	_ "github.com/KyberNetwork/kyber-trace-go/tools"
 	"github.com/urfave/cli/v2"
 	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
 )
 
 func main() {
 	app := libapp.NewApp()
 	app.Name = "EVM compatible listener service"
 	app.Action = run
+	app.Commands = []*cli.Command{
+		{
+			Name:   "backfill",
+			Usage:  "Publish blocks of a past range to a topic, progress is saved so that it can be resumed",
+			Flags:  libapp.NewBackfillFlags(),
+			Action: backfill,
+		},
+	}
 
 	if err := app.Run(os.Args); err != nil {
 		panic(err)
@@ -23,7 +37,7 @@ func main() {
 }
 
 func run(c *cli.Context) error {
-	logger, _, flush, err := libapp.NewLogger(c)
+	logger, level, flush, err := libapp.NewLogger(c)
 	if err != nil {
 		return fmt.Errorf("new logger: %w", err)
 	}
@@ -35,15 +49,100 @@ func run(c *cli.Context) error {
 	l.Infow("App starting ..")
 	defer l.Infow("App stopped!")
 
-	listener, err := libapp.NewListener(c)
+	var listener interface {
+		health.Checker
+		Run(ctx context.Context) error
+		Close() error
+	}
+	var listeners admin.Listeners
+	if libapp.IsMultiChain(c) {
+		var group *pkglistener.Group
+		group, err = libapp.NewListenerGroup(c)
+		listener, listeners = group, group
+	} else {
+		var single *pkglistener.Listener
+		single, err = libapp.NewListener(c)
+		listener, listeners = single, admin.SingleListener(single)
+	}
 	if err != nil {
 		l.Errorw("Fail to setup Listener service", "error", err)
 
 		return err
 	}
 
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	adminServer, err := libapp.NewAdminServer(c, listeners, level)
+	if err != nil {
+		l.Errorw("Fail to setup admin api", "error", err)
+
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	return listener.Run(ctx)
+	// Servers are stopped with the listener, and the listener is stopped when a server fails,
+	// e.g. to bind its address.
+	g, ctx := errgroup.WithContext(ctx)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if server := libapp.NewHealthServer(c, listener); server != nil {
+		g.Go(func() error {
+			return server.Run(ctx)
+		})
+	}
+
+	if adminServer != nil {
+		g.Go(func() error {
+			return adminServer.Run(ctx)
+		})
+	}
+
+	g.Go(func() error {
+		defer cancel()
+
+		return listener.Run(ctx)
+	})
+
+	err = g.Wait()
+	if closeErr := listener.Close(); closeErr != nil {
+		l.Errorw("Fail to close listener", "error", closeErr)
+	}
+
+	var listenerErr *pkglistener.Error
+	switch {
+	case errors.Is(err, pkglistener.ErrShutdownTimeout):
+		l.Errorw("Listener stopped before publishing all fetched blocks", "error", err)
+	case errors.As(err, &listenerErr):
+		l.Errorw("Listener stopped", "stage", listenerErr.Stage, "error", listenerErr.Err)
+	}
+
+	return err
+}
+
+func backfill(c *cli.Context) error {
+	logger, _, flush, err := libapp.NewLogger(c)
+	if err != nil {
+		return fmt.Errorf("new logger: %w", err)
+	}
+
+	defer flush()
+
+	zap.ReplaceGlobals(logger)
+	l := logger.Sugar()
+	l.Infow("Backfill starting ..")
+	defer l.Infow("Backfill stopped!")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	c.Context = ctx
+
+	backfiller, err := libapp.NewBackfiller(c)
+	if err != nil {
+		l.Errorw("Fail to setup Backfiller", "error", err)
+
+		return err
+	}
+
+	return backfiller.Run(ctx)
 }
@@ -2,20 +2,32 @@ package app
 
 import (
 	"context"
+	"fmt"
 	"net/http"
-	"strconv"
+	"net/url"
+	"os"
 	"time"
 
+	"github.com/KyberNetwork/evmlistener/pkg/admin"
 	"github.com/KyberNetwork/evmlistener/pkg/block"
+	"github.com/KyberNetwork/evmlistener/pkg/chain"
+	"github.com/KyberNetwork/evmlistener/pkg/errors"
 	"github.com/KyberNetwork/evmlistener/pkg/evmclient"
+	"github.com/KyberNetwork/evmlistener/pkg/health"
+	"github.com/KyberNetwork/evmlistener/pkg/labels"
 	"github.com/KyberNetwork/evmlistener/pkg/listener"
+	"github.com/KyberNetwork/evmlistener/pkg/pubsub"
 	"github.com/KyberNetwork/evmlistener/pkg/redis"
+	"github.com/KyberNetwork/evmlistener/pkg/retry"
+	"github.com/KyberNetwork/evmlistener/pkg/spool"
 	"github.com/urfave/cli/v2"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 )
 
 const (
 	defaultRequestTimeout = 10 * time.Second
+	leaderLeaseKey        = "leader-lease"
 )
 
 // NewApp creates a new cli App instance with common flags pre-loaded.
@@ -47,66 +59,528 @@ func redisConfigFromCli(c *cli.Context) redis.Config {
 	return cfg
 }
 
+func chainRegistryFromCli(c *cli.Context) (*chain.Registry, error) {
+	path := c.String(chainConfigFlag.Name)
+	if path == "" {
+		return chain.DefaultRegistry(), nil
+	}
+
+	return chain.LoadRegistry(path)
+}
+
+// rpcName returns name of a rpc for logging, which does not include path or credentials.
+func rpcName(rpc string) string {
+	u, err := url.Parse(rpc)
+	if err != nil || u.Host == "" {
+		return "***"
+	}
+
+	return u.Scheme + "://" + u.Host
+}
+
+// rpcConfig is a rpc to connect to, with its authentication.
+type rpcConfig struct {
+	url  string
+	auth evmclient.Auth
+}
+
+// rpcConfigs returns rpcs along with authentications given by specs, the spec at index i applies to
+// the rpc at index i.
+func rpcConfigs(rpcs []string, authSpecs []string) ([]rpcConfig, error) {
+	if len(authSpecs) > len(rpcs) {
+		return nil, fmt.Errorf("%w: %d auth specs are given for %d rpcs",
+			errors.ErrInvalidArgument, len(authSpecs), len(rpcs))
+	}
+
+	res := make([]rpcConfig, 0, len(rpcs))
+	for i, rpc := range rpcs {
+		cfg := rpcConfig{url: rpc}
+		if i < len(authSpecs) {
+			auth, err := evmclient.ParseAuth(authSpecs[i])
+			if err != nil {
+				return nil, fmt.Errorf("auth of rpc %s: %w", rpcName(rpc), err)
+			}
+			cfg.auth = auth
+		}
+		res = append(res, cfg)
+	}
+
+	return res, nil
+}
+
+// websocketRPCs returns the rpcs using websocket scheme, http rpcs can not be used for subscriptions.
+func websocketRPCs(rpcs []rpcConfig) []rpcConfig {
+	res := make([]rpcConfig, 0, len(rpcs))
+	for _, rpc := range rpcs {
+		u, err := url.Parse(rpc.url)
+		if err == nil && (u.Scheme == "ws" || u.Scheme == "wss") {
+			res = append(res, rpc)
+		}
+	}
+
+	return res
+}
+
+// dialEndpoints connects to given rpcs, skipping the ones that can not be connected.
+// It returns the connected endpoints along with the first connected client for getting chain information.
+func dialEndpoints(
+	l *zap.SugaredLogger, rpcs []rpcConfig, httpClient *http.Client, opts ...evmclient.Option,
+) ([]evmclient.Endpoint, *evmclient.Client, error) {
+	var first *evmclient.Client
+	var lastErr error
+	endpoints := make([]evmclient.Endpoint, 0, len(rpcs))
+	for _, rpc := range rpcs {
+		name := rpcName(rpc.url)
+		l.Infow("Connect to node rpc", "rpc", name, "auth", rpc.auth.Masked())
+		client, err := evmclient.DialContextWithTimeout(context.Background(), rpc.url, httpClient,
+			defaultRequestTimeout, append(opts, evmclient.WithAuth(rpc.auth))...)
+		if err != nil {
+			l.Errorw("Fail to connect to node", "rpc", name, "error", err)
+			lastErr = err
+
+			continue
+		}
+
+		if first == nil {
+			first = client
+		}
+
+		instrumented, err := evmclient.NewInstrumentedClient(client, name)
+		if err != nil {
+			l.Errorw("Fail to setup instrumentation for node rpc", "rpc", name, "error", err)
+
+			return nil, nil, err
+		}
+		endpoints = append(endpoints, evmclient.Endpoint{Name: name, Client: instrumented})
+	}
+
+	if len(endpoints) == 0 {
+		if lastErr == nil {
+			lastErr = fmt.Errorf("%w: no rpc was configured", errors.ErrInvalidArgument)
+		}
+
+		return nil, nil, lastErr
+	}
+
+	return endpoints, first, nil
+}
+
+// chainLabel returns the name of the chain to label logs and metrics with.
+func chainLabel(c *cli.Context, profile chain.Profile) string {
+	if name := c.String(chainNameFlag.Name); name != "" {
+		return name
+	}
+
+	return profile.Name
+}
+
+// withChainLabel returns the chain label along with l labeled with it, unless it is given by chain-name
+// in which case l was already labeled.
+func withChainLabel(
+	c *cli.Context, l *zap.SugaredLogger, profile chain.Profile,
+) (*zap.SugaredLogger, string) {
+	name := chainLabel(c, profile)
+	if c.String(chainNameFlag.Name) == "" {
+		l = l.With("chainName", name)
+	}
+
+	return l, name
+}
+
+// newEVMClient returns an EVM client over given endpoints: the endpoint itself if there is only one,
+// a QuorumClient if quorum is greater than 1, otherwise a FailoverClient which is run with ctx.
+func newEVMClient(
+	ctx context.Context, l *zap.SugaredLogger, endpoints []evmclient.Endpoint, quorum int,
+) (evmclient.IClient, error) {
+	if quorum > 1 {
+		l.Infow("Setup quorum client", "numEndpoints", len(endpoints), "quorum", quorum)
+
+		return evmclient.NewQuorumClient(l, endpoints, quorum)
+	}
+
+	if len(endpoints) == 1 {
+		return endpoints[0].Client, nil
+	}
+
+	l.Infow("Setup failover client", "numEndpoints", len(endpoints))
+	client, err := evmclient.NewFailoverClient(l, endpoints)
+	if err != nil {
+		return nil, err
+	}
+
+	go client.Run(ctx)
+
+	return client, nil
+}
+
+// nodeHTTPEVMClient connects to node http rpcs, it returns the EVM client for requests
+// along with the chain profile of the node.
+func nodeHTTPEVMClient(
+	c *cli.Context, l *zap.SugaredLogger, registry *chain.Registry, httpClient *http.Client,
+) (evmclient.IClient, chain.Profile, error) {
+	httpRPCs, err := rpcConfigs(c.StringSlice(httpRPCFlag.Name), c.StringSlice(httpRPCAuthFlag.Name))
+	if err != nil {
+		l.Errorw("Fail to parse http rpc auth", "error", err)
+
+		return nil, chain.Profile{}, err
+	}
+
+	l.Infow("Connect to node http rpc")
+	httpEndpoints, client, err := dialEndpoints(l, httpRPCs, httpClient, evmclient.WithChainRegistry(registry))
+	if err != nil {
+		l.Errorw("Fail to connect to http rpc", "error", err)
+
+		return nil, chain.Profile{}, err
+	}
+
+	profile := client.Profile()
+	l, name := withChainLabel(c, l, profile)
+
+	quorum := profile.Quorum
+	if c.IsSet(rpcQuorumFlag.Name) {
+		quorum = c.Int(rpcQuorumFlag.Name)
+	}
+	httpEVMClient, err := newEVMClient(labels.WithChain(context.Background(), name), l, httpEndpoints, quorum)
+	if err != nil {
+		l.Errorw("Fail to setup http EVM client", "error", err)
+
+		return nil, chain.Profile{}, err
+	}
+
+	return httpEVMClient, profile, nil
+}
+
+// nodeEVMClients connects to node rpcs, it returns the EVM clients for subscribing to new heads
+// and for other requests, along with the chain profile of the node.
+func nodeEVMClients(
+	c *cli.Context, l *zap.SugaredLogger, registry *chain.Registry, httpClient *http.Client,
+) (evmclient.IClient, evmclient.IClient, chain.Profile, error) {
+	wsRPCs, err := rpcConfigs(c.StringSlice(wsRPCFlag.Name), c.StringSlice(wsRPCAuthFlag.Name))
+	if err != nil {
+		l.Errorw("Fail to parse websocket rpc auth", "error", err)
+
+		return nil, nil, chain.Profile{}, err
+	}
+	wsRPCs = websocketRPCs(wsRPCs)
+
+	httpEVMClient, profile, err := nodeHTTPEVMClient(c, l, registry, httpClient)
+	if err != nil {
+		return nil, nil, chain.Profile{}, err
+	}
+	l, name := withChainLabel(c, l, profile)
+
+	if len(wsRPCs) == 0 {
+		l.Infow("No websocket rpc configured, poll new heads from http rpc", "blockTime", profile.BlockTime)
+
+		return evmclient.NewPollingClient(l, httpEVMClient, profile.BlockTime), httpEVMClient, profile, nil
+	}
+
+	l.Infow("Connect to node websocket rpc")
+	wsEndpoints, _, err := dialEndpoints(l, wsRPCs, httpClient, evmclient.WithChainRegistry(registry))
+	if err != nil {
+		l.Errorw("Fail to connect to websocket rpc", "error", err)
+
+		return nil, nil, chain.Profile{}, err
+	}
+
+	wsEVMClient, err := newEVMClient(labels.WithChain(context.Background(), name), l, wsEndpoints, 0)
+	if err != nil {
+		l.Errorw("Fail to setup websocket EVM client", "error", err)
+
+		return nil, nil, chain.Profile{}, err
+	}
+
+	if c.Bool(wsRaceFlag.Name) && len(wsEndpoints) > 1 {
+		l.Infow("Race new head subscriptions on websocket rpcs", "numEndpoints", len(wsEndpoints))
+		wsEVMClient, err = evmclient.NewRaceClient(l, wsEVMClient, wsEndpoints)
+		if err != nil {
+			l.Errorw("Fail to setup racing websocket EVM client", "error", err)
+
+			return nil, nil, chain.Profile{}, err
+		}
+	}
+
+	return wsEVMClient, httpEVMClient, profile, nil
+}
+
+// replayEVMClients returns a replay client serving recorded node rpc for both subscriptions and requests.
+func replayEVMClients(
+	c *cli.Context, l *zap.SugaredLogger, registry *chain.Registry,
+) (evmclient.IClient, evmclient.IClient, chain.Profile, error) {
+	path := c.String(rpcReplayFileFlag.Name)
+	client, err := evmclient.LoadReplayClient(path, c.Float64(rpcReplaySpeedFlag.Name))
+	if err != nil {
+		l.Errorw("Fail to load rpc records", "path", path, "error", err)
+
+		return nil, nil, chain.Profile{}, err
+	}
+
+	if client.ChainID() == nil {
+		err = fmt.Errorf("%w: chain id was not recorded in %s", errors.ErrInvalidArgument, path)
+		l.Errorw("Fail to load rpc records", "path", path, "error", err)
+
+		return nil, nil, chain.Profile{}, err
+	}
+
+	return client, client, registry.Get(client.ChainID().Uint64()), nil
+}
+
+// recordEVMClients wraps given clients to record their requests and responses to a file.
+func recordEVMClients(
+	l *zap.SugaredLogger, path string, chainID uint64, wsEVMClient, httpEVMClient evmclient.IClient,
+) (evmclient.IClient, evmclient.IClient, error) {
+	l.Infow("Record node rpc to file", "path", path)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600) //nolint:gomnd
+	if err != nil {
+		l.Errorw("Fail to open rpc record file", "path", path, "error", err)
+
+		return nil, nil, err
+	}
+
+	recorder := evmclient.NewRecorder(f)
+	if err = recorder.Record(evmclient.RecordChainID, 0, nil, chainID, nil); err != nil {
+		l.Errorw("Fail to record chain id", "path", path, "error", err)
+
+		return nil, nil, err
+	}
+
+	return evmclient.NewRecordingClient(wsEVMClient, recorder),
+		evmclient.NewRecordingClient(httpEVMClient, recorder), nil
+}
+
+// listenerOptions returns options for fetching blocks from flags and the chain profile.
+// evmClient is the client for requests, it must serve block receipts for receipts verification.
+func listenerOptions(
+	c *cli.Context, l *zap.SugaredLogger, profile chain.Profile, evmClient evmclient.IClient,
+) ([]listener.Option, error) {
+	logVerification, err := listener.ParseLogVerification(c.String(logVerificationFlag.Name))
+	if err != nil {
+		l.Errorw("Fail to parse log verification mode", "error", err)
+
+		return nil, err
+	}
+	if logVerification == listener.LogVerificationReceipts && !profile.Methods.GetBlockReceipts {
+		l.Warnw("Chain does not support block receipts, verify logs against logs bloom only")
+		logVerification = listener.LogVerificationBloom
+	}
+	if _, ok := evmClient.(evmclient.ReceiptsClient); logVerification == listener.LogVerificationReceipts && !ok {
+		err = fmt.Errorf("%w: rpc client can not serve block receipts for log verification %s",
+			errors.ErrInvalidArgument, logVerification)
+		l.Errorw("Fail to setup log verification", "error", err)
+
+		return nil, err
+	}
+
+	return []listener.Option{
+		listener.WithEventLogs(nil, nil),
+		listener.WithLogRetry(profile.LogRetry.MaxAttempts, profile.LogRetry.RetryOnEmpty),
+		listener.WithBatchSize(c.Int(rpcBatchSizeFlag.Name)),
+		listener.WithLogRange(c.Uint64(rpcLogRangeFlag.Name)),
+		listener.WithCatchUpWindow(c.Int(catchUpWindowFlag.Name)),
+		listener.WithLogVerification(logVerification),
+		listener.WithRetryPolicy(retry.Policy{
+			MaxAttempts:     c.Int(retryMaxAttemptsFlag.Name),
+			InitialInterval: c.Duration(retryInitialIntervalFlag.Name),
+			MaxInterval:     c.Duration(retryMaxIntervalFlag.Name),
+			Multiplier:      2, //nolint:gomnd
+			Jitter:          c.Float64(retryJitterFlag.Name),
+		}),
+		listener.WithRestartPolicy(listener.RestartPolicy{
+			MaxRestarts: c.Int(restartMaxFlag.Name),
+			Window:      c.Duration(restartWindowFlag.Name),
+		}),
+		listener.WithReadinessPolicy(listener.ReadinessPolicy{
+			MaxLag:  c.Uint64(readyMaxLagFlag.Name),
+			MaxIdle: c.Duration(readyMaxIdleFlag.Name),
+		}),
+	}, nil
+}
+
+// startOptions returns the option for the block to start from on a fresh deployment, if any.
+func startOptions(c *cli.Context) ([]listener.Option, error) {
+	var opts []listener.Option
+	if c.IsSet(startBlockFlag.Name) {
+		opts = append(opts, listener.WithStartBlock(c.Uint64(startBlockFlag.Name)))
+	}
+	if c.IsSet(startBlockHashFlag.Name) {
+		opts = append(opts, listener.WithStartBlockHash(c.String(startBlockHashFlag.Name)))
+	}
+	if c.IsSet(startTimeFlag.Name) {
+		opts = append(opts, listener.WithStartTime(c.Uint64(startTimeFlag.Name)))
+	}
+
+	if len(opts) > 1 {
+		return nil, fmt.Errorf("%w: only one of %s, %s and %s can be set", errors.ErrInvalidArgument,
+			startBlockFlag.Name, startBlockHashFlag.Name, startTimeFlag.Name)
+	}
+
+	return opts, nil
+}
+
+// newRedisClient connects to redis, passwords are masked in logs.
+func newRedisClient(c *cli.Context, l *zap.SugaredLogger) (*redis.Client, error) {
+	redisConfig := redisConfigFromCli(c)
+	redisConfigForLog := redisConfig
+	redisConfigForLog.SentinelPassword = "***"
+	redisConfigForLog.Password = "***"
+	l.Infow("Connect to redis", "cfg", redisConfigForLog)
+	redisClient, err := redis.New(redisConfig)
+	if err != nil {
+		l.Errorw("Fail to connect to redis", "cfg", redisConfigForLog, "error", err)
+
+		return nil, err
+	}
+
+	return redisClient, nil
+}
+
+func newLease(c *cli.Context, l *zap.SugaredLogger, client *redis.Client) (*redis.Lease, error) {
+	id := c.String(leaderIDFlag.Name)
+	if id == "" {
+		var err error
+		id, err = os.Hostname()
+		if err != nil {
+			l.Errorw("Fail to get hostname for leader id", "error", err)
+
+			return nil, err
+		}
+	}
+
+	ttl := c.Duration(leaderLeaseTTLFlag.Name)
+	l.Infow("Setup leader election", "id", id, "ttl", ttl)
+
+	return redis.NewLease(client, leaderLeaseKey, id, ttl), nil
+}
+
+// NewHealthServer returns the server of health endpoints for checker, nil if health-addr is empty.
+func NewHealthServer(c *cli.Context, checker health.Checker) *health.Server {
+	addr := c.String(healthAddrFlag.Name)
+	if addr == "" {
+		return nil
+	}
+
+	return health.NewServer(zap.S(), addr, checker)
+}
+
+// NewAdminServer returns the server of the admin api for listeners, nil if admin-addr is empty.
+func NewAdminServer(
+	c *cli.Context, listeners admin.Listeners, level zap.AtomicLevel,
+) (*admin.Server, error) {
+	addr := c.String(adminAddrFlag.Name)
+	if addr == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	return admin.NewServer(zap.S(), addr, c.String(adminTokenFlag.Name), listeners, level)
+}
+
 // NewListener setups and returns listener service.
 func NewListener(c *cli.Context) (*listener.Listener, error) {
 	l := zap.S()
+	if name := c.String(chainNameFlag.Name); name != "" {
+		l = l.With("chainName", name)
+	}
 
-	httpClient := &http.Client{
-		Timeout: defaultRequestTimeout,
+	redisClient, err := newRedisClient(c, l)
+	if err != nil {
+		return nil, err
+	}
+
+	return newListener(c, l, redisClient)
+}
+
+// newListener setups a listener of the chain configured by c, storing blocks and publishing messages
+// with redisClient. Given options are applied after the ones from flags.
+//
+//nolint:funlen,cyclop
+func newListener(
+	c *cli.Context, l *zap.SugaredLogger, redisClient *redis.Client, extraOpts ...listener.Option,
+) (*listener.Listener, error) {
+	topic := c.String(publisherTopicFlag.Name)
+	if topic == "" {
+		return nil, fmt.Errorf("%w: %s is required", errors.ErrInvalidArgument, publisherTopicFlag.Name)
 	}
-	wsRPC := c.String(wsRPCFlag.Name)
-	l.Infow("Connect to node websocket rpc", "rpc", wsRPC)
-	wsEVMClient, err := evmclient.DialContextWithTimeout(
-		context.Background(), wsRPC, httpClient, defaultRequestTimeout)
+
+	registry, err := chainRegistryFromCli(c)
 	if err != nil {
-		l.Errorw("Fail to connect to node", "rpc", wsRPC, "error", err)
+		l.Errorw("Fail to load chain profiles", "path", c.String(chainConfigFlag.Name), "error", err)
 
 		return nil, err
 	}
 
-	httpRPC := c.String(httpRPCFlag.Name)
-	l.Infow("Connect to node http rpc", "rpc", httpRPC)
-	httpEVMClient, err := evmclient.DialContextWithTimeout(
-		context.Background(), httpRPC, httpClient, defaultRequestTimeout)
+	httpClient := &http.Client{
+		Timeout: defaultRequestTimeout,
+	}
+	var wsEVMClient, httpEVMClient evmclient.IClient
+	var profile chain.Profile
+	if replayFile := c.String(rpcReplayFileFlag.Name); replayFile != "" {
+		l.Infow("Replay node rpc from file", "path", replayFile)
+		wsEVMClient, httpEVMClient, profile, err = replayEVMClients(c, l, registry)
+	} else {
+		wsEVMClient, httpEVMClient, profile, err = nodeEVMClients(c, l, registry, httpClient)
+	}
 	if err != nil {
-		l.Errorw("Fail to connect to node", "rpc", httpRPC, "error", err)
+		return nil, err
+	}
+
+	l, name := withChainLabel(c, l, profile)
+	l.Infow("Use chain profile", "profile", profile)
+
+	if recordFile := c.String(rpcRecordFileFlag.Name); recordFile != "" {
+		wsEVMClient, httpEVMClient, err = recordEVMClients(l, recordFile, profile.ChainID, wsEVMClient, httpEVMClient)
+		if err != nil {
+			return nil, err
+		}
+	}
 
+	opts, err := listenerOptions(c, l, profile, httpEVMClient)
+	if err != nil {
 		return nil, err
 	}
 
-	l.Infow("Get chainID from node")
-	chainID, err := httpEVMClient.ChainID(context.Background())
+	startOpts, err := startOptions(c)
 	if err != nil {
-		l.Errorw("Fail to get chainID", "error", err)
+		l.Errorw("Fail to parse start block", "error", err)
 
 		return nil, err
 	}
-
-	l = l.With("chainName", chainIDToName(chainID.Int64()))
+	opts = append(opts, startOpts...)
+	opts = append(opts, listener.WithShutdownTimeout(c.Duration(shutdownTimeoutFlag.Name)),
+		listener.WithChainName(name))
+	opts = append(opts, extraOpts...)
 
 	sanityCheckInterval := c.Duration(sanityCheckIntervalFlag.Name)
+	if !c.IsSet(sanityCheckIntervalFlag.Name) && profile.SanityCheckInterval > 0 {
+		sanityCheckInterval = profile.SanityCheckInterval
+	}
 	var sanityEVMClient evmclient.IClient
 	sanityRPC := c.String(sanityNodeRPCFlag.Name)
 	if sanityRPC != "" {
-		l.Infow("Connect to public node rpc for sanity check", "rpc", sanityRPC)
-		sanityEVMClient, err = evmclient.DialContext(context.Background(), sanityRPC, httpClient)
+		var sanityAuth evmclient.Auth
+		sanityAuth, err = evmclient.ParseAuth(c.String(sanityNodeRPCAuthFlag.Name))
+		if err != nil {
+			l.Errorw("Fail to parse sanity check rpc auth", "error", err)
+
+			return nil, err
+		}
+
+		l.Infow("Connect to public node rpc for sanity check", "rpc", rpcName(sanityRPC),
+			"auth", sanityAuth.Masked())
+		var client *evmclient.Client
+		client, err = evmclient.DialContext(context.Background(), sanityRPC, httpClient,
+			evmclient.WithChainRegistry(registry), evmclient.WithAuth(sanityAuth))
 		if err != nil {
 			l.Errorw("Fail to setup EVM client for sanity check", "error", err)
 
 			return nil, err
 		}
-	}
 
-	redisConfig := redisConfigFromCli(c)
-	redisConfigForLog := redisConfig
-	redisConfigForLog.SentinelPassword = "***"
-	redisConfigForLog.Password = "***"
-	l.Infow("Connect to redis", "cfg", redisConfigForLog)
-	redisClient, err := redis.New(redisConfig)
-	if err != nil {
-		l.Errorw("Fail to connect to redis", "cfg", redisConfigForLog, "error", err)
+		sanityEVMClient, err = evmclient.NewInstrumentedClient(client, rpcName(sanityRPC))
+		if err != nil {
+			l.Errorw("Fail to setup instrumentation for sanity check rpc", "error", err)
 
-		return nil, err
+			return nil, err
+		}
 	}
 
 	maxNumBlocks := c.Int(maxNumBlocksFlag.Name)
@@ -118,72 +592,48 @@ func NewListener(c *cli.Context) (*listener.Listener, error) {
 	l.Infow("Setup redis stream", "maxLen", maxLen)
 	redisStream := redis.NewStream(redisClient, maxLen)
 
-	topic := c.String(publisherTopicFlag.Name)
-	l.Infow("Setup handler", "topic", topic)
-	handler := listener.NewHandler(l, topic, httpEVMClient, blockKeeper, redisStream,
-		listener.WithEventLogs(nil, nil))
+	if c.Bool(leaderElectionFlag.Name) {
+		// Spooled messages would be published after the lease was taken over.
+		if c.String(publisherSpoolDirFlag.Name) != "" {
+			return nil, fmt.Errorf("%w: publisher spool can not be used with leader election",
+				errors.ErrInvalidArgument)
+		}
+		// Fenced writes access the lease and the written key in a single script, which a cluster
+		// rejects when the keys are on different slots.
+		if redisClient.IsCluster() {
+			return nil, fmt.Errorf("%w: leader election can not be used with a redis cluster",
+				errors.ErrInvalidArgument)
+		}
 
-	l.Infow("Setup listener")
+		lease, err := newLease(c, l, redisClient)
+		if err != nil {
+			return nil, err
+		}
 
-	return listener.New(l, wsEVMClient, httpEVMClient, handler, sanityEVMClient, sanityCheckInterval,
-		listener.WithEventLogs(nil, nil)), nil
-}
+		blockKeeper.SetLease(lease)
+		redisStream = redis.NewFencedStream(redisClient, maxLen, lease)
+		opts = append(opts, listener.WithLeaderElection(lease))
+	}
 
-const (
-	chainIDEthereum     = 1
-	chainIDOptimism     = 10
-	chainIDCronos       = 25
-	chainIDBSC          = 56
-	chainIDVelas        = 106
-	chainIDPolygon      = 137
-	chainIDBitTorrent   = 199
-	chainIDFantom       = 250
-	chainIDZKSyncEra    = 324
-	chainIDPolygonZKEVM = 1101
-	chainIDBase         = 8453
-	chainIDArbitrum     = 42161
-	chainIDOasis        = 42262
-	chainIDAvalanche    = 43114
-	chainIDLinea        = 59144
-	chainIDAurora       = 1313161554
-)
+	var publisher pubsub.Publisher = redisStream
+	if spoolDir := c.String(publisherSpoolDirFlag.Name); spoolDir != "" {
+		maxSize := c.Int64(publisherSpoolMaxSizeFlag.Name)
+		l.Infow("Setup publisher spool", "dir", spoolDir, "maxSize", maxSize)
+		publisher, err = spool.New(l, redisStream, spoolDir, maxSize, attribute.String(labels.ChainKey, name))
+		if err != nil {
+			l.Errorw("Fail to open publisher spool", "dir", spoolDir, "error", err)
+
+			return nil, err
+		}
 
-//nolint:cyclop
-func chainIDToName(chainID int64) string {
-	switch chainID {
-	case chainIDEthereum:
-		return "Ethereum"
-	case chainIDOptimism:
-		return "Optimism"
-	case chainIDCronos:
-		return "Cronos"
-	case chainIDBSC:
-		return "BSC"
-	case chainIDVelas:
-		return "Velas"
-	case chainIDPolygon:
-		return "Polygon"
-	case chainIDBitTorrent:
-		return "BitTorrent"
-	case chainIDFantom:
-		return "Fantom"
-	case chainIDArbitrum:
-		return "Arbitrum"
-	case chainIDOasis:
-		return "Oasis"
-	case chainIDAvalanche:
-		return "Avalanche"
-	case chainIDAurora:
-		return "Aurora"
-	case chainIDLinea:
-		return "Linea"
-	case chainIDPolygonZKEVM:
-		return "Polygon zkEVM"
-	case chainIDZKSyncEra:
-		return "zkSync Era"
-	case chainIDBase:
-		return "Base"
-	default:
-		return strconv.FormatInt(chainID, 10)
+		blockKeeper.SetMemoryFallback()
 	}
+
+	l.Infow("Setup handler", "topic", topic)
+	handler := listener.NewHandler(l, topic, httpEVMClient, blockKeeper, publisher, opts...)
+
+	l.Infow("Setup listener")
+
+	return listener.New(l, wsEVMClient, httpEVMClient, handler, sanityEVMClient, sanityCheckInterval,
+		opts...), nil
 }
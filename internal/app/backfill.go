@@ -0,0 +1,174 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/KyberNetwork/evmlistener/pkg/errors"
+	"github.com/KyberNetwork/evmlistener/pkg/evmclient"
+	"github.com/KyberNetwork/evmlistener/pkg/listener"
+	"github.com/KyberNetwork/evmlistener/pkg/redis"
+	"github.com/urfave/cli/v2"
+	"go.uber.org/zap"
+)
+
+//nolint:gochecknoglobals
+var (
+	backfillFromBlockFlag = &cli.Uint64Flag{
+		Name:    "from-block",
+		EnvVars: []string{"BACKFILL_FROM_BLOCK"},
+		Usage:   "First block to backfill",
+	}
+	backfillToBlockFlag = &cli.Uint64Flag{
+		Name:    "to-block",
+		EnvVars: []string{"BACKFILL_TO_BLOCK"},
+		Usage:   "Last block to backfill",
+	}
+	backfillFromTimeFlag = &cli.Uint64Flag{
+		Name:    "from-time",
+		EnvVars: []string{"BACKFILL_FROM_TIME"},
+		Usage:   "Unix timestamp to backfill from, used if from-block is not set",
+	}
+	backfillToTimeFlag = &cli.Uint64Flag{
+		Name:    "to-time",
+		EnvVars: []string{"BACKFILL_TO_TIME"},
+		Usage:   "Unix timestamp to backfill to, used if to-block is not set",
+	}
+	backfillTopicFlag = &cli.StringFlag{
+		Name:    "topic",
+		EnvVars: []string{"BACKFILL_TOPIC"},
+		Usage:   "Topic to publish backfilled blocks to. Default: publisher-topic",
+	}
+	backfillConcurrencyFlag = &cli.IntFlag{
+		Name:    "concurrency",
+		EnvVars: []string{"BACKFILL_CONCURRENCY"},
+		Value:   4, //nolint:gomnd
+		Usage:   "Maximum number of chunks of blocks fetched at the same time. Default: 4",
+	}
+	backfillChunkSizeFlag = &cli.Uint64Flag{
+		Name:    "chunk-size",
+		EnvVars: []string{"BACKFILL_CHUNK_SIZE"},
+		Value:   32, //nolint:gomnd
+		Usage:   "Number of blocks published in a message. Default: 32",
+	}
+)
+
+// NewBackfillFlags returns flags for the backfill command.
+func NewBackfillFlags() []cli.Flag {
+	return []cli.Flag{
+		backfillFromBlockFlag, backfillToBlockFlag, backfillFromTimeFlag, backfillToTimeFlag,
+		backfillTopicFlag, backfillConcurrencyFlag, backfillChunkSizeFlag,
+	}
+}
+
+// backfillRange resolves the block range to backfill from block numbers or timestamps.
+func backfillRange(ctx context.Context, c *cli.Context, evmClient evmclient.IClient) (uint64, uint64, error) {
+	var from, to uint64
+	var err error
+
+	switch {
+	case c.IsSet(backfillFromBlockFlag.Name):
+		from = c.Uint64(backfillFromBlockFlag.Name)
+	case c.IsSet(backfillFromTimeFlag.Name):
+		from, err = listener.FindBlockByTimestamp(ctx, evmClient, c.Uint64(backfillFromTimeFlag.Name))
+		if err != nil {
+			return 0, 0, err
+		}
+	default:
+		return 0, 0, fmt.Errorf("%w: from-block or from-time is required", errors.ErrInvalidArgument)
+	}
+
+	switch {
+	case c.IsSet(backfillToBlockFlag.Name):
+		to = c.Uint64(backfillToBlockFlag.Name)
+	case c.IsSet(backfillToTimeFlag.Name):
+		// The last block is the one before the first block after the timestamp.
+		to, err = listener.FindBlockByTimestamp(ctx, evmClient, c.Uint64(backfillToTimeFlag.Name)+1)
+		switch {
+		case errors.Is(err, errors.ErrNotFound):
+			var head uint64
+			head, err = evmClient.BlockNumber(ctx)
+			if err != nil {
+				return 0, 0, err
+			}
+			to = head
+		case err != nil:
+			return 0, 0, err
+		case to == 0:
+			return 0, 0, fmt.Errorf("%w: no block before to-time", errors.ErrInvalidArgument)
+		default:
+			to--
+		}
+	default:
+		return 0, 0, fmt.Errorf("%w: to-block or to-time is required", errors.ErrInvalidArgument)
+	}
+
+	return from, to, nil
+}
+
+// NewBackfiller setups and returns backfiller for the backfill command. Blocks are fetched from
+// the http rpcs only, the block keeper of the live listener is not touched.
+func NewBackfiller(c *cli.Context) (*listener.Backfiller, error) {
+	l := zap.S()
+
+	registry, err := chainRegistryFromCli(c)
+	if err != nil {
+		l.Errorw("Fail to load chain profiles", "path", c.String(chainConfigFlag.Name), "error", err)
+
+		return nil, err
+	}
+
+	httpClient := &http.Client{
+		Timeout: defaultRequestTimeout,
+	}
+	evmClient, profile, err := nodeHTTPEVMClient(c, l, registry, httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	l = l.With("chainName", profile.Name)
+	l.Infow("Use chain profile", "profile", profile)
+
+	opts, err := listenerOptions(c, l, profile, evmClient)
+	if err != nil {
+		return nil, err
+	}
+
+	from, to, err := backfillRange(c.Context, c, evmClient)
+	if err != nil {
+		l.Errorw("Fail to resolve backfill range", "error", err)
+
+		return nil, err
+	}
+
+	redisClient, err := newRedisClient(c, l)
+	if err != nil {
+		return nil, err
+	}
+
+	topic := c.String(backfillTopicFlag.Name)
+	if topic == "" {
+		topic = c.String(publisherTopicFlag.Name)
+	}
+	if topic == "" {
+		return nil, fmt.Errorf("%w: %s or %s is required", errors.ErrInvalidArgument,
+			backfillTopicFlag.Name, publisherTopicFlag.Name)
+	}
+
+	maxLen := c.Int64(publisherMaxLenFlag.Name)
+	l.Infow("Setup redis stream", "maxLen", maxLen)
+	redisStream := redis.NewStream(redisClient, maxLen)
+
+	checkpoint := redis.NewCheckpoint(redisClient, fmt.Sprintf("backfill-checkpoint:%s:%d-%d", topic, from, to))
+
+	l.Infow("Setup backfiller", "topic", topic, "from", from, "to", to)
+
+	return listener.NewBackfiller(l, evmClient, redisStream, checkpoint, listener.BackfillConfig{
+		Topic:       topic,
+		FromBlock:   from,
+		ToBlock:     to,
+		Concurrency: c.Int(backfillConcurrencyFlag.Name),
+		ChunkSize:   c.Uint64(backfillChunkSizeFlag.Name),
+	}, opts...), nil
+}
@@ -0,0 +1,122 @@
+package app
+
+import (
+	"context"
+	"math/big"
+	"strconv"
+	"testing"
+
+	"github.com/KyberNetwork/evmlistener/pkg/errors"
+	"github.com/KyberNetwork/evmlistener/pkg/evmclient"
+	"github.com/KyberNetwork/evmlistener/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	testHeadNumber = 100
+	testGenesis    = 1000
+	testBlockTime  = 10
+)
+
+// timedClient serves a chain whose block n is mined at testGenesis + n*testBlockTime.
+type timedClient struct {
+	evmclient.IClient
+}
+
+func (c timedClient) BlockNumber(context.Context) (uint64, error) {
+	return testHeadNumber, nil
+}
+
+func (c timedClient) HeaderByNumber(_ context.Context, number *big.Int) (*types.Header, error) {
+	if number == nil {
+		number = big.NewInt(testHeadNumber)
+	}
+
+	return &types.Header{
+		Hash:   strconv.FormatUint(number.Uint64(), 16),
+		Number: number,
+		Time:   testGenesis + number.Uint64()*testBlockTime,
+	}, nil
+}
+
+func newBackfillContext(t *testing.T, args ...string) *cli.Context {
+	t.Helper()
+
+	c := newTestContext(t)
+
+	return cli.NewContext(c.App, newTestFlagSet(t, NewBackfillFlags(), args...), c)
+}
+
+func TestBackfillRange(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		from uint64
+		to   uint64
+		err  error
+	}{
+		{
+			name: "block numbers",
+			args: []string{"--from-block=10", "--to-block=20"},
+			from: 10,
+			to:   20,
+		},
+		{
+			name: "timestamps",
+			args: []string{"--from-time=1105", "--to-time=1200"},
+			from: 11,
+			to:   20,
+		},
+		{
+			name: "timestamp between blocks",
+			args: []string{"--from-block=5", "--to-time=1205"},
+			from: 5,
+			to:   20,
+		},
+		{
+			name: "to-time after the head",
+			args: []string{"--from-block=90", "--to-time=5000"},
+			from: 90,
+			to:   testHeadNumber,
+		},
+		{
+			name: "to-time before the first block",
+			args: []string{"--from-block=0", "--to-time=999"},
+			err:  errors.ErrInvalidArgument,
+		},
+		{
+			name: "from-time after the head",
+			args: []string{"--from-time=5000", "--to-block=100"},
+			err:  errors.ErrNotFound,
+		},
+		{
+			name: "missing from",
+			args: []string{"--to-block=20"},
+			err:  errors.ErrInvalidArgument,
+		},
+		{
+			name: "missing to",
+			args: []string{"--from-block=10"},
+			err:  errors.ErrInvalidArgument,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := newBackfillContext(t, test.args...)
+
+			from, to, err := backfillRange(context.Background(), c, timedClient{})
+			if test.err != nil {
+				assert.ErrorIs(t, err, test.err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.from, from)
+			assert.Equal(t, test.to, to)
+		})
+	}
+}
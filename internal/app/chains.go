@@ -0,0 +1,254 @@
+package app
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/KyberNetwork/evmlistener/pkg/errors"
+	"github.com/KyberNetwork/evmlistener/pkg/listener"
+	"github.com/KyberNetwork/evmlistener/pkg/retry"
+	"github.com/urfave/cli/v2"
+	"go.uber.org/zap"
+)
+
+// ChainConfig declares a chain listened to by a process running several chains. Settings which are not
+// declared are taken from flags, Flags overrides other flags by name for the chain, except the ones
+// shared by all chains or set by fields of the chain.
+type ChainConfig struct {
+	Name          string            `json:"name"`
+	WSRPC         []string          `json:"wsRpc"`
+	WSRPCAuth     []string          `json:"wsRpcAuth"`
+	HTTPRPC       []string          `json:"httpRpc"`
+	HTTPRPCAuth   []string          `json:"httpRpcAuth"`
+	SanityNodeRPC string            `json:"sanityNodeRpc"`
+	KeyPrefix     string            `json:"keyPrefix"`
+	Topic         string            `json:"topic"`
+	Contracts     []string          `json:"contracts"`
+	Topics        [][]string        `json:"topics"`
+	Flags         map[string]string `json:"flags"`
+}
+
+type listenersConfig struct {
+	Chains []ChainConfig `json:"chains"`
+}
+
+//nolint:gochecknoglobals
+var (
+	// sharedFlags are flags which can not be overridden for a chain, as they apply to the whole process.
+	sharedFlags = []string{
+		logLevelFlag.Name,
+		listenersConfigFlag.Name,
+		healthAddrFlag.Name,
+		adminAddrFlag.Name,
+		adminTokenFlag.Name,
+		leaderIDFlag.Name,
+		sentryDSNFlag.Name,
+		sentryLevelFlag.Name,
+		redisMasterNameFlag.Name,
+		redisAddrsFlag.Name,
+		redisDBFlag.Name,
+		redisUsernameFlag.Name,
+		redisPasswordFlag.Name,
+		redisKeyPrefixFlag.Name,
+		redisReadTimeoutFlag.Name,
+		redisWriteTimeoutFlag.Name,
+	}
+	// entryFlags are flags which are set by fields of a chain entry, they can not be overridden by flags.
+	entryFlags = []string{
+		chainNameFlag.Name,
+		publisherTopicFlag.Name,
+		wsRPCFlag.Name,
+		wsRPCAuthFlag.Name,
+		httpRPCFlag.Name,
+		httpRPCAuthFlag.Name,
+		sanityNodeRPCFlag.Name,
+	}
+)
+
+// loadChainConfigs loads chains from a JSON file, names and key prefixes of chains must be unique.
+// Unknown fields and flags which can not be overridden for a chain are rejected.
+//
+//nolint:cyclop
+func loadChainConfigs(path string, keyPrefix string) ([]ChainConfig, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var cfg listenersConfig
+	dec := json.NewDecoder(file)
+	dec.DisallowUnknownFields()
+	if err = dec.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("%w: %w", errors.ErrInvalidArgument, err)
+	}
+
+	if len(cfg.Chains) == 0 {
+		return nil, fmt.Errorf("%w: no chain is declared", errors.ErrInvalidArgument)
+	}
+
+	names := make(map[string]bool)
+	keyPrefixes := make(map[string]string)
+	for i := range cfg.Chains {
+		chain := &cfg.Chains[i]
+		if chain.Name == "" {
+			return nil, fmt.Errorf("%w: name of chain at index %d is empty", errors.ErrInvalidArgument, i)
+		}
+		if names[chain.Name] {
+			return nil, fmt.Errorf("%w: chain %s is declared twice", errors.ErrInvalidArgument, chain.Name)
+		}
+		names[chain.Name] = true
+
+		if chain.Topic == "" {
+			return nil, fmt.Errorf("%w: topic of chain %s is empty", errors.ErrInvalidArgument, chain.Name)
+		}
+
+		if chain.KeyPrefix == "" {
+			chain.KeyPrefix = keyPrefix + chain.Name + ":"
+		}
+		if other, ok := keyPrefixes[chain.KeyPrefix]; ok {
+			return nil, fmt.Errorf("%w: chains %s and %s use the same key prefix %s",
+				errors.ErrInvalidArgument, other, chain.Name, chain.KeyPrefix)
+		}
+		keyPrefixes[chain.KeyPrefix] = chain.Name
+
+		for name := range chain.Flags {
+			if slices.Contains(sharedFlags, name) {
+				return nil, fmt.Errorf("%w: flag %s of chain %s is shared by all chains",
+					errors.ErrInvalidArgument, name, chain.Name)
+			}
+			if slices.Contains(entryFlags, name) {
+				return nil, fmt.Errorf("%w: flag %s of chain %s is set by a field of the chain",
+					errors.ErrInvalidArgument, name, chain.Name)
+			}
+		}
+	}
+
+	return cfg.Chains, nil
+}
+
+// flagValues returns values of flags overridden for the chain, a nil value keeps the flag as is.
+func (cfg ChainConfig) flagValues() map[string][]string {
+	values := make(map[string][]string)
+	for name, value := range cfg.Flags {
+		values[name] = []string{value}
+	}
+
+	for name, value := range map[string][]string{
+		wsRPCFlag.Name:       cfg.WSRPC,
+		wsRPCAuthFlag.Name:   cfg.WSRPCAuth,
+		httpRPCFlag.Name:     cfg.HTTPRPC,
+		httpRPCAuthFlag.Name: cfg.HTTPRPCAuth,
+	} {
+		if value != nil {
+			values[name] = value
+		}
+	}
+
+	if cfg.SanityNodeRPC != "" {
+		values[sanityNodeRPCFlag.Name] = []string{cfg.SanityNodeRPC}
+	}
+	values[chainNameFlag.Name] = []string{cfg.Name}
+	values[publisherTopicFlag.Name] = []string{cfg.Topic}
+
+	return values
+}
+
+// chainContext returns a child context of c with flags overridden for the chain. The spool of the chain,
+// if any, is put in a sub directory named after the chain.
+func chainContext(c *cli.Context, cfg ChainConfig) (*cli.Context, error) {
+	values := cfg.flagValues()
+	if _, ok := values[publisherSpoolDirFlag.Name]; !ok {
+		if dir := c.String(publisherSpoolDirFlag.Name); dir != "" {
+			values[publisherSpoolDirFlag.Name] = []string{filepath.Join(dir, cfg.Name)}
+		}
+	}
+
+	set := flag.NewFlagSet(cfg.Name, flag.ContinueOnError)
+	for name, value := range values {
+		f := findFlag(c.App.Flags, name)
+		if f == nil {
+			return nil, fmt.Errorf("%w: unknown flag %s of chain %s", errors.ErrInvalidArgument, name, cfg.Name)
+		}
+
+		if _, ok := f.(*cli.StringSliceFlag); ok {
+			set.Var(&cli.StringSlice{}, name, "")
+		} else {
+			set.String(name, "", "")
+		}
+
+		for _, v := range value {
+			if err := set.Set(name, v); err != nil {
+				return nil, fmt.Errorf("%w: flag %s of chain %s: %w", errors.ErrInvalidArgument, name, cfg.Name, err)
+			}
+		}
+	}
+
+	return cli.NewContext(c.App, set, c), nil
+}
+
+func findFlag(flags []cli.Flag, name string) cli.Flag {
+	for _, f := range flags {
+		if slices.Contains(f.Names(), name) {
+			return f
+		}
+	}
+
+	return nil
+}
+
+// IsMultiChain returns whether chains to listen to are declared by listeners-config.
+func IsMultiChain(c *cli.Context) bool {
+	return c.String(listenersConfigFlag.Name) != ""
+}
+
+// NewListenerGroup setups listeners of chains declared in the file given by listeners-config. Listeners
+// share the redis connection pool and the logger, logs and metrics are labeled by chain.
+func NewListenerGroup(c *cli.Context) (*listener.Group, error) {
+	l := zap.S()
+
+	path := c.String(listenersConfigFlag.Name)
+	chains, err := loadChainConfigs(path, c.String(redisKeyPrefixFlag.Name))
+	if err != nil {
+		l.Errorw("Fail to load listeners config", "path", path, "error", err)
+
+		return nil, err
+	}
+
+	redisClient, err := newRedisClient(c, l)
+	if err != nil {
+		return nil, err
+	}
+
+	group := listener.NewGroup(l, retry.Policy{
+		InitialInterval: c.Duration(retryInitialIntervalFlag.Name),
+		MaxInterval:     c.Duration(retryMaxIntervalFlag.Name),
+		Multiplier:      2, //nolint:gomnd
+		Jitter:          c.Float64(retryJitterFlag.Name),
+	})
+	for _, chain := range chains {
+		chainCtx, err := chainContext(c, chain)
+		if err != nil {
+			l.Errorw("Fail to parse flags of chain", "chainName", chain.Name, "error", err)
+
+			return nil, err
+		}
+
+		chainRedisClient := redisClient.WithKeyPrefix(chain.KeyPrefix)
+		var opts []listener.Option
+		if len(chain.Contracts) > 0 || len(chain.Topics) > 0 {
+			opts = append(opts, listener.WithEventLogs(chain.Contracts, chain.Topics))
+		}
+
+		l.Infow("Add chain listener", "chainName", chain.Name, "keyPrefix", chain.KeyPrefix, "topic", chain.Topic)
+		group.Add(chain.Name, func() (*listener.Listener, error) {
+			return newListener(chainCtx, l.With("chainName", chain.Name), chainRedisClient, opts...)
+		})
+	}
+
+	return group, nil
+}
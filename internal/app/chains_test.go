@@ -0,0 +1,169 @@
+package app
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/KyberNetwork/evmlistener/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v2"
+)
+
+// newTestFlagSet returns a flag set of flags parsed from given command line arguments.
+func newTestFlagSet(t *testing.T, flags []cli.Flag, args ...string) *flag.FlagSet {
+	t.Helper()
+
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	for _, f := range flags {
+		require.NoError(t, f.Apply(set))
+	}
+	require.NoError(t, set.Parse(args))
+
+	return set
+}
+
+// newTestContext returns a context of the listener app with given command line arguments.
+func newTestContext(t *testing.T, args ...string) *cli.Context {
+	t.Helper()
+
+	app := NewApp()
+
+	return cli.NewContext(app, newTestFlagSet(t, app.Flags, args...), nil)
+}
+
+func TestLoadChainConfigs(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected []ChainConfig
+		err      error
+	}{
+		{
+			name: "valid",
+			data: `{"chains": [
+				{"name": "eth", "topic": "eth-blocks", "flags": {"max-num-blocks": "64"}},
+				{"name": "bsc", "topic": "bsc-blocks", "keyPrefix": "bsc:"}
+			]}`,
+			expected: []ChainConfig{
+				{Name: "eth", Topic: "eth-blocks", KeyPrefix: "listener:eth:", Flags: map[string]string{"max-num-blocks": "64"}},
+				{Name: "bsc", Topic: "bsc-blocks", KeyPrefix: "bsc:"},
+			},
+		},
+		{
+			name: "no chain",
+			data: `{"chains": []}`,
+			err:  errors.ErrInvalidArgument,
+		},
+		{
+			name: "unknown field",
+			data: `{"chains": [{"name": "eth", "topic": "eth-blocks", "httpRpcs": ["http://node"]}]}`,
+			err:  errors.ErrInvalidArgument,
+		},
+		{
+			name: "missing name",
+			data: `{"chains": [{"topic": "eth-blocks"}]}`,
+			err:  errors.ErrInvalidArgument,
+		},
+		{
+			name: "missing topic",
+			data: `{"chains": [{"name": "eth"}]}`,
+			err:  errors.ErrInvalidArgument,
+		},
+		{
+			name: "duplicated name",
+			data: `{"chains": [{"name": "eth", "topic": "a"}, {"name": "eth", "topic": "b"}]}`,
+			err:  errors.ErrInvalidArgument,
+		},
+		{
+			name: "duplicated key prefix",
+			data: `{"chains": [{"name": "eth", "topic": "a"}, {"name": "bsc", "topic": "b", "keyPrefix": "listener:eth:"}]}`,
+			err:  errors.ErrInvalidArgument,
+		},
+		{
+			name: "shared flag",
+			data: `{"chains": [{"name": "eth", "topic": "a", "flags": {"admin-addr": ":9090"}}]}`,
+			err:  errors.ErrInvalidArgument,
+		},
+		{
+			name: "flag of a chain field",
+			data: `{"chains": [{"name": "eth", "topic": "a", "flags": {"publisher-topic": "b"}}]}`,
+			err:  errors.ErrInvalidArgument,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "listeners.json")
+			require.NoError(t, os.WriteFile(path, []byte(test.data), 0o600))
+
+			chains, err := loadChainConfigs(path, "listener:")
+			if test.err != nil {
+				assert.ErrorIs(t, err, test.err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, chains)
+		})
+	}
+}
+
+func TestChainContext(t *testing.T) {
+	c := newTestContext(t, "--rpc-batch-size=10", "--http-rpc=http://default", "--publisher-spool-dir=/var/spool")
+
+	tests := []struct {
+		name      string
+		cfg       ChainConfig
+		batchSize int
+		httpRPC   []string
+		spoolDir  string
+		err       error
+	}{
+		{
+			name:      "flags of the process",
+			cfg:       ChainConfig{Name: "eth", Topic: "eth-blocks"},
+			batchSize: 10,
+			httpRPC:   []string{"http://default"},
+			spoolDir:  filepath.Join("/var/spool", "eth"),
+		},
+		{
+			name: "overridden flags",
+			cfg: ChainConfig{
+				Name:    "bsc",
+				Topic:   "bsc-blocks",
+				HTTPRPC: []string{"http://bsc-1", "http://bsc-2"},
+				Flags:   map[string]string{"rpc-batch-size": "20", "publisher-spool-dir": "/data/bsc"},
+			},
+			batchSize: 20,
+			httpRPC:   []string{"http://bsc-1", "http://bsc-2"},
+			spoolDir:  "/data/bsc",
+		},
+		{
+			name: "unknown flag",
+			cfg:  ChainConfig{Name: "eth", Topic: "eth-blocks", Flags: map[string]string{"batch-size": "20"}},
+			err:  errors.ErrInvalidArgument,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			chainCtx, err := chainContext(c, test.cfg)
+			if test.err != nil {
+				assert.ErrorIs(t, err, test.err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.cfg.Name, chainCtx.String(chainNameFlag.Name))
+			assert.Equal(t, test.cfg.Topic, chainCtx.String(publisherTopicFlag.Name))
+			assert.Equal(t, test.batchSize, chainCtx.Int(rpcBatchSizeFlag.Name))
+			assert.Equal(t, test.httpRPC, chainCtx.StringSlice(httpRPCFlag.Name))
+			assert.Equal(t, test.spoolDir, chainCtx.String(publisherSpoolDirFlag.Name))
+		})
+	}
+}
@@ -14,28 +14,216 @@ var (
 		Value:   "info",
 		Usage:   "Set log level for logger, values: debug, info, warn, error. Default: info",
 	}
-	wsRPCFlag = &cli.StringFlag{
+	wsRPCFlag = &cli.StringSliceFlag{
 		Name:    "ws-rpc",
 		EnvVars: []string{"WS_RPC"},
-		Value:   "ws://localhost:8546",
-		Usage:   "Websocket rpc to connect to blockchain node, default: ws://localhost:8546",
+		Value:   cli.NewStringSlice("ws://localhost:8546"),
+		Usage: "Websocket rpc to connect to blockchain node, multiple values enable failover, " +
+			"new heads are polled from http rpc if there is no websocket rpc, default: ws://localhost:8546",
 	}
-	httpRPCFlag = &cli.StringFlag{
+	wsRPCAuthFlag = &cli.StringSliceFlag{
+		Name:    "ws-rpc-auth",
+		EnvVars: []string{"WS_RPC_AUTH"},
+		Usage: "Authentication of websocket rpcs, the n-th value applies to the n-th rpc of ws-rpc. " +
+			"A value is a list of items separated by ';': header:<name>=<value>, bearer-token-file:<path> " +
+			"or jwt-secret-file:<path>",
+	}
+	wsRaceFlag = &cli.BoolFlag{
+		Name:    "ws-race",
+		EnvVars: []string{"WS_RACE"},
+		Usage:   "Subscribe to new heads on all websocket rpcs at once and use the first arrival of each head",
+	}
+	httpRPCFlag = &cli.StringSliceFlag{
 		Name:    "http-rpc",
 		EnvVars: []string{"HTTP_RPC"},
-		Value:   "http://localhost:8545",
-		Usage:   "HTTP RPC to connect to blockchain node, default: http://localhost:8545",
+		Value:   cli.NewStringSlice("http://localhost:8545"),
+		Usage: "HTTP RPC to connect to blockchain node, multiple values enable failover, " +
+			"default: http://localhost:8545",
+	}
+	httpRPCAuthFlag = &cli.StringSliceFlag{
+		Name:    "http-rpc-auth",
+		EnvVars: []string{"HTTP_RPC_AUTH"},
+		Usage:   "Authentication of http rpcs, the n-th value applies to the n-th rpc of http-rpc, see ws-rpc-auth",
 	}
 	sanityNodeRPCFlag = &cli.StringFlag{
 		Name:    "sanity-node-rpc",
 		EnvVars: []string{"SANITY_NODE_RPC"},
 		Usage:   "RPC to connect to blockchain nod for sanity check",
 	}
+	sanityNodeRPCAuthFlag = &cli.StringFlag{
+		Name:    "sanity-node-rpc-auth",
+		EnvVars: []string{"SANITY_NODE_RPC_AUTH"},
+		Usage:   "Authentication of the sanity check rpc, see ws-rpc-auth",
+	}
 	sanityCheckIntervalFlag = &cli.DurationFlag{
 		Name:    "sanity-check-interval",
 		EnvVars: []string{"SANITY_CHECK_INTERVAL"},
 		Value:   24 * time.Second, //nolint:gomnd
-		Usage:   "Interval time for running santity check, default: 24s",
+		Usage:   "Interval time for running santity check, default: chain profile value or 24s",
+	}
+	rpcQuorumFlag = &cli.IntFlag{
+		Name:    "rpc-quorum",
+		EnvVars: []string{"RPC_QUORUM"},
+		Usage: "Number of http rpcs that must agree on headers and logs, " +
+			"0 uses chain profile value, 1 disables cross-checking",
+	}
+	rpcBatchSizeFlag = &cli.IntFlag{
+		Name:    "rpc-batch-size",
+		EnvVars: []string{"RPC_BATCH_SIZE"},
+		Value:   32, //nolint:gomnd
+		Usage:   "Maximum number of requests per JSON-RPC batch when fetching blocks, 0 disables batching. Default: 32",
+	}
+	rpcLogRangeFlag = &cli.Uint64Flag{
+		Name:    "rpc-log-range",
+		EnvVars: []string{"RPC_LOG_RANGE"},
+		Usage: "Maximum number of blocks per eth_getLogs range query when catching up, " +
+			"0 queries logs block by block. Default: 0",
+	}
+	catchUpWindowFlag = &cli.IntFlag{
+		Name:    "catch-up-window",
+		EnvVars: []string{"CATCH_UP_WINDOW"},
+		Value:   256, //nolint:gomnd
+		Usage:   "Maximum number of blocks fetched at the same time when catching up with the chain. Default: 256",
+	}
+	logVerificationFlag = &cli.StringFlag{
+		Name:    "log-verification",
+		EnvVars: []string{"LOG_VERIFICATION"},
+		Value:   "bloom",
+		Usage: "Verify logs of blocks for completeness before publishing, values: none, bloom, receipts " +
+			"(also checks logs of block receipts against receipts root). Default: bloom",
+	}
+	retryMaxAttemptsFlag = &cli.IntFlag{
+		Name:    "retry-max-attempts",
+		EnvVars: []string{"RETRY_MAX_ATTEMPTS"},
+		Value:   5, //nolint:gomnd
+		Usage:   "Maximum number of attempts for a failed rpc request, 0 retries without limit. Default: 5",
+	}
+	retryInitialIntervalFlag = &cli.DurationFlag{
+		Name:    "retry-initial-interval",
+		EnvVars: []string{"RETRY_INITIAL_INTERVAL"},
+		Value:   500 * time.Millisecond, //nolint:gomnd
+		Usage:   "Delay before the first retry of a failed rpc request, doubled on each retry. Default: 500ms",
+	}
+	retryMaxIntervalFlag = &cli.DurationFlag{
+		Name:    "retry-max-interval",
+		EnvVars: []string{"RETRY_MAX_INTERVAL"},
+		Value:   5 * time.Second, //nolint:gomnd
+		Usage:   "Maximum delay between retries of a failed rpc request or re-subscriptions. Default: 5s",
+	}
+	retryJitterFlag = &cli.Float64Flag{
+		Name:    "retry-jitter",
+		EnvVars: []string{"RETRY_JITTER"},
+		Value:   0.2, //nolint:gomnd
+		Usage:   "Fraction of the retry delay which is randomized, in range [0, 1]. Default: 0.2",
+	}
+	restartMaxFlag = &cli.IntFlag{
+		Name:    "restart-max",
+		EnvVars: []string{"RESTART_MAX"},
+		Usage: "Maximum number of restarts of the subscription for new heads within restart-window, " +
+			"the listener exits when it is exceeded, 0 means no limit. Default: 0",
+	}
+	restartWindowFlag = &cli.DurationFlag{
+		Name:    "restart-window",
+		EnvVars: []string{"RESTART_WINDOW"},
+		Value:   10 * time.Minute, //nolint:gomnd
+		Usage:   "Period over which restarts are counted for restart-max. Default: 10m",
+	}
+	shutdownTimeoutFlag = &cli.DurationFlag{
+		Name:    "shutdown-timeout",
+		EnvVars: []string{"SHUTDOWN_TIMEOUT"},
+		Value:   20 * time.Second, //nolint:gomnd
+		Usage:   "Maximum time to publish blocks already fetched when stopping. Default: 20s",
+	}
+	healthAddrFlag = &cli.StringFlag{
+		Name:    "health-addr",
+		EnvVars: []string{"HEALTH_ADDR"},
+		Value:   ":8080",
+		Usage:   "Address to serve /healthz and /readyz on, empty to disable. Default: :8080",
+	}
+	adminAddrFlag = &cli.StringFlag{
+		Name:    "admin-addr",
+		EnvVars: []string{"ADMIN_ADDR"},
+		Usage:   "Address to serve the admin api on, the api is disabled if empty",
+	}
+	adminTokenFlag = &cli.StringFlag{
+		Name:    "admin-token",
+		EnvVars: []string{"ADMIN_TOKEN"},
+		Usage:   "Bearer token required by the admin api (Required if admin-addr is set)",
+	}
+	readyMaxLagFlag = &cli.Uint64Flag{
+		Name:    "ready-max-lag",
+		EnvVars: []string{"READY_MAX_LAG"},
+		Value:   5, //nolint:gomnd
+		Usage:   "Maximum number of blocks the last handled block may be behind the chain head to be ready. Default: 5",
+	}
+	readyMaxIdleFlag = &cli.DurationFlag{
+		Name:    "ready-max-idle",
+		EnvVars: []string{"READY_MAX_IDLE"},
+		Value:   5 * time.Minute, //nolint:gomnd
+		Usage:   "Maximum duration since the last handled block to be ready, zero to disable. Default: 5m",
+	}
+	rpcRecordFileFlag = &cli.StringFlag{
+		Name:    "rpc-record-file",
+		EnvVars: []string{"RPC_RECORD_FILE"},
+		Usage:   "Path to file for recording node rpc requests, responses and new heads",
+	}
+	rpcReplayFileFlag = &cli.StringFlag{
+		Name:    "rpc-replay-file",
+		EnvVars: []string{"RPC_REPLAY_FILE"},
+		Usage:   "Path to file recorded with rpc-record-file, replays it instead of connecting to node rpc",
+	}
+	rpcReplaySpeedFlag = &cli.Float64Flag{
+		Name:    "rpc-replay-speed",
+		EnvVars: []string{"RPC_REPLAY_SPEED"},
+		Value:   1,
+		Usage:   "Speed of replaying new heads relative to recorded pace, 0 replays as fast as possible. Default: 1",
+	}
+	chainConfigFlag = &cli.StringFlag{
+		Name:    "chain-config",
+		EnvVars: []string{"CHAIN_CONFIG"},
+		Usage:   "Path to JSON file declaring chain profiles, overrides built-in profiles",
+	}
+	startBlockFlag = &cli.Uint64Flag{
+		Name:    "start-block",
+		EnvVars: []string{"START_BLOCK"},
+		Usage:   "Block number to start publishing from on a fresh deployment, ignored if a saved head exists",
+	}
+	startBlockHashFlag = &cli.StringFlag{
+		Name:    "start-block-hash",
+		EnvVars: []string{"START_BLOCK_HASH"},
+		Usage:   "Block hash to start publishing from on a fresh deployment, ignored if a saved head exists",
+	}
+	startTimeFlag = &cli.Uint64Flag{
+		Name:    "start-time",
+		EnvVars: []string{"START_TIME"},
+		Usage: "Unix timestamp to start publishing from on a fresh deployment, the first block at or after it " +
+			"is used, ignored if a saved head exists",
+	}
+	leaderElectionFlag = &cli.BoolFlag{
+		Name:    "leader-election",
+		EnvVars: []string{"LEADER_ELECTION"},
+		Usage:   "Elect a leader among replicas with a lease on redis, only the leader publishes messages",
+	}
+	leaderLeaseTTLFlag = &cli.DurationFlag{
+		Name:    "leader-lease-ttl",
+		EnvVars: []string{"LEADER_LEASE_TTL"},
+		Value:   5 * time.Second, //nolint:gomnd
+		Usage:   "Duration of the leader lease, a standby takes over within a third of it after expiry. Default: 5s",
+	}
+	leaderIDFlag = &cli.StringFlag{
+		Name:    "leader-id",
+		EnvVars: []string{"LEADER_ID"},
+		Usage:   "Identity of the replica for leader election. Default: hostname",
+	}
+	chainNameFlag = &cli.StringFlag{
+		Name:    "chain-name",
+		EnvVars: []string{"CHAIN_NAME"},
+		Usage:   "Name of the chain to label logs and metrics with. Default: name of the chain profile",
+	}
+	listenersConfigFlag = &cli.StringFlag{
+		Name:    "listeners-config",
+		EnvVars: []string{"LISTENERS_CONFIG"},
+		Usage:   "Path to JSON file declaring chains to listen to in a single process, see README",
 	}
 
 	sentryDSNFlag = &cli.StringFlag{
@@ -100,11 +288,10 @@ var (
 	}
 
 	publisherTopicFlag = &cli.StringFlag{
-		Name:     "publisher-topic",
-		EnvVars:  []string{"PUBLISHER_TOPIC"},
-		Value:    "",
-		Required: true,
-		Usage:    "Topic name of publisher to publish message to (Required)",
+		Name:    "publisher-topic",
+		EnvVars: []string{"PUBLISHER_TOPIC"},
+		Value:   "",
+		Usage:   "Topic name of publisher to publish message to (Required, unless listeners-config is set)",
 	}
 	publisherMaxLenFlag = &cli.Int64Flag{
 		Name:    "publisher-max-len",
@@ -112,6 +299,18 @@ var (
 		Value:   7200, //nolint:gomnd
 		Usage:   "Maximum length for publisher's queue. Default: 7200",
 	}
+	publisherSpoolDirFlag = &cli.StringFlag{
+		Name:    "publisher-spool-dir",
+		EnvVars: []string{"PUBLISHER_SPOOL_DIR"},
+		Usage: "Directory of the on-disk spool keeping messages while the publisher is unavailable, " +
+			"empty disables the spool",
+	}
+	publisherSpoolMaxSizeFlag = &cli.Int64Flag{
+		Name:    "publisher-spool-max-size",
+		EnvVars: []string{"PUBLISHER_SPOOL_MAX_SIZE"},
+		Value:   256 << 20, //nolint:gomnd
+		Usage:   "Maximum size in bytes of messages in the spool. Default: 268435456 (256MiB)",
+	}
 
 	maxNumBlocksFlag = &cli.IntFlag{
 		Name:    "max-num-blocks",
@@ -143,7 +342,7 @@ func NewRedisFlags() []cli.Flag {
 
 // NewPublisherFlags returns flags for publishers.
 func NewPublisherFlags() []cli.Flag {
-	return []cli.Flag{publisherMaxLenFlag, publisherTopicFlag}
+	return []cli.Flag{publisherMaxLenFlag, publisherTopicFlag, publisherSpoolDirFlag, publisherSpoolMaxSizeFlag}
 }
 
 // NewBlockKeeperFlags returns flags for block keeper.
@@ -156,9 +355,42 @@ func NewFlags() []cli.Flag {
 	flags := []cli.Flag{
 		logLevelFlag,
 		wsRPCFlag,
+		wsRPCAuthFlag,
+		wsRaceFlag,
 		httpRPCFlag,
+		httpRPCAuthFlag,
 		sanityNodeRPCFlag,
+		sanityNodeRPCAuthFlag,
 		sanityCheckIntervalFlag,
+		rpcQuorumFlag,
+		rpcBatchSizeFlag,
+		rpcLogRangeFlag,
+		catchUpWindowFlag,
+		logVerificationFlag,
+		retryMaxAttemptsFlag,
+		retryInitialIntervalFlag,
+		retryMaxIntervalFlag,
+		retryJitterFlag,
+		restartMaxFlag,
+		restartWindowFlag,
+		shutdownTimeoutFlag,
+		healthAddrFlag,
+		adminAddrFlag,
+		adminTokenFlag,
+		readyMaxLagFlag,
+		readyMaxIdleFlag,
+		rpcRecordFileFlag,
+		rpcReplayFileFlag,
+		rpcReplaySpeedFlag,
+		chainConfigFlag,
+		startBlockFlag,
+		startBlockHashFlag,
+		startTimeFlag,
+		leaderElectionFlag,
+		leaderLeaseTTLFlag,
+		leaderIDFlag,
+		chainNameFlag,
+		listenersConfigFlag,
 	}
 	flags = append(flags, NewSentryFlags()...)
 	flags = append(flags, NewRedisFlags()...)
@@ -0,0 +1,283 @@
+// Package admin serves an authenticated HTTP API for inspecting and controlling running listeners.
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KyberNetwork/evmlistener/pkg/errors"
+	"github.com/KyberNetwork/evmlistener/pkg/listener"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultNumBlocks  = 10
+	readHeaderTimeout = 5 * time.Second
+	shutdownTimeout   = 5 * time.Second
+)
+
+// Listeners gives access to listeners by chain name.
+type Listeners interface {
+	// Chains returns names of all chains.
+	Chains() []string
+	// Listener returns the listener of the chain, false if it is not available.
+	Listener(chain string) (*listener.Listener, bool)
+}
+
+type singleListener struct {
+	listener *listener.Listener
+}
+
+// SingleListener returns Listeners of a process listening to a single chain.
+func SingleListener(l *listener.Listener) Listeners {
+	return singleListener{listener: l}
+}
+
+func (s singleListener) Chains() []string {
+	return []string{s.listener.ChainName()}
+}
+
+func (s singleListener) Listener(chain string) (*listener.Listener, bool) {
+	return s.listener, chain == s.listener.ChainName()
+}
+
+// Server serves the admin API, every request must carry the token as a bearer token:
+//
+//	GET  /chains                         status of all chains
+//	GET  /chains/{chain}                 status of the chain
+//	GET  /chains/{chain}/blocks?n=10     most recent blocks of the block keeper
+//	POST /chains/{chain}/pause           pause publishing
+//	POST /chains/{chain}/resume          resume publishing
+//	POST /chains/{chain}/resync          force a resync, body {"fromBlock": 123}
+//	POST /chains/{chain}/rewind          re-publish blocks after a block, body {"toBlock": 123}
+//	GET  /log/level                      log level, PUT {"level": "debug"} to change it
+type Server struct {
+	l         *zap.SugaredLogger
+	addr      string
+	token     string
+	listeners Listeners
+	level     zap.AtomicLevel
+}
+
+// NewServer returns a new Server object listening on addr.
+func NewServer(
+	l *zap.SugaredLogger, addr string, token string, listeners Listeners, level zap.AtomicLevel,
+) (*Server, error) {
+	if token == "" {
+		return nil, fmt.Errorf("%w: admin token is required", errors.ErrInvalidArgument)
+	}
+
+	return &Server{
+		l:         l,
+		addr:      addr,
+		token:     token,
+		listeners: listeners,
+		level:     level,
+	}, nil
+}
+
+// Handler returns the handler of the admin API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /chains", s.handleStatuses)
+	mux.HandleFunc("GET /chains/{chain}", s.withListener(s.handleStatus))
+	mux.HandleFunc("GET /chains/{chain}/blocks", s.withListener(s.handleBlocks))
+	mux.HandleFunc("POST /chains/{chain}/pause", s.withListener(s.handlePause))
+	mux.HandleFunc("POST /chains/{chain}/resume", s.withListener(s.handleResume))
+	mux.HandleFunc("POST /chains/{chain}/resync", s.withListener(s.handleResync))
+	mux.HandleFunc("POST /chains/{chain}/rewind", s.withListener(s.handleRewind))
+	mux.Handle("/log/level", s.level)
+
+	return s.authenticate(mux)
+}
+
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("unauthorized"))
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) withListener(
+	handle func(w http.ResponseWriter, r *http.Request, l *listener.Listener),
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chain := r.PathValue("chain")
+		l, ok := s.listeners.Listener(chain)
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("chain %s: %w", chain, errors.ErrNotFound))
+
+			return
+		}
+
+		handle(w, r, l)
+	}
+}
+
+func (s *Server) handleStatuses(w http.ResponseWriter, _ *http.Request) {
+	statuses := make([]listener.Status, 0)
+	for _, chain := range s.listeners.Chains() {
+		if l, ok := s.listeners.Listener(chain); ok {
+			statuses = append(statuses, l.Status())
+		} else {
+			statuses = append(statuses, listener.Status{Chain: chain})
+		}
+	}
+
+	writeJSON(w, http.StatusOK, statuses)
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, _ *http.Request, l *listener.Listener) {
+	writeJSON(w, http.StatusOK, l.Status())
+}
+
+func (s *Server) handleBlocks(w http.ResponseWriter, r *http.Request, l *listener.Listener) {
+	n := defaultNumBlocks
+	if v := r.URL.Query().Get("n"); v != "" {
+		var err error
+		n, err = strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("%w: invalid number of blocks %q",
+				errors.ErrInvalidArgument, v))
+
+			return
+		}
+	}
+
+	blocks, err := l.RecentBlocks(n)
+	if err != nil {
+		s.l.Errorw("Fail to get recent blocks", "chain", l.ChainName(), "error", err)
+		writeError(w, http.StatusInternalServerError, err)
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, blocks)
+}
+
+func (s *Server) handlePause(w http.ResponseWriter, _ *http.Request, l *listener.Listener) {
+	s.l.Warnw("Pause publishing from admin api", "chain", l.ChainName())
+	l.Pause()
+	writeJSON(w, http.StatusOK, l.Status())
+}
+
+func (s *Server) handleResume(w http.ResponseWriter, _ *http.Request, l *listener.Listener) {
+	s.l.Infow("Resume publishing from admin api", "chain", l.ChainName())
+	l.Resume()
+	writeJSON(w, http.StatusOK, l.Status())
+}
+
+type resyncRequest struct {
+	FromBlock *uint64 `json:"fromBlock"`
+}
+
+func (s *Server) handleResync(w http.ResponseWriter, r *http.Request, l *listener.Listener) {
+	var req resyncRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.FromBlock == nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("%w: fromBlock is required", errors.ErrInvalidArgument))
+
+		return
+	}
+
+	s.l.Warnw("Force resync from admin api", "chain", l.ChainName(), "fromBlock", *req.FromBlock)
+	if err := l.Resync(r.Context(), *req.FromBlock); err != nil {
+		s.l.Errorw("Fail to force resync", "chain", l.ChainName(), "fromBlock", *req.FromBlock, "error", err)
+		writeError(w, statusOf(err), err)
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, l.Status())
+}
+
+type rewindRequest struct {
+	ToBlock *uint64 `json:"toBlock"`
+}
+
+func (s *Server) handleRewind(w http.ResponseWriter, r *http.Request, l *listener.Listener) {
+	var req rewindRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ToBlock == nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("%w: toBlock is required", errors.ErrInvalidArgument))
+
+		return
+	}
+
+	s.l.Warnw("Rewind from admin api", "chain", l.ChainName(), "toBlock", *req.ToBlock)
+	if err := l.Rewind(r.Context(), *req.ToBlock); err != nil {
+		s.l.Errorw("Fail to rewind", "chain", l.ChainName(), "toBlock", *req.ToBlock, "error", err)
+		writeError(w, statusOf(err), err)
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, l.Status())
+}
+
+func statusOf(err error) int {
+	switch {
+	case errors.Is(err, errors.ErrInvalidArgument):
+		return http.StatusBadRequest
+	case errors.Is(err, listener.ErrPaused), errors.Is(err, listener.ErrNotRunning):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+// Run serves the admin API until ctx is done.
+func (s *Server) Run(ctx context.Context) error {
+	server := &http.Server{
+		Addr:              s.addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: readHeaderTimeout,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		s.l.Infow("Serve admin api", "addr", s.addr)
+		errCh <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		s.l.Errorw("Fail to serve admin api", "addr", s.addr, "error", err)
+
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		s.l.Errorw("Fail to shutdown admin server", "error", err)
+
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+package admin
+
+import (
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KyberNetwork/evmlistener/pkg/block"
+	"github.com/KyberNetwork/evmlistener/pkg/listener"
+	"github.com/KyberNetwork/evmlistener/pkg/types"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const testToken = "secret"
+
+type AdminTestSuite struct {
+	suite.Suite
+
+	listener *listener.Listener
+	level    zap.AtomicLevel
+	server   *httptest.Server
+}
+
+func (ts *AdminTestSuite) SetupTest() {
+	keeper := block.NewBaseBlockKeeper(8)
+	ts.Require().NoError(keeper.Add(types.Block{Number: big.NewInt(100), Hash: "0x100", ParentHash: "0x99"}))
+	ts.Require().NoError(keeper.Add(types.Block{Number: big.NewInt(101), Hash: "0x101", ParentHash: "0x100"}))
+
+	opts := []listener.Option{listener.WithChainName("test")}
+	handler := listener.NewHandler(zap.S(), "test-topic", nil, keeper, nil, opts...)
+	ts.listener = listener.New(zap.S(), nil, nil, handler, nil, 0, opts...)
+	ts.level = zap.NewAtomicLevelAt(zapcore.InfoLevel)
+
+	server, err := NewServer(zap.S(), "", testToken, SingleListener(ts.listener), ts.level)
+	ts.Require().NoError(err)
+	ts.server = httptest.NewServer(server.Handler())
+}
+
+func (ts *AdminTestSuite) TearDownTest() {
+	ts.server.Close()
+}
+
+func (ts *AdminTestSuite) do(method, path, token, body string, v interface{}) int {
+	req, err := http.NewRequest(method, ts.server.URL+path, strings.NewReader(body)) //nolint:noctx
+	ts.Require().NoError(err)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	ts.Require().NoError(err)
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	ts.Require().NoError(err)
+	if v != nil {
+		ts.Require().NoError(json.Unmarshal(data, v), string(data))
+	}
+
+	return resp.StatusCode
+}
+
+func (ts *AdminTestSuite) TestUnauthorized() {
+	ts.Assert().Equal(http.StatusUnauthorized, ts.do(http.MethodGet, "/chains", "", "", nil))
+	ts.Assert().Equal(http.StatusUnauthorized, ts.do(http.MethodGet, "/chains", "wrong", "", nil))
+	ts.Assert().Equal(http.StatusUnauthorized, ts.do(http.MethodPut, "/log/level", "", `{"level":"debug"}`, nil))
+	ts.Assert().Equal(zapcore.InfoLevel, ts.level.Level())
+
+	_, err := NewServer(zap.S(), "", "", SingleListener(ts.listener), ts.level)
+	ts.Assert().Error(err)
+}
+
+func (ts *AdminTestSuite) TestStatus() {
+	var statuses []listener.Status
+	ts.Require().Equal(http.StatusOK, ts.do(http.MethodGet, "/chains", testToken, "", &statuses))
+	ts.Require().Len(statuses, 1)
+	ts.Assert().Equal("test", statuses[0].Chain)
+	ts.Assert().False(statuses[0].Running)
+	ts.Require().NotNil(statuses[0].Head)
+	ts.Assert().Equal("0x101", statuses[0].Head.Hash)
+	ts.Assert().Equal(2, statuses[0].KeeperLen)
+	ts.Assert().Equal(8, statuses[0].KeeperCap)
+
+	ts.Assert().Equal(http.StatusNotFound, ts.do(http.MethodGet, "/chains/unknown", testToken, "", nil))
+}
+
+func (ts *AdminTestSuite) TestBlocks() {
+	var blocks []types.Block
+	ts.Require().Equal(http.StatusOK, ts.do(http.MethodGet, "/chains/test/blocks?n=1", testToken, "", &blocks))
+	ts.Require().Len(blocks, 1)
+	ts.Assert().Equal("0x101", blocks[0].Hash)
+
+	ts.Assert().Equal(http.StatusBadRequest, ts.do(http.MethodGet, "/chains/test/blocks?n=x", testToken, "", nil))
+}
+
+func (ts *AdminTestSuite) TestPause() {
+	var status listener.Status
+	ts.Require().Equal(http.StatusOK, ts.do(http.MethodPost, "/chains/test/pause", testToken, "", &status))
+	ts.Assert().True(status.Paused)
+
+	ts.Require().Equal(http.StatusOK, ts.do(http.MethodPost, "/chains/test/resume", testToken, "", &status))
+	ts.Assert().False(status.Paused)
+}
+
+func (ts *AdminTestSuite) TestResync() {
+	ts.Assert().Equal(http.StatusBadRequest, ts.do(http.MethodPost, "/chains/test/resync", testToken, `{}`, nil))
+
+	// The listener is not running.
+	ts.Assert().Equal(http.StatusConflict,
+		ts.do(http.MethodPost, "/chains/test/resync", testToken, `{"fromBlock":100}`, nil))
+}
+
+func (ts *AdminTestSuite) TestRewind() {
+	ts.Assert().Equal(http.StatusBadRequest, ts.do(http.MethodPost, "/chains/test/rewind", testToken, `{}`, nil))
+
+	// The listener is not running.
+	ts.Assert().Equal(http.StatusConflict,
+		ts.do(http.MethodPost, "/chains/test/rewind", testToken, `{"toBlock":100}`, nil))
+}
+
+func (ts *AdminTestSuite) TestLogLevel() {
+	ts.Require().Equal(http.StatusOK, ts.do(http.MethodPut, "/log/level", testToken, `{"level":"debug"}`, nil))
+	ts.Assert().Equal(zapcore.DebugLevel, ts.level.Level())
+
+	var res struct {
+		Level string `json:"level"`
+	}
+	ts.Require().Equal(http.StatusOK, ts.do(http.MethodGet, "/log/level", testToken, "", &res))
+	ts.Assert().Equal("debug", res.Level)
+}
+
+func TestAdminTestSuite(t *testing.T) {
+	suite.Run(t, new(AdminTestSuite))
+}
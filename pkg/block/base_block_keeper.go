@@ -18,6 +18,7 @@ type BaseBlockKeeper struct {
 	head         string
 	blockMap     map[string]types.Block
 	queue        *circularbuffer.Queue
+	rewind       *Rewind
 }
 
 // NewBaseBlockKeeper ...
@@ -180,6 +181,46 @@ func (k *BaseBlockKeeper) GetHead() string {
 	return k.head
 }
 
+// Flush does nothing, blocks are only kept on memory.
+func (k *BaseBlockKeeper) Flush() error {
+	return nil
+}
+
+// Reset removes all blocks and the head from the keeper.
+func (k *BaseBlockKeeper) Reset() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.blockMap = make(map[string]types.Block, k.maxNumBlocks)
+	k.queue.Clear()
+	k.head = ""
+
+	return nil
+}
+
+// SavedHead returns hash of the head block, blocks are only kept on memory.
+func (k *BaseBlockKeeper) SavedHead() (string, error) {
+	return k.GetHead(), nil
+}
+
+// SetRewind records a rewind until it is finished, nil means there is none.
+func (k *BaseBlockKeeper) SetRewind(rewind *Rewind) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.rewind = rewind
+
+	return nil
+}
+
+// PendingRewind returns the rewind which is not finished, nil if there is none.
+func (k *BaseBlockKeeper) PendingRewind() (*Rewind, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return k.rewind, nil
+}
+
 // SetHead sets hash for the head block.
 func (k *BaseBlockKeeper) SetHead(hash string) {
 	k.mu.Lock()
@@ -170,6 +170,20 @@ func (ts *BaseBlockKeeperTestSuite) TestHead() {
 	ts.Assert().ErrorIs(err, errors.ErrNotFound)
 }
 
+func (ts *BaseBlockKeeperTestSuite) TestReset() {
+	ts.Require().NoError(ts.keeper.Reset())
+	ts.Assert().Zero(ts.keeper.Len())
+	ts.Assert().Empty(ts.keeper.GetHead())
+	_, err := ts.keeper.Head()
+	ts.Assert().ErrorIs(err, errors.ErrNotFound)
+
+	// The first block added after a reset is the new head.
+	ts.Require().NoError(ts.keeper.Add(sampleBlocks[1]))
+	head, err := ts.keeper.Head()
+	ts.Require().NoError(err)
+	ts.Assert().Equal(sampleBlocks[1].Hash, head.Hash)
+}
+
 func (ts *BaseBlockKeeperTestSuite) TestIsReorg() {
 	tests := []struct {
 		block  types.Block
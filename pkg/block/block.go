@@ -4,6 +4,16 @@ import (
 	"github.com/KyberNetwork/evmlistener/pkg/types"
 )
 
+// Rewind is a rewind of the block keeper which is not finished yet.
+type Rewind struct {
+	// Target is the block the head is moved back to.
+	Target types.Block `json:"target"`
+	// Reverted are the blocks after the target, in descending order.
+	Reverted []types.Block `json:"reverted"`
+	// Published is set once the message reverting the blocks is published.
+	Published bool `json:"published"`
+}
+
 // Keeper is an interface for interacting with block keeper.
 //
 //nolint:interfacebloat
@@ -19,4 +29,9 @@ type Keeper interface {
 	GetRecentBlocks(n int) ([]types.Block, error)
 	GetHead() string
 	SetHead(hash string)
+	Flush() error
+	Reset() error
+	SavedHead() (string, error)
+	SetRewind(rewind *Rewind) error
+	PendingRewind() (*Rewind, error)
 }
@@ -7,12 +7,14 @@ import (
 
 	"github.com/KyberNetwork/evmlistener/pkg/errors"
 	"github.com/KyberNetwork/evmlistener/pkg/redis"
+	"github.com/KyberNetwork/evmlistener/pkg/retry"
 	"github.com/KyberNetwork/evmlistener/pkg/types"
 	"go.uber.org/zap"
 )
 
 const (
-	blockHeadKey = "block-head"
+	blockHeadKey   = "block-head"
+	blockRewindKey = "block-rewind"
 
 	minExpirationTime = time.Second
 )
@@ -21,8 +23,10 @@ const (
 type RedisBlockKeeper struct {
 	expiration time.Duration
 
-	redisClient *redis.Client
-	l           *zap.SugaredLogger
+	redisClient    *redis.Client
+	lease          *redis.Lease
+	memoryFallback bool
+	l              *zap.SugaredLogger
 
 	*BaseBlockKeeper
 }
@@ -39,6 +43,18 @@ func NewRedisBlockKeeper(
 	}
 }
 
+// SetLease makes the keeper write into redis only while the lease is held, blocks are kept on memory
+// only otherwise. The block head is written with the fencing token of the lease.
+func (k *RedisBlockKeeper) SetLease(lease *redis.Lease) {
+	k.lease = lease
+}
+
+// SetMemoryFallback makes the keeper keep blocks on memory only while redis is unavailable, instead
+// of failing. It is used with the publisher spool, which keeps the listener running through an outage.
+func (k *RedisBlockKeeper) SetMemoryFallback() {
+	k.memoryFallback = true
+}
+
 // Init ...
 func (k *RedisBlockKeeper) Init() error {
 	// Get blockchain head from redis.
@@ -117,23 +133,115 @@ func (k *RedisBlockKeeper) Add(block types.Block) error {
 		return fmt.Errorf("block %v: %w", block.Hash, errors.ErrAlreadyExists)
 	}
 
-	// Store new block and new head into redis.
+	// Store new block and new head into redis. Redis is only used to resume from, so the block
+	// is still kept on memory if redis is briefly unavailable and memory fallback is enabled.
+	err = k.store(block)
+	switch {
+	case errors.Is(err, errors.ErrFenced):
+		k.l.Debugw("Keep block on memory only, lease is not held", "hash", block.Hash)
+	case err != nil && (!k.memoryFallback || !retry.Classify(err).Retryable()):
+		return err
+	case err != nil:
+		k.l.Warnw("Keep block on memory only, redis is unavailable", "hash", block.Hash, "error", err)
+	}
+
+	return k.BaseBlockKeeper.Add(block)
+}
+
+func (k *RedisBlockKeeper) store(block types.Block) error {
+	if k.lease != nil && k.lease.Token() == 0 {
+		return errors.ErrFenced
+	}
+
 	expiration := k.getExpiration(int64(block.Timestamp))
-	err = k.redisClient.Set(context.Background(), block.Hash, block, expiration)
+	err := k.redisClient.Set(context.Background(), block.Hash, block, expiration)
 	if err != nil {
 		k.l.Errorw("Fail to store block into redis", "hash", block.Hash, "error", err)
 
 		return err
 	}
 
-	err = k.redisClient.Set(context.Background(), blockHeadKey, block.Hash, 0)
+	err = k.setHead(block.Hash)
 	if err != nil {
 		k.l.Errorw("Fail to store block head into redis", "hash", block.Hash, "error", err)
 
 		return err
 	}
 
-	return k.BaseBlockKeeper.Add(block)
+	return nil
+}
+
+func (k *RedisBlockKeeper) setHead(hash string) error {
+	if k.lease != nil {
+		return k.lease.Set(context.Background(), blockHeadKey, hash, 0)
+	}
+
+	return k.redisClient.Set(context.Background(), blockHeadKey, hash, 0)
+}
+
+// Flush stores the head of the keeper into redis, so that the listener resumes from it.
+func (k *RedisBlockKeeper) Flush() error {
+	head, err := k.BaseBlockKeeper.Head()
+	if errors.Is(err, errors.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = k.setHead(head.Hash)
+	if errors.Is(err, errors.ErrFenced) {
+		k.l.Infow("Skip storing block head, lease is not held", "hash", head.Hash)
+
+		return nil
+	}
+	if err != nil {
+		k.l.Errorw("Fail to store block head into redis", "hash", head.Hash, "error", err)
+
+		return err
+	}
+
+	return nil
+}
+
+// SavedHead returns hash of the head block stored into redis, empty if there is none.
+func (k *RedisBlockKeeper) SavedHead() (string, error) {
+	var head string
+	err := k.redisClient.Get(context.Background(), blockHeadKey, &head)
+	if errors.Is(err, errors.ErrNotFound) {
+		return "", nil
+	}
+
+	return head, err
+}
+
+// SetRewind stores a rewind into redis until it is finished, so that a rewind interrupted by
+// a restart is finished after it. It fails if the lease is not held.
+func (k *RedisBlockKeeper) SetRewind(rewind *Rewind) error {
+	var err error
+	if k.lease != nil {
+		err = k.lease.Set(context.Background(), blockRewindKey, rewind, 0)
+	} else {
+		err = k.redisClient.Set(context.Background(), blockRewindKey, rewind, 0)
+	}
+	if err != nil {
+		k.l.Errorw("Fail to store rewind into redis", "error", err)
+
+		return err
+	}
+
+	return k.BaseBlockKeeper.SetRewind(rewind)
+}
+
+// PendingRewind returns the rewind stored into redis which is not finished, nil if there is none.
+func (k *RedisBlockKeeper) PendingRewind() (*Rewind, error) {
+	var rewind *Rewind
+	err := k.redisClient.Get(context.Background(), blockRewindKey, &rewind)
+	if errors.Is(err, errors.ErrNotFound) {
+		return nil, nil
+	}
+
+	return rewind, err
 }
 
 // Get ...
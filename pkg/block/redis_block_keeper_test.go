@@ -5,12 +5,14 @@ import (
 	"fmt"
 	"math/big"
 	"math/rand"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/KyberNetwork/evmlistener/pkg/errors"
 	"github.com/KyberNetwork/evmlistener/pkg/redis"
 	"github.com/KyberNetwork/evmlistener/pkg/types"
+	goredis "github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/suite"
 	"go.uber.org/zap"
 )
@@ -78,6 +80,95 @@ func (ts *RedisBlockKeeperTestSuite) TestAdd() {
 	ts.Assert().ErrorIs(err, errors.ErrAlreadyExists)
 }
 
+// unavailableHook fails every redis command as if the server refused connections.
+type unavailableHook struct{}
+
+func (unavailableHook) DialHook(next goredis.DialHook) goredis.DialHook {
+	return next
+}
+
+func (unavailableHook) ProcessHook(goredis.ProcessHook) goredis.ProcessHook {
+	return func(_ context.Context, cmd goredis.Cmder) error {
+		cmd.SetErr(syscall.ECONNREFUSED)
+
+		return cmd.Err()
+	}
+}
+
+func (unavailableHook) ProcessPipelineHook(next goredis.ProcessPipelineHook) goredis.ProcessPipelineHook {
+	return next
+}
+
+func (ts *RedisBlockKeeperTestSuite) TestAddRedisUnavailable() {
+	block := types.Block{
+		Number:     big.NewInt(35338115),
+		Hash:       "0xf11b9c19c31319321e6730754f4fe1746f24d1b6ca925d30622059e6a5d79450",
+		ParentHash: "0x9a24538f47e0c6faa56732a0c3f1f036bea5372a57369c3ecef1423972957c6a",
+	}
+	ts.redisClient.AddHook(unavailableHook{})
+
+	// Block is not kept on memory only without memory fallback.
+	err := ts.keeper.Add(block)
+	ts.Assert().ErrorIs(err, syscall.ECONNREFUSED)
+	ts.Assert().Equal(0, ts.keeper.Len())
+
+	ts.keeper.SetMemoryFallback()
+	ts.Require().NoError(ts.keeper.Add(block))
+	ts.Assert().Equal(1, ts.keeper.Len())
+}
+
+func (ts *RedisBlockKeeperTestSuite) TestFlush() {
+	// Nothing to flush.
+	ts.Assert().NoError(ts.keeper.Flush())
+
+	block := types.Block{
+		Number:     big.NewInt(35338115),
+		Hash:       "0xf11b9c19c31319321e6730754f4fe1746f24d1b6ca925d30622059e6a5d79450",
+		ParentHash: "0x9a24538f47e0c6faa56732a0c3f1f036bea5372a57369c3ecef1423972957c6a",
+	}
+	err := ts.keeper.Add(block)
+	ts.Require().NoError(err)
+
+	err = ts.redisClient.Set(context.Background(), blockHeadKey, block.ParentHash, 0)
+	ts.Require().NoError(err)
+
+	ts.Require().NoError(ts.keeper.Flush())
+	var head string
+	err = ts.redisClient.Get(context.Background(), blockHeadKey, &head)
+	ts.Require().NoError(err)
+	ts.Assert().Equal(block.Hash, head)
+}
+
+func (ts *RedisBlockKeeperTestSuite) TestRewind() {
+	// No pending rewind.
+	rewind, err := ts.keeper.PendingRewind()
+	ts.Require().NoError(err)
+	ts.Assert().Nil(rewind)
+
+	// A pending rewind is read from redis, e.g. after a restart.
+	ts.Require().NoError(ts.keeper.SetRewind(&Rewind{
+		Target:    sampleBlocks[2],
+		Reverted:  sampleBlocks[:2],
+		Published: true,
+	}))
+	keeper := NewRedisBlockKeeper(zap.S(), ts.redisClient, 4, time.Second)
+	rewind, err = keeper.PendingRewind()
+	ts.Require().NoError(err)
+	if ts.Assert().NotNil(rewind) {
+		ts.Assert().Equal(sampleBlocks[2].Hash, rewind.Target.Hash)
+		if ts.Assert().Len(rewind.Reverted, 2) {
+			ts.Assert().Equal(sampleBlocks[0].Hash, rewind.Reverted[0].Hash)
+			ts.Assert().Equal(sampleBlocks[1].Hash, rewind.Reverted[1].Hash)
+		}
+		ts.Assert().True(rewind.Published)
+	}
+
+	ts.Require().NoError(keeper.SetRewind(nil))
+	rewind, err = ts.keeper.PendingRewind()
+	ts.Require().NoError(err)
+	ts.Assert().Nil(rewind)
+}
+
 func TestRedisBlockKeeperTestSuite(t *testing.T) {
 	suite.Run(t, new(RedisBlockKeeperTestSuite))
 }
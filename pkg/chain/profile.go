@@ -0,0 +1,193 @@
+package chain
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/KyberNetwork/evmlistener/pkg/errors"
+)
+
+// HeaderMode defines how block headers returned by the node should be decoded.
+type HeaderMode string
+
+const (
+	// HeaderModeStandard decodes headers with go-ethereum types and computes hash from header fields.
+	HeaderModeStandard HeaderMode = "standard"
+	// HeaderModeCustom decodes headers with the custom decoder and takes hash from the RPC response.
+	// It is required by chains whose header hash can not be re-computed from go-ethereum header.
+	HeaderModeCustom HeaderMode = "custom"
+)
+
+const (
+	defaultLogRetryMaxAttempts = 5
+)
+
+// LogRetry contains retry behavior when fetching logs of a block.
+type LogRetry struct {
+	// MaxAttempts is maximum number of attempts for fetching logs of a block.
+	MaxAttempts int `json:"maxAttempts"`
+	// RetryOnEmpty retries fetching logs when node returns an empty list of logs.
+	RetryOnEmpty bool `json:"retryOnEmpty"`
+}
+
+// Methods contains optional RPC methods supported by nodes of the chain.
+type Methods struct {
+	// GetBlockReceipts is whether nodes support eth_getBlockReceipts.
+	GetBlockReceipts bool `json:"getBlockReceipts"`
+	// FinalizedTag is whether nodes support the "finalized" block tag.
+	FinalizedTag bool `json:"finalizedTag"`
+}
+
+// Profile contains chain specific configurations.
+type Profile struct {
+	ChainID             uint64
+	Name                string
+	HeaderMode          HeaderMode
+	BlockTime           time.Duration
+	SanityCheckInterval time.Duration
+	LogRetry            LogRetry
+	Methods             Methods
+	// VerifyReceiptsRoot checks block receipts against the receipts root of the header. It must be
+	// disabled for chains with receipt types that go-ethereum can not encode, e.g. OP deposits.
+	VerifyReceiptsRoot bool
+	// Quorum is the number of providers that must agree on a result, 0 or 1 disables cross-checking.
+	Quorum int
+}
+
+// DefaultProfile returns profile for a chain that was not declared in registry.
+func DefaultProfile(chainID uint64) Profile {
+	return Profile{
+		ChainID:            chainID,
+		Name:               strconv.FormatUint(chainID, 10),
+		HeaderMode:         HeaderModeStandard,
+		VerifyReceiptsRoot: true,
+		LogRetry: LogRetry{
+			MaxAttempts:  defaultLogRetryMaxAttempts,
+			RetryOnEmpty: true,
+		},
+	}
+}
+
+// Validate checks whether profile is valid or not.
+func (p Profile) Validate() error {
+	if p.ChainID == 0 {
+		return fmt.Errorf("%w: missing chain id", errors.ErrInvalidArgument)
+	}
+
+	switch p.HeaderMode {
+	case HeaderModeStandard, HeaderModeCustom:
+	default:
+		return fmt.Errorf("%w: unknown header mode %q for chain %d",
+			errors.ErrInvalidArgument, p.HeaderMode, p.ChainID)
+	}
+
+	if p.BlockTime < 0 || p.SanityCheckInterval < 0 {
+		return fmt.Errorf("%w: negative duration for chain %d", errors.ErrInvalidArgument, p.ChainID)
+	}
+
+	if p.Quorum < 0 {
+		return fmt.Errorf("%w: negative quorum for chain %d", errors.ErrInvalidArgument, p.ChainID)
+	}
+
+	if p.LogRetry.MaxAttempts <= 0 {
+		return fmt.Errorf("%w: log retry max attempts must be positive for chain %d",
+			errors.ErrInvalidArgument, p.ChainID)
+	}
+
+	return nil
+}
+
+// MarshalJSON marshals as JSON.
+func (p Profile) MarshalJSON() ([]byte, error) {
+	type Profile struct {
+		ChainID             uint64     `json:"chainId"`
+		Name                string     `json:"name"`
+		HeaderMode          HeaderMode `json:"headerMode"`
+		BlockTime           string     `json:"blockTime"`
+		SanityCheckInterval string     `json:"sanityCheckInterval"`
+		LogRetry            LogRetry   `json:"logRetry"`
+		Methods             Methods    `json:"methods"`
+		VerifyReceiptsRoot  bool       `json:"verifyReceiptsRoot"`
+		Quorum              int        `json:"quorum"`
+	}
+
+	return json.Marshal(Profile{
+		ChainID:             p.ChainID,
+		Name:                p.Name,
+		HeaderMode:          p.HeaderMode,
+		BlockTime:           p.BlockTime.String(),
+		SanityCheckInterval: p.SanityCheckInterval.String(),
+		LogRetry:            p.LogRetry,
+		Methods:             p.Methods,
+		VerifyReceiptsRoot:  p.VerifyReceiptsRoot,
+		Quorum:              p.Quorum,
+	})
+}
+
+// UnmarshalJSON unmarshals from JSON. Missing fields keep their current values,
+// so a profile can be decoded on top of a default one. Unknown fields are rejected.
+//
+//nolint:cyclop
+func (p *Profile) UnmarshalJSON(data []byte) error {
+	type Profile struct {
+		ChainID             *uint64     `json:"chainId"`
+		Name                *string     `json:"name"`
+		HeaderMode          *HeaderMode `json:"headerMode"`
+		BlockTime           *string     `json:"blockTime"`
+		SanityCheckInterval *string     `json:"sanityCheckInterval"`
+		LogRetry            *LogRetry   `json:"logRetry"`
+		Methods             *Methods    `json:"methods"`
+		VerifyReceiptsRoot  *bool       `json:"verifyReceiptsRoot"`
+		Quorum              *int        `json:"quorum"`
+	}
+
+	var dec Profile
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&dec); err != nil {
+		return err
+	}
+
+	if dec.ChainID == nil {
+		return errors.New("missing required field 'chainId' for Profile")
+	}
+	p.ChainID = *dec.ChainID
+
+	if dec.Name != nil {
+		p.Name = *dec.Name
+	}
+	if dec.HeaderMode != nil {
+		p.HeaderMode = *dec.HeaderMode
+	}
+	if dec.BlockTime != nil {
+		d, err := time.ParseDuration(*dec.BlockTime)
+		if err != nil {
+			return fmt.Errorf("invalid field 'blockTime' for Profile: %w", err)
+		}
+		p.BlockTime = d
+	}
+	if dec.SanityCheckInterval != nil {
+		d, err := time.ParseDuration(*dec.SanityCheckInterval)
+		if err != nil {
+			return fmt.Errorf("invalid field 'sanityCheckInterval' for Profile: %w", err)
+		}
+		p.SanityCheckInterval = d
+	}
+	if dec.LogRetry != nil {
+		p.LogRetry = *dec.LogRetry
+	}
+	if dec.Methods != nil {
+		p.Methods = *dec.Methods
+	}
+	if dec.VerifyReceiptsRoot != nil {
+		p.VerifyReceiptsRoot = *dec.VerifyReceiptsRoot
+	}
+	if dec.Quorum != nil {
+		p.Quorum = *dec.Quorum
+	}
+
+	return nil
+}
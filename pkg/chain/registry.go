@@ -0,0 +1,185 @@
+package chain
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Registry holds profiles of known chains.
+type Registry struct {
+	mu       sync.RWMutex
+	profiles map[uint64]Profile
+}
+
+// NewRegistry returns a new registry with given profiles.
+func NewRegistry(profiles ...Profile) (*Registry, error) {
+	r := &Registry{
+		profiles: make(map[uint64]Profile, len(profiles)),
+	}
+
+	for _, p := range profiles {
+		if err := r.Register(p); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// DefaultRegistry returns a new registry pre-loaded with built-in chain profiles.
+func DefaultRegistry() *Registry {
+	r, err := NewRegistry(defaultProfiles()...)
+	if err != nil {
+		panic(err)
+	}
+
+	return r
+}
+
+// LoadRegistry returns the default registry overridden by profiles declared in the given file.
+// The file is a JSON document with the format:
+//
+//	{"chains": [{"chainId": 1, "name": "Ethereum", "headerMode": "standard", "blockTime": "12s"}]}
+//
+// Fields that are missing from a declared profile keep their built-in values, unknown fields are rejected.
+func LoadRegistry(path string) (*Registry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var cfg struct {
+		Chains []json.RawMessage `json:"chains"`
+	}
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("decode chain config %s: %w", path, err)
+	}
+
+	r := DefaultRegistry()
+	for i, raw := range cfg.Chains {
+		var id struct {
+			ChainID uint64 `json:"chainId"`
+		}
+		if err = json.Unmarshal(raw, &id); err != nil {
+			return nil, fmt.Errorf("decode chain config %s at index %d: %w", path, i, err)
+		}
+
+		p := r.Get(id.ChainID)
+		if err = json.Unmarshal(raw, &p); err != nil {
+			return nil, fmt.Errorf("decode chain config %s at index %d: %w", path, i, err)
+		}
+
+		if err = r.Register(p); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// Register adds or replaces profile of a chain.
+func (r *Registry) Register(p Profile) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.profiles[p.ChainID] = p
+
+	return nil
+}
+
+// Lookup returns profile of the chain and whether it was declared in the registry.
+func (r *Registry) Lookup(chainID uint64) (Profile, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	p, ok := r.profiles[chainID]
+
+	return p, ok
+}
+
+// Get returns profile of the chain, or the default profile if the chain was not declared.
+func (r *Registry) Get(chainID uint64) Profile {
+	p, ok := r.Lookup(chainID)
+	if !ok {
+		return DefaultProfile(chainID)
+	}
+
+	return p
+}
+
+// Profiles returns all declared profiles ordered by chain id.
+func (r *Registry) Profiles() []Profile {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	profiles := make([]Profile, 0, len(r.profiles))
+	for _, p := range r.profiles {
+		profiles = append(profiles, p)
+	}
+
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].ChainID < profiles[j].ChainID
+	})
+
+	return profiles
+}
+
+func newProfile(chainID uint64, name string, blockTime time.Duration, opts ...func(*Profile)) Profile {
+	p := DefaultProfile(chainID)
+	p.Name = name
+	p.BlockTime = blockTime
+	for _, opt := range opts {
+		opt(&p)
+	}
+
+	return p
+}
+
+func withCustomHeader(p *Profile) {
+	p.HeaderMode = HeaderModeCustom
+}
+
+func withBlockReceipts(p *Profile) {
+	p.Methods.GetBlockReceipts = true
+}
+
+// withoutReceiptsRoot disables receipts root verification for chains with non-Ethereum receipt types.
+func withoutReceiptsRoot(p *Profile) {
+	p.VerifyReceiptsRoot = false
+}
+
+func withFinalizedTag(p *Profile) {
+	p.Methods.FinalizedTag = true
+}
+
+func defaultProfiles() []Profile {
+	return []Profile{
+		newProfile(1, "Ethereum", 12*time.Second, withBlockReceipts, withFinalizedTag),
+		newProfile(10, "Optimism", 2*time.Second, withBlockReceipts, withFinalizedTag, withoutReceiptsRoot),
+		newProfile(25, "Cronos", 6*time.Second),
+		newProfile(56, "BSC", 3*time.Second, withBlockReceipts, withFinalizedTag),
+		newProfile(106, "Velas", 5*time.Second),
+		newProfile(137, "Polygon", 2*time.Second, withBlockReceipts, withFinalizedTag),
+		newProfile(199, "BitTorrent", 2*time.Second),
+		newProfile(250, "Fantom", time.Second, withCustomHeader),
+		newProfile(324, "zkSync Era", time.Second, withCustomHeader),
+		newProfile(1101, "Polygon zkEVM", 5*time.Second),
+		newProfile(8453, "Base", 2*time.Second, withBlockReceipts, withFinalizedTag, withoutReceiptsRoot),
+		newProfile(42161, "Arbitrum", 250*time.Millisecond, withBlockReceipts, withFinalizedTag, withoutReceiptsRoot),
+		newProfile(42262, "Oasis", 6*time.Second),
+		newProfile(43114, "Avalanche", 2*time.Second, withCustomHeader, withBlockReceipts),
+		newProfile(59144, "Linea", 2*time.Second, withBlockReceipts, withFinalizedTag),
+		newProfile(1313161554, "Aurora", time.Second),
+	}
+}
@@ -0,0 +1,95 @@
+package chain
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/KyberNetwork/evmlistener/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultRegistry(t *testing.T) {
+	r := DefaultRegistry()
+
+	p, ok := r.Lookup(250)
+	require.True(t, ok)
+	assert.Equal(t, "Fantom", p.Name)
+	assert.Equal(t, HeaderModeCustom, p.HeaderMode)
+
+	p = r.Get(1)
+	assert.Equal(t, HeaderModeStandard, p.HeaderMode)
+	assert.True(t, p.Methods.GetBlockReceipts)
+	assert.True(t, p.Methods.FinalizedTag)
+	assert.True(t, p.VerifyReceiptsRoot)
+
+	// Deposit receipts of Optimism can not be verified against the receipts root.
+	p = r.Get(10)
+	assert.True(t, p.Methods.GetBlockReceipts)
+	assert.False(t, p.VerifyReceiptsRoot)
+
+	// Unknown chain falls back to default profile.
+	_, ok = r.Lookup(123456)
+	assert.False(t, ok)
+	p = r.Get(123456)
+	assert.Equal(t, "123456", p.Name)
+	assert.Equal(t, HeaderModeStandard, p.HeaderMode)
+	assert.Equal(t, defaultLogRetryMaxAttempts, p.LogRetry.MaxAttempts)
+}
+
+func TestLoadRegistry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chains.json")
+	data := `{"chains": [
+		{"chainId": 1, "sanityCheckInterval": "1m"},
+		{"chainId": 777, "name": "Devnet", "headerMode": "custom", "blockTime": "500ms",
+		 "logRetry": {"maxAttempts": 2, "retryOnEmpty": false}}
+	]}`
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+
+	r, err := LoadRegistry(path)
+	require.NoError(t, err)
+
+	// Overridden fields are applied on top of built-in profile.
+	p := r.Get(1)
+	assert.Equal(t, "Ethereum", p.Name)
+	assert.Equal(t, 12*time.Second, p.BlockTime)
+	assert.Equal(t, time.Minute, p.SanityCheckInterval)
+
+	p = r.Get(777)
+	assert.Equal(t, "Devnet", p.Name)
+	assert.Equal(t, HeaderModeCustom, p.HeaderMode)
+	assert.Equal(t, 500*time.Millisecond, p.BlockTime)
+	assert.Equal(t, LogRetry{MaxAttempts: 2, RetryOnEmpty: false}, p.LogRetry)
+}
+
+func TestLoadRegistryInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chains.json")
+	data := `{"chains": [{"chainId": 777, "headerMode": "unknown"}]}`
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+
+	_, err := LoadRegistry(path)
+	require.ErrorIs(t, err, errors.ErrInvalidArgument)
+}
+
+func TestLoadRegistryUnknownField(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"document", `{"chains": [], "chain": [{"chainId": 1}]}`},
+		{"profile", `{"chains": [{"chainId": 1, "blockInterval": "1s"}]}`},
+		{"methods", `{"chains": [{"chainId": 1, "methods": {"getBlockReceipt": true}}]}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "chains.json")
+			require.NoError(t, os.WriteFile(path, []byte(test.data), 0o600))
+
+			_, err := LoadRegistry(path)
+			require.ErrorContains(t, err, "unknown field")
+		})
+	}
+}
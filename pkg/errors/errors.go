@@ -7,6 +7,7 @@ var (
 	ErrInvalidArgument = errors.New("invalid argument")
 	ErrNotFound        = errors.New("not found")
 	ErrAlreadyExists   = errors.New("already exists")
+	ErrFenced          = errors.New("fenced")
 )
 
 // New wraps for errors.New function.
@@ -18,5 +19,8 @@ var As = errors.As //nolint:gochecknoglobals
 // Is wraps for errors.Is function.
 var Is = errors.Is //nolint:gochecknoglobals
 
+// Join wraps for errors.Join function.
+var Join = errors.Join //nolint:gochecknoglobals
+
 // Unwrap wraps for errors.Unwrap function.
 var Unwrap = errors.Unwrap //nolint:gochecknoglobals
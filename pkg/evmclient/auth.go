@@ -0,0 +1,208 @@
+package evmclient
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KyberNetwork/evmlistener/pkg/errors"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	jwtSecretLength = 32
+	maskedValue     = "***"
+)
+
+// Auth contains authentication of a rpc endpoint. It is applied to every HTTP request,
+// and to the handshake of websocket connections.
+type Auth struct {
+	// Headers are static headers, e.g. API keys.
+	Headers map[string]string `json:"headers,omitempty"`
+	// BearerTokenFile is path to a file containing a bearer token. The file is read again
+	// whenever it is modified, so that the token can be rotated without restarting.
+	BearerTokenFile string `json:"bearerTokenFile,omitempty"`
+	// JWTSecretFile is path to a file containing a hex encoded 32 bytes secret. A HS256 JWT
+	// is issued with the secret for every request, as the Engine API does.
+	JWTSecretFile string `json:"jwtSecretFile,omitempty"`
+}
+
+// IsZero reports whether no authentication is configured.
+func (a Auth) IsZero() bool {
+	return len(a.Headers) == 0 && a.BearerTokenFile == "" && a.JWTSecretFile == ""
+}
+
+// Masked returns a copy of the auth for logging, with values of headers masked.
+func (a Auth) Masked() Auth {
+	if len(a.Headers) > 0 {
+		headers := make(map[string]string, len(a.Headers))
+		for k := range a.Headers {
+			headers[k] = maskedValue
+		}
+		a.Headers = headers
+	}
+
+	return a
+}
+
+// clientOptions returns options of the rpc client applying the auth.
+func (a Auth) clientOptions() ([]rpc.ClientOption, error) {
+	if a.BearerTokenFile != "" && a.JWTSecretFile != "" {
+		return nil, fmt.Errorf("%w: bearer token and jwt secret can not be used together",
+			errors.ErrInvalidArgument)
+	}
+
+	var opts []rpc.ClientOption
+	if len(a.Headers) > 0 {
+		headers := make(http.Header, len(a.Headers))
+		for k, v := range a.Headers {
+			headers.Set(k, v)
+		}
+		opts = append(opts, rpc.WithHeaders(headers))
+	}
+
+	switch {
+	case a.BearerTokenFile != "":
+		token := &tokenFile{path: a.BearerTokenFile}
+		if _, err := token.get(); err != nil {
+			return nil, err
+		}
+		opts = append(opts, rpc.WithHTTPAuth(bearerAuth(token)))
+	case a.JWTSecretFile != "":
+		secret, err := readJWTSecret(a.JWTSecretFile)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, rpc.WithHTTPAuth(jwtAuth(secret)))
+	}
+
+	return opts, nil
+}
+
+func bearerAuth(token *tokenFile) rpc.HTTPAuth {
+	return func(h http.Header) error {
+		s, err := token.get()
+		if err != nil {
+			return err
+		}
+
+		h.Set("Authorization", "Bearer "+s)
+
+		return nil
+	}
+}
+
+func jwtAuth(secret []byte) rpc.HTTPAuth {
+	return func(h http.Header) error {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"iat": &jwt.NumericDate{Time: time.Now()},
+		})
+
+		s, err := token.SignedString(secret)
+		if err != nil {
+			return fmt.Errorf("fail to create jwt: %w", err)
+		}
+
+		h.Set("Authorization", "Bearer "+s)
+
+		return nil
+	}
+}
+
+func readJWTSecret(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := hexutil.Decode("0x" + strings.TrimPrefix(strings.TrimSpace(string(data)), "0x"))
+	if err != nil || len(secret) != jwtSecretLength {
+		return nil, fmt.Errorf("%w: jwt secret in %s must be %d bytes hex encoded",
+			errors.ErrInvalidArgument, path, jwtSecretLength)
+	}
+
+	return secret, nil
+}
+
+// tokenFile is a token read from a file, it is read again when the file is modified.
+type tokenFile struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	token   string
+}
+
+func (t *tokenFile) get() (string, error) {
+	info, err := os.Stat(t.path)
+	if err != nil {
+		return "", err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.token != "" && info.ModTime().Equal(t.modTime) {
+		return t.token, nil
+	}
+
+	data, err := os.ReadFile(t.path)
+	if err != nil {
+		return "", err
+	}
+
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("%w: bearer token file %s is empty", errors.ErrInvalidArgument, t.path)
+	}
+	t.token, t.modTime = token, info.ModTime()
+
+	return t.token, nil
+}
+
+// ParseAuth parses authentication from a spec of items separated by semicolons, each item is one of
+// "header:<name>=<value>", "bearer-token-file:<path>" or "jwt-secret-file:<path>". An empty spec means
+// no authentication.
+func ParseAuth(spec string) (Auth, error) {
+	var auth Auth
+	for i, item := range strings.Split(spec, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		// Items may contain secrets, errors only report the index of an item.
+		kind, value, ok := strings.Cut(item, ":")
+		if !ok {
+			return Auth{}, fmt.Errorf("%w: invalid auth item #%d", errors.ErrInvalidArgument, i+1)
+		}
+		if value == "" {
+			return Auth{}, fmt.Errorf("%w: invalid auth item #%d", errors.ErrInvalidArgument, i+1)
+		}
+
+		switch kind {
+		case "header":
+			name, v, ok := strings.Cut(value, "=")
+			if !ok || name == "" {
+				return Auth{}, fmt.Errorf("%w: invalid auth header in item #%d", errors.ErrInvalidArgument, i+1)
+			}
+			if auth.Headers == nil {
+				auth.Headers = make(map[string]string)
+			}
+			auth.Headers[strings.TrimSpace(name)] = strings.TrimSpace(v)
+		case "bearer-token-file":
+			auth.BearerTokenFile = value
+		case "jwt-secret-file":
+			auth.JWTSecretFile = value
+		default:
+			return Auth{}, fmt.Errorf("%w: unknown auth item #%d", errors.ErrInvalidArgument, i+1)
+		}
+	}
+
+	return auth, nil
+}
@@ -0,0 +1,138 @@
+package evmclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/KyberNetwork/evmlistener/pkg/errors"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/suite"
+)
+
+type chainIDService struct{}
+
+//nolint:revive,stylecheck
+func (chainIDService) ChainId() hexutil.Uint64 {
+	return 1
+}
+
+type AuthTestSuite struct {
+	suite.Suite
+
+	server *httptest.Server
+	mu     sync.Mutex
+	header http.Header
+}
+
+func (ts *AuthTestSuite) SetupTest() {
+	rpcServer := rpc.NewServer()
+	ts.Require().NoError(rpcServer.RegisterName("eth", chainIDService{}))
+
+	ts.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ts.mu.Lock()
+		ts.header = r.Header.Clone()
+		ts.mu.Unlock()
+
+		rpcServer.ServeHTTP(w, r)
+	}))
+}
+
+func (ts *AuthTestSuite) TearDownTest() {
+	ts.server.Close()
+}
+
+func (ts *AuthTestSuite) lastHeader(name string) string {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	return ts.header.Get(name)
+}
+
+func (ts *AuthTestSuite) writeFile(name, content string) string {
+	path := filepath.Join(ts.T().TempDir(), name)
+	ts.Require().NoError(os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+func (ts *AuthTestSuite) TestParseAuth() {
+	auth, err := ParseAuth("header:X-Api-Key=secret; jwt-secret-file:/run/jwt.hex")
+	ts.Require().NoError(err)
+	ts.Assert().Equal(Auth{
+		Headers:       map[string]string{"X-Api-Key": "secret"},
+		JWTSecretFile: "/run/jwt.hex",
+	}, auth)
+	ts.Assert().Equal(map[string]string{"X-Api-Key": maskedValue}, auth.Masked().Headers)
+	ts.Assert().Equal("secret", auth.Headers["X-Api-Key"])
+
+	auth, err = ParseAuth("")
+	ts.Require().NoError(err)
+	ts.Assert().True(auth.IsZero())
+
+	_, err = ParseAuth("password:secret")
+	ts.Assert().Error(err)
+
+	// Invalid items are not quoted, as they may contain secrets.
+	for _, spec := range []string{"Bearer sk-secret", "Authorization=secret", "header:secret", "secret:", "secret:value"} {
+		_, err = ParseAuth(spec)
+		ts.Require().ErrorIs(err, errors.ErrInvalidArgument)
+		ts.Assert().NotContains(err.Error(), "secret")
+	}
+}
+
+func (ts *AuthTestSuite) TestHeaders() {
+	_, err := Dial(ts.server.URL, http.DefaultClient, WithAuth(Auth{
+		Headers: map[string]string{"X-Api-Key": "secret"},
+	}))
+	ts.Require().NoError(err)
+	ts.Assert().Equal("secret", ts.lastHeader("X-Api-Key"))
+}
+
+func (ts *AuthTestSuite) TestBearerTokenFile() {
+	path := ts.writeFile("token", "first\n")
+	token := &tokenFile{path: path}
+
+	h := make(http.Header)
+	ts.Require().NoError(bearerAuth(token)(h))
+	ts.Assert().Equal("Bearer first", h.Get("Authorization"))
+
+	_, err := Dial(ts.server.URL, http.DefaultClient, WithAuth(Auth{BearerTokenFile: path}))
+	ts.Require().NoError(err)
+	ts.Assert().Equal("Bearer first", ts.lastHeader("Authorization"))
+
+	_, err = Dial(ts.server.URL, http.DefaultClient, WithAuth(Auth{BearerTokenFile: path + ".missing"}))
+	ts.Assert().Error(err)
+}
+
+func (ts *AuthTestSuite) TestJWT() {
+	secret := strings.Repeat("ab", jwtSecretLength)
+	path := ts.writeFile("jwt.hex", "0x"+secret)
+
+	_, err := Dial(ts.server.URL, http.DefaultClient, WithAuth(Auth{JWTSecretFile: path}))
+	ts.Require().NoError(err)
+
+	bearer, ok := strings.CutPrefix(ts.lastHeader("Authorization"), "Bearer ")
+	ts.Require().True(ok)
+	token, err := jwt.Parse(bearer, func(*jwt.Token) (interface{}, error) {
+		return hexutil.MustDecode("0x" + secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	ts.Require().NoError(err)
+	ts.Assert().True(token.Valid)
+
+	_, err = Dial(ts.server.URL, http.DefaultClient, WithAuth(Auth{JWTSecretFile: ts.writeFile("short", "0x01")}))
+	ts.Assert().Error(err)
+
+	_, err = Dial(ts.server.URL, http.DefaultClient, WithAuth(Auth{JWTSecretFile: path, BearerTokenFile: path}))
+	ts.Assert().Error(err)
+}
+
+func TestAuthTestSuite(t *testing.T) {
+	suite.Run(t, new(AuthTestSuite))
+}
@@ -0,0 +1,133 @@
+package evmclient
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+
+	"github.com/KyberNetwork/evmlistener/pkg/chain"
+	"github.com/KyberNetwork/evmlistener/pkg/errors"
+	commonclient "github.com/KyberNetwork/evmlistener/pkg/evmclient/common"
+	"github.com/KyberNetwork/evmlistener/pkg/types"
+	"github.com/ethereum/go-ethereum"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ErrBatchNotSupported is returned when batch request is not supported by the client.
+var ErrBatchNotSupported = errors.New("batch request is not supported")
+
+// BatchClient is an interface for EVM client that supports JSON-RPC batch requests.
+type BatchClient interface {
+	// BatchHeadersByNumber returns headers for given block numbers in a single batch request.
+	BatchHeadersByNumber(context.Context, []*big.Int) ([]*types.Header, error)
+	// BatchFilterLogs executes given filter queries in a single batch request.
+	BatchFilterLogs(context.Context, []FilterQuery) ([][]types.Log, error)
+}
+
+func toFilterArg(q FilterQuery) interface{} {
+	fq := toEthereumFilterQuery(q)
+	arg := map[string]interface{}{
+		"address": fq.Addresses,
+		"topics":  fq.Topics,
+	}
+
+	if fq.BlockHash != nil {
+		arg["blockHash"] = *fq.BlockHash
+	} else {
+		if fq.FromBlock == nil {
+			arg["fromBlock"] = "0x0"
+		} else {
+			arg["fromBlock"] = commonclient.ToBlockNumArg(fq.FromBlock)
+		}
+		arg["toBlock"] = commonclient.ToBlockNumArg(fq.ToBlock)
+	}
+
+	return arg
+}
+
+func (c *Client) decodeHeader(raw json.RawMessage) (*types.Header, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, ethereum.NotFound
+	}
+
+	switch c.profile.HeaderMode {
+	case chain.HeaderModeCustom:
+		var header commonclient.Header
+		if err := json.Unmarshal(raw, &header); err != nil {
+			return nil, err
+		}
+
+		return fromCustomHeader(&header), nil
+	default:
+		var header ethtypes.Header
+		if err := json.Unmarshal(raw, &header); err != nil {
+			return nil, err
+		}
+
+		return fromEthereumHeader(&header), nil
+	}
+}
+
+// BatchHeadersByNumber returns headers for given block numbers in a single batch request.
+// It fails if any of the headers can not be fetched.
+func (c *Client) BatchHeadersByNumber(ctx context.Context, numbers []*big.Int) ([]*types.Header, error) {
+	results := make([]json.RawMessage, len(numbers))
+	elems := make([]rpc.BatchElem, 0, len(numbers))
+	for i, number := range numbers {
+		elems = append(elems, rpc.BatchElem{
+			Method: "eth_getBlockByNumber",
+			Args:   []interface{}{commonclient.ToBlockNumArg(number), false},
+			Result: &results[i],
+		})
+	}
+
+	if err := c.rpcClient.BatchCallContext(ctx, elems); err != nil {
+		return nil, err
+	}
+
+	headers := make([]*types.Header, 0, len(numbers))
+	for i, elem := range elems {
+		if elem.Error != nil {
+			return nil, elem.Error
+		}
+
+		header, err := c.decodeHeader(results[i])
+		if err != nil {
+			return nil, err
+		}
+
+		headers = append(headers, header)
+	}
+
+	return headers, nil
+}
+
+// BatchFilterLogs executes given filter queries in a single batch request.
+// It fails if any of the queries fails.
+func (c *Client) BatchFilterLogs(ctx context.Context, queries []FilterQuery) ([][]types.Log, error) {
+	results := make([][]ethtypes.Log, len(queries))
+	elems := make([]rpc.BatchElem, 0, len(queries))
+	for i, q := range queries {
+		elems = append(elems, rpc.BatchElem{
+			Method: "eth_getLogs",
+			Args:   []interface{}{toFilterArg(q)},
+			Result: &results[i],
+		})
+	}
+
+	if err := c.rpcClient.BatchCallContext(ctx, elems); err != nil {
+		return nil, err
+	}
+
+	logs := make([][]types.Log, 0, len(queries))
+	for i, elem := range elems {
+		if elem.Error != nil {
+			return nil, elem.Error
+		}
+
+		logs = append(logs, fromEthereumLogs(results[i]))
+	}
+
+	return logs, nil
+}
@@ -7,7 +7,7 @@ import (
 	"net/http"
 	"time"
 
-	"github.com/KyberNetwork/evmlistener/pkg/common"
+	"github.com/KyberNetwork/evmlistener/pkg/chain"
 	commonclient "github.com/KyberNetwork/evmlistener/pkg/evmclient/common"
 	"github.com/KyberNetwork/evmlistener/pkg/types"
 	ethcommon "github.com/ethereum/go-ethereum/common"
@@ -16,12 +16,6 @@ import (
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
-const (
-	chainIDFantom    = 250
-	chainIDAvalanche = 43114
-	chainIDZKSync    = 324
-)
-
 type FilterQuery struct {
 	BlockHash *string
 	FromBlock *big.Int
@@ -52,16 +46,25 @@ type IClient interface {
 
 type Client struct {
 	chainID      uint64
+	profile      chain.Profile
+	rpcClient    *rpc.Client
 	ethClient    *ethclient.Client
 	customClient *commonclient.Client
 }
 
-func Dial(rawurl string, httpClient *http.Client) (*Client, error) {
-	return DialContext(context.Background(), rawurl, httpClient)
+func Dial(rawurl string, httpClient *http.Client, opts ...Option) (*Client, error) {
+	return DialContext(context.Background(), rawurl, httpClient, opts...)
 }
 
-func DialContext(ctx context.Context, rawurl string, httpClient *http.Client) (*Client, error) {
-	rpcClient, err := rpc.DialOptions(ctx, rawurl, rpc.WithHTTPClient(httpClient))
+func DialContext(ctx context.Context, rawurl string, httpClient *http.Client, opts ...Option) (*Client, error) {
+	o := newOptions(opts...)
+
+	authOpts, err := o.auth.clientOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	rpcClient, err := rpc.DialOptions(ctx, rawurl, append(authOpts, rpc.WithHTTPClient(httpClient))...)
 	if err != nil {
 		return nil, err
 	}
@@ -73,20 +76,18 @@ func DialContext(ctx context.Context, rawurl string, httpClient *http.Client) (*
 	}
 
 	client := &Client{
-		chainID: chainID.Uint64(),
+		chainID:   chainID.Uint64(),
+		profile:   o.registry.Get(chainID.Uint64()),
+		rpcClient: rpcClient,
 	}
 
-	switch client.chainID {
-	case chainIDFantom, chainIDAvalanche, chainIDZKSync:
+	switch client.profile.HeaderMode {
+	case chain.HeaderModeCustom:
 		client.customClient = commonclient.NewClient(rpcClient)
 	default:
 		client.ethClient = ethClient
 	}
 
-	if err != nil {
-		return nil, err
-	}
-
 	return client, nil
 }
 
@@ -95,6 +96,7 @@ func DialContextWithTimeout(
 	rawurl string,
 	httpClient *http.Client,
 	timeout time.Duration,
+	opts ...Option,
 ) (*Client, error) {
 	type dialContextResponse struct {
 		client *Client
@@ -103,7 +105,7 @@ func DialContextWithTimeout(
 
 	ch := make(chan dialContextResponse, 1)
 	go func() {
-		client, err := DialContext(ctx, rawurl, httpClient)
+		client, err := DialContext(ctx, rawurl, httpClient, opts...)
 		ch <- dialContextResponse{
 			client: client,
 			err:    err,
@@ -124,9 +126,14 @@ func (c *Client) ChainID(ctx context.Context) (*big.Int, error) {
 	return new(big.Int).SetUint64(c.chainID), nil
 }
 
+// Profile returns profile of the chain that the client connected to.
+func (c *Client) Profile() chain.Profile {
+	return c.profile
+}
+
 func (c *Client) BlockNumber(ctx context.Context) (uint64, error) {
-	switch c.chainID {
-	case chainIDFantom, chainIDAvalanche, chainIDZKSync:
+	switch c.profile.HeaderMode {
+	case chain.HeaderModeCustom:
 		return c.customClient.BlockNumber(ctx)
 	default:
 		return c.ethClient.BlockNumber(ctx)
@@ -135,8 +142,8 @@ func (c *Client) BlockNumber(ctx context.Context) (uint64, error) {
 
 //nolint:cyclop,ireturn,gocognit
 func (c *Client) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (Subscription, error) {
-	switch c.chainID {
-	case chainIDFantom, chainIDAvalanche, chainIDZKSync:
+	switch c.profile.HeaderMode {
+	case chain.HeaderModeCustom:
 		headerCh := make(chan *commonclient.Header)
 		sub, err := c.customClient.SubscribeNewHead(ctx, headerCh)
 		if err != nil {
@@ -149,12 +156,7 @@ func (c *Client) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header)
 				case <-ctx.Done():
 					return
 				case header := <-headerCh:
-					ch <- &types.Header{
-						Hash:       common.ToHex(header.Hash),
-						ParentHash: common.ToHex(header.ParentHash),
-						Number:     header.Number,
-						Time:       header.Time,
-					}
+					ch <- fromCustomHeader(header)
 				}
 			}
 		}()
@@ -173,12 +175,7 @@ func (c *Client) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header)
 				case <-ctx.Done():
 					return
 				case header := <-headerCh:
-					ch <- &types.Header{
-						Hash:       common.ToHex(header.Hash()),
-						ParentHash: common.ToHex(header.ParentHash),
-						Number:     header.Number,
-						Time:       header.Time,
-					}
+					ch <- fromEthereumHeader(header)
 				}
 			}
 		}()
@@ -188,8 +185,8 @@ func (c *Client) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header)
 }
 
 func (c *Client) FilterLogs(ctx context.Context, q FilterQuery) ([]types.Log, error) {
-	switch c.chainID {
-	case chainIDFantom, chainIDAvalanche, chainIDZKSync:
+	switch c.profile.HeaderMode {
+	case chain.HeaderModeCustom:
 		return filterLogs(ctx, c.customClient, q)
 	default:
 		return filterLogs(ctx, c.ethClient, q)
@@ -197,59 +194,39 @@ func (c *Client) FilterLogs(ctx context.Context, q FilterQuery) ([]types.Log, er
 }
 
 func (c *Client) HeaderByHash(ctx context.Context, hash string) (*types.Header, error) {
-	switch c.chainID {
-	case chainIDFantom, chainIDAvalanche, chainIDZKSync:
+	switch c.profile.HeaderMode {
+	case chain.HeaderModeCustom:
 		header, err := c.customClient.HeaderByHash(ctx, ethcommon.HexToHash(hash))
 		if err != nil {
 			return nil, err
 		}
 
-		return &types.Header{
-			Hash:       common.ToHex(header.Hash),
-			ParentHash: common.ToHex(header.ParentHash),
-			Number:     header.Number,
-			Time:       header.Time,
-		}, nil
+		return fromCustomHeader(header), nil
 	default:
 		header, err := c.ethClient.HeaderByHash(ctx, ethcommon.HexToHash(hash))
 		if err != nil {
 			return nil, err
 		}
 
-		return &types.Header{
-			Hash:       common.ToHex(header.Hash()),
-			ParentHash: common.ToHex(header.ParentHash),
-			Number:     header.Number,
-			Time:       header.Time,
-		}, nil
+		return fromEthereumHeader(header), nil
 	}
 }
 
 func (c *Client) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
-	switch c.chainID {
-	case chainIDFantom, chainIDAvalanche, chainIDZKSync:
+	switch c.profile.HeaderMode {
+	case chain.HeaderModeCustom:
 		header, err := c.customClient.HeaderByNumber(ctx, number)
 		if err != nil {
 			return nil, err
 		}
 
-		return &types.Header{
-			Hash:       common.ToHex(header.Hash),
-			ParentHash: common.ToHex(header.ParentHash),
-			Number:     header.Number,
-			Time:       header.Time,
-		}, nil
+		return fromCustomHeader(header), nil
 	default:
 		header, err := c.ethClient.HeaderByNumber(ctx, number)
 		if err != nil {
 			return nil, err
 		}
 
-		return &types.Header{
-			Hash:       common.ToHex(header.Hash()),
-			ParentHash: common.ToHex(header.ParentHash),
-			Number:     header.Number,
-			Time:       header.Time,
-		}, nil
+		return fromEthereumHeader(header), nil
 	}
 }
@@ -161,7 +161,7 @@ func (h *Header) UnmarshalJSON(data []byte) error {
 
 func (c *Client) HeaderByNumber(ctx context.Context, number *big.Int) (*Header, error) {
 	var head *Header
-	err := c.c.CallContext(ctx, &head, "eth_getBlockByNumber", toBlockNumArg(number), false)
+	err := c.c.CallContext(ctx, &head, "eth_getBlockByNumber", ToBlockNumArg(number), false)
 	if err == nil && head == nil {
 		err = ethereum.NotFound
 	}
@@ -186,7 +186,8 @@ func (c *Client) SubscribeNewHead(
 	return c.c.EthSubscribe(ctx, ch, "newHeads")
 }
 
-func toBlockNumArg(number *big.Int) string {
+// ToBlockNumArg returns the JSON-RPC argument for given block number.
+func ToBlockNumArg(number *big.Int) string {
 	if number == nil {
 		return "latest"
 	}
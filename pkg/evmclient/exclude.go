@@ -0,0 +1,88 @@
+package evmclient
+
+import (
+	"context"
+	"sync"
+)
+
+type endpointTrackerKey struct{}
+
+// endpointTracker records endpoints serving requests made with a context, so that following requests
+// can be sent to other endpoints.
+type endpointTracker struct {
+	mu       sync.Mutex
+	served   map[string]struct{}
+	excluded map[string]struct{}
+}
+
+// WithEndpointTracking returns a context which records endpoints of a FailoverClient serving requests
+// made with it, see ExcludeServedEndpoints.
+func WithEndpointTracking(ctx context.Context) context.Context {
+	return context.WithValue(ctx, endpointTrackerKey{}, &endpointTracker{
+		served:   make(map[string]struct{}),
+		excluded: make(map[string]struct{}),
+	})
+}
+
+// ExcludeServedEndpoints makes following requests made with ctx avoid the endpoints which served
+// requests made with it so far, e.g. when their responses turn out to be incomplete. Excluded endpoints
+// are still used when no other endpoint is available. It reports whether any endpoint was excluded.
+func ExcludeServedEndpoints(ctx context.Context) bool {
+	t := trackerFromContext(ctx)
+	if t == nil {
+		return false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var excluded bool
+	for name := range t.served {
+		if _, ok := t.excluded[name]; !ok {
+			t.excluded[name] = struct{}{}
+			excluded = true
+		}
+	}
+	clear(t.served)
+
+	return excluded
+}
+
+func trackerFromContext(ctx context.Context) *endpointTracker {
+	t, _ := ctx.Value(endpointTrackerKey{}).(*endpointTracker)
+
+	return t
+}
+
+func (t *endpointTracker) serve(name string) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.served[name] = struct{}{}
+}
+
+// order moves excluded endpoints to the end, keeping the order of the others.
+func (t *endpointTracker) order(endpoints []*endpointState) []*endpointState {
+	if t == nil {
+		return endpoints
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	res := make([]*endpointState, 0, len(endpoints))
+	excluded := make([]*endpointState, 0, len(t.excluded))
+	for _, e := range endpoints {
+		if _, ok := t.excluded[e.Name]; ok {
+			excluded = append(excluded, e)
+		} else {
+			res = append(res, e)
+		}
+	}
+
+	return append(res, excluded...)
+}
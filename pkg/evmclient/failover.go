@@ -0,0 +1,555 @@
+package evmclient
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/KyberNetwork/evmlistener/pkg/types"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultFailoverMaxAttempts   = 3
+	defaultFailoverProbeInterval = 10 * time.Second
+	defaultMaxGapFill            = 128
+
+	// ewmaWeight is the weight of the latest sample in exponentially weighted moving averages.
+	ewmaWeight = 0.2
+	// errorPenalty is the score added for an endpoint that always fails.
+	errorPenalty = 5.0
+	// lagPenalty is the score added for each block an endpoint is behind the highest known head.
+	lagPenalty = 1.0
+)
+
+var errNoEndpoint = errors.New("no endpoint available")
+
+// Endpoint is a named EVM client.
+type Endpoint struct {
+	Name   string
+	Client IClient
+}
+
+type endpointState struct {
+	Endpoint
+
+	mu        sync.Mutex
+	latency   float64 // in seconds
+	errorRate float64
+	head      uint64
+}
+
+func (e *endpointState) record(latency time.Duration, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.latency = (1-ewmaWeight)*e.latency + ewmaWeight*latency.Seconds()
+	e.recordErrorLocked(err)
+}
+
+// recordError records the result of a request without a latency sample, e.g. a subscription failure.
+func (e *endpointState) recordError(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.recordErrorLocked(err)
+}
+
+func (e *endpointState) recordErrorLocked(err error) {
+	var v float64
+	if err != nil {
+		v = 1
+	}
+	e.errorRate = (1-ewmaWeight)*e.errorRate + ewmaWeight*v
+}
+
+func (e *endpointState) setHead(head uint64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if head > e.head {
+		e.head = head
+	}
+}
+
+func (e *endpointState) score(maxHead uint64) float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var lag float64
+	if maxHead > e.head {
+		lag = float64(maxHead - e.head)
+	}
+
+	return e.latency + e.errorRate*errorPenalty + lag*lagPenalty
+}
+
+func (e *endpointState) getHead() uint64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.head
+}
+
+// FailoverClient is an EVM client that sends requests to the healthiest of its endpoints.
+// Endpoints are scored by latency, error rate and how far their head is behind the others.
+// A failed request is retried on a different endpoint.
+type FailoverClient struct {
+	l           *zap.SugaredLogger
+	endpoints   []*endpointState
+	maxAttempts int
+	maxGapFill  uint64
+}
+
+// NewFailoverClient returns a new FailoverClient for given endpoints.
+func NewFailoverClient(l *zap.SugaredLogger, endpoints []Endpoint) (*FailoverClient, error) {
+	if len(endpoints) == 0 {
+		return nil, errNoEndpoint
+	}
+
+	states := make([]*endpointState, 0, len(endpoints))
+	for _, e := range endpoints {
+		states = append(states, &endpointState{Endpoint: e})
+	}
+
+	maxAttempts := defaultFailoverMaxAttempts
+	if maxAttempts > len(endpoints) {
+		maxAttempts = len(endpoints)
+	}
+
+	return &FailoverClient{
+		l:           l,
+		endpoints:   states,
+		maxAttempts: maxAttempts,
+		maxGapFill:  defaultMaxGapFill,
+	}, nil
+}
+
+// Run probes all endpoints periodically so that stats of endpoints which are not in use stay fresh.
+// It blocks until the context is canceled.
+func (c *FailoverClient) Run(ctx context.Context) {
+	ticker := time.NewTicker(defaultFailoverProbeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			var wg sync.WaitGroup
+			for _, e := range c.endpoints {
+				wg.Add(1)
+				go func(e *endpointState) {
+					defer wg.Done()
+					_, _ = c.call(ctx, e, func(client IClient) (uint64, error) {
+						return client.BlockNumber(ctx)
+					})
+				}(e)
+			}
+			wg.Wait()
+		}
+	}
+}
+
+func (c *FailoverClient) maxHead() uint64 {
+	var head uint64
+	for _, e := range c.endpoints {
+		if h := e.getHead(); h > head {
+			head = h
+		}
+	}
+
+	return head
+}
+
+// ranked returns endpoints ordered from the healthiest to the least healthy one.
+func (c *FailoverClient) ranked() []*endpointState {
+	maxHead := c.maxHead()
+
+	type scored struct {
+		e     *endpointState
+		score float64
+	}
+	items := make([]scored, 0, len(c.endpoints))
+	for _, e := range c.endpoints {
+		items = append(items, scored{e: e, score: e.score(maxHead)})
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].score < items[j].score
+	})
+
+	res := make([]*endpointState, 0, len(items))
+	for _, item := range items {
+		res = append(res, item.e)
+	}
+
+	return res
+}
+
+func (c *FailoverClient) call(
+	ctx context.Context, e *endpointState, fn func(IClient) (uint64, error),
+) (uint64, error) {
+	start := time.Now()
+	head, err := fn(e.Client)
+	if ctx.Err() != nil {
+		// Do not penalize endpoint for a canceled request.
+		return head, err
+	}
+
+	if isNotSupported(err) {
+		// The endpoint lacks an optional capability, it is not unhealthy.
+		return head, err
+	}
+
+	e.record(time.Since(start), err)
+	if err == nil && head > 0 {
+		e.setHead(head)
+	}
+
+	return head, err
+}
+
+// isNotSupported returns whether err reports that a client lacks an optional capability.
+func isNotSupported(err error) bool {
+	return errors.Is(err, ErrBatchNotSupported) || errors.Is(err, ErrReceiptsNotSupported)
+}
+
+// do executes fn on the healthiest endpoints until it succeeds or maximum number of attempts is reached.
+// Endpoints excluded for the context are tried last. Endpoints that do not support the request
+// are skipped without using an attempt.
+// fn returns the block number observed by the request (0 if unknown) for tracking endpoint head.
+func (c *FailoverClient) do(ctx context.Context, method string, fn func(IClient) (uint64, error)) error {
+	var lastErr, notSupportedErr error
+	tracker := trackerFromContext(ctx)
+	attempts := 0
+	for _, e := range tracker.order(c.ranked()) {
+		if attempts >= c.maxAttempts {
+			break
+		}
+
+		_, err := c.call(ctx, e, fn)
+		if err == nil {
+			tracker.serve(e.Name)
+
+			return nil
+		}
+
+		if ctx.Err() != nil {
+			return err
+		}
+
+		if isNotSupported(err) {
+			notSupportedErr = err
+
+			continue
+		}
+
+		attempts++
+		lastErr = err
+		c.l.Warnw("Request to endpoint failed, fail over to next endpoint",
+			"method", method, "endpoint", e.Name, "attempt", attempts, "error", err)
+	}
+
+	if lastErr == nil {
+		return notSupportedErr
+	}
+
+	return lastErr
+}
+
+// BlockNumber returns the most recent block number.
+func (c *FailoverClient) BlockNumber(ctx context.Context) (uint64, error) {
+	var res uint64
+	err := c.do(ctx, "BlockNumber", func(client IClient) (uint64, error) {
+		var err error
+		res, err = client.BlockNumber(ctx)
+
+		return res, err
+	})
+
+	return res, err
+}
+
+// FilterLogs executes a filter query.
+func (c *FailoverClient) FilterLogs(ctx context.Context, q FilterQuery) ([]types.Log, error) {
+	var res []types.Log
+	err := c.do(ctx, "FilterLogs", func(client IClient) (uint64, error) {
+		var err error
+		res, err = client.FilterLogs(ctx, q)
+
+		return 0, err
+	})
+
+	return res, err
+}
+
+// HeaderByHash returns the block header with the given hash.
+func (c *FailoverClient) HeaderByHash(ctx context.Context, hash string) (*types.Header, error) {
+	var res *types.Header
+	err := c.do(ctx, "HeaderByHash", func(client IClient) (uint64, error) {
+		var err error
+		res, err = client.HeaderByHash(ctx, hash)
+		if err != nil {
+			return 0, err
+		}
+
+		return res.Number.Uint64(), nil
+	})
+
+	return res, err
+}
+
+// HeaderByNumber returns a block header from the current canonical chain.
+// If number is nil, the latest known header is returned.
+func (c *FailoverClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	var res *types.Header
+	err := c.do(ctx, "HeaderByNumber", func(client IClient) (uint64, error) {
+		var err error
+		res, err = client.HeaderByNumber(ctx, number)
+		if err != nil {
+			return 0, err
+		}
+
+		return res.Number.Uint64(), nil
+	})
+
+	return res, err
+}
+
+// SubscribeNewHead subscribes to notifications about the current blockchain head on
+// the healthiest endpoint. When the subscription fails, it moves to the next endpoint and
+// fills the gap between the last received header and the first header from the new endpoint.
+//
+//nolint:ireturn
+func (c *FailoverClient) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (Subscription, error) {
+	sub := newManagedSubscription()
+
+	// Make sure at least one endpoint accepts the subscription before returning.
+	inner, innerCh, e, err := c.subscribe(ctx, sub.quit)
+	if err != nil {
+		return nil, err
+	}
+
+	go c.runSubscription(ctx, sub, ch, inner, innerCh, e)
+
+	return sub, nil
+}
+
+type innerSubscription struct {
+	Subscription
+	cancel context.CancelFunc
+}
+
+func (s innerSubscription) Unsubscribe() {
+	s.Subscription.Unsubscribe()
+	s.cancel()
+}
+
+func (c *FailoverClient) subscribe(
+	ctx context.Context, quit <-chan struct{},
+) (innerSubscription, chan *types.Header, *endpointState, error) {
+	err := errNoEndpoint
+	for _, e := range c.ranked() {
+		select {
+		case <-quit:
+			return innerSubscription{}, nil, nil, err
+		default:
+		}
+
+		subCtx, cancel := context.WithCancel(ctx)
+		headerCh := make(chan *types.Header, 1)
+
+		var sub Subscription
+		start := time.Now()
+		sub, err = e.Client.SubscribeNewHead(subCtx, headerCh)
+		e.record(time.Since(start), err)
+		if err == nil {
+			c.l.Infow("Subscribe new head on endpoint", "endpoint", e.Name)
+
+			return innerSubscription{Subscription: sub, cancel: cancel}, headerCh, e, nil
+		}
+
+		cancel()
+		c.l.Warnw("Fail to subscribe new head on endpoint", "endpoint", e.Name, "error", err)
+	}
+
+	return innerSubscription{}, nil, nil, err
+}
+
+//nolint:cyclop
+func (c *FailoverClient) runSubscription(
+	ctx context.Context, sub *managedSubscription, ch chan<- *types.Header,
+	inner innerSubscription, innerCh chan *types.Header, e *endpointState,
+) {
+	defer close(sub.done)
+
+	var last *types.Header
+	for {
+		select {
+		case <-ctx.Done():
+			inner.Unsubscribe()
+
+			return
+		case <-sub.quit:
+			inner.Unsubscribe()
+
+			return
+		case err := <-inner.Err():
+			inner.Unsubscribe()
+			e.recordError(err)
+			c.l.Warnw("Subscription on endpoint failed, fail over to next endpoint",
+				"endpoint", e.Name, "error", err)
+
+			var subErr error
+			inner, innerCh, e, subErr = c.subscribe(ctx, sub.quit)
+			if subErr != nil {
+				if err == nil {
+					err = subErr
+				}
+				sub.errCh <- err
+
+				return
+			}
+		case header := <-innerCh:
+			e.setHead(header.Number.Uint64())
+			if !c.emit(ctx, sub, ch, e, last, header) {
+				inner.Unsubscribe()
+
+				return
+			}
+			last = header
+		}
+	}
+}
+
+// emit sends header to the channel, preceded by any headers missing between last and header.
+func (c *FailoverClient) emit(
+	ctx context.Context, sub *managedSubscription, ch chan<- *types.Header,
+	e *endpointState, last, header *types.Header,
+) bool {
+	headers := []*types.Header{header}
+	if last != nil {
+		from := last.Number.Uint64() + 1
+		to := header.Number.Uint64()
+		if to > from && to-from <= c.maxGapFill {
+			c.l.Infow("Fill missing headers after failover", "endpoint", e.Name, "from", from, "to", to-1)
+			gap := make([]*types.Header, 0, to-from+1)
+			for n := from; n < to; n++ {
+				h, err := e.Client.HeaderByNumber(ctx, new(big.Int).SetUint64(n))
+				if err != nil {
+					// A truncated gap is not sent, the listener fills the gap from parent hashes instead.
+					c.l.Warnw("Fail to fill missing header, send the new header only",
+						"endpoint", e.Name, "number", n, "error", err)
+					gap = nil
+
+					break
+				}
+				gap = append(gap, h)
+			}
+			headers = append(gap, header)
+		}
+	}
+
+	for _, h := range headers {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-sub.quit:
+			return false
+		case ch <- h:
+		}
+	}
+
+	return true
+}
+
+// managedSubscription is a Subscription driven by a background goroutine, which closes done when it exits.
+type managedSubscription struct {
+	errCh chan error
+	quit  chan struct{}
+	done  chan struct{}
+	once  sync.Once
+}
+
+func newManagedSubscription() *managedSubscription {
+	return &managedSubscription{
+		errCh: make(chan error, 1),
+		quit:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+}
+
+// Unsubscribe cancels the subscription and closes the error channel.
+func (s *managedSubscription) Unsubscribe() {
+	s.once.Do(func() {
+		close(s.quit)
+		<-s.done
+		close(s.errCh)
+	})
+}
+
+// Err returns the subscription error channel.
+func (s *managedSubscription) Err() <-chan error {
+	return s.errCh
+}
+
+// BatchHeadersByNumber returns headers for given block numbers using batch request
+// on the healthiest endpoint that supports it.
+func (c *FailoverClient) BatchHeadersByNumber(ctx context.Context, numbers []*big.Int) ([]*types.Header, error) {
+	var res []*types.Header
+	err := c.do(ctx, "BatchHeadersByNumber", func(client IClient) (uint64, error) {
+		batchClient, ok := client.(BatchClient)
+		if !ok {
+			return 0, ErrBatchNotSupported
+		}
+
+		var err error
+		res, err = batchClient.BatchHeadersByNumber(ctx, numbers)
+
+		return 0, err
+	})
+
+	return res, err
+}
+
+// BatchFilterLogs executes given filter queries using batch request
+// on the healthiest endpoint that supports it.
+func (c *FailoverClient) BatchFilterLogs(ctx context.Context, queries []FilterQuery) ([][]types.Log, error) {
+	var res [][]types.Log
+	err := c.do(ctx, "BatchFilterLogs", func(client IClient) (uint64, error) {
+		batchClient, ok := client.(BatchClient)
+		if !ok {
+			return 0, ErrBatchNotSupported
+		}
+
+		var err error
+		res, err = batchClient.BatchFilterLogs(ctx, queries)
+
+		return 0, err
+	})
+
+	return res, err
+}
+
+// BlockReceiptLogs returns all logs of the block with given hash from its receipts
+// on the healthiest endpoint that supports it.
+func (c *FailoverClient) BlockReceiptLogs(ctx context.Context, hash string, receiptsRoot string) ([]types.Log, error) {
+	var res []types.Log
+	err := c.do(ctx, "BlockReceiptLogs", func(client IClient) (uint64, error) {
+		receiptsClient, ok := client.(ReceiptsClient)
+		if !ok {
+			return 0, ErrReceiptsNotSupported
+		}
+
+		var err error
+		res, err = receiptsClient.BlockReceiptLogs(ctx, hash, receiptsRoot)
+
+		return 0, err
+	})
+
+	return res, err
+}
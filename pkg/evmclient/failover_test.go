@@ -0,0 +1,364 @@
+package evmclient
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KyberNetwork/evmlistener/pkg/types"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+)
+
+type fakeSubscription struct {
+	errCh chan error
+}
+
+func (s *fakeSubscription) Unsubscribe() {}
+
+func (s *fakeSubscription) Err() <-chan error {
+	return s.errCh
+}
+
+// fakeClient serves a linear chain of headers whose hash is derived from the block number.
+type fakeClient struct {
+	mu      sync.Mutex
+	head    uint64
+	delay   time.Duration
+	err     error
+	calls   int
+	subChs  []chan<- *types.Header
+	subErrs []chan error
+	// headerErr fails HeaderByNumber requests only.
+	headerErr error
+}
+
+func fakeHeader(n uint64) *types.Header {
+	return &types.Header{
+		Hash:       big.NewInt(int64(n)).Text(16),
+		ParentHash: big.NewInt(int64(n) - 1).Text(16),
+		Number:     new(big.Int).SetUint64(n),
+		Time:       n,
+	}
+}
+
+func (c *fakeClient) result() error {
+	c.mu.Lock()
+	c.calls++
+	delay, err := c.delay, c.err
+	c.mu.Unlock()
+
+	time.Sleep(delay)
+
+	return err
+}
+
+func (c *fakeClient) BlockNumber(context.Context) (uint64, error) {
+	if err := c.result(); err != nil {
+		return 0, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.head, nil
+}
+
+//nolint:ireturn
+func (c *fakeClient) SubscribeNewHead(_ context.Context, ch chan<- *types.Header) (Subscription, error) {
+	if err := c.result(); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sub := &fakeSubscription{errCh: make(chan error, 1)}
+	c.subChs = append(c.subChs, ch)
+	c.subErrs = append(c.subErrs, sub.errCh)
+
+	return sub, nil
+}
+
+func (c *fakeClient) FilterLogs(context.Context, FilterQuery) ([]types.Log, error) {
+	return nil, c.result()
+}
+
+func (c *fakeClient) HeaderByHash(context.Context, string) (*types.Header, error) {
+	return nil, c.result()
+}
+
+func (c *fakeClient) HeaderByNumber(_ context.Context, number *big.Int) (*types.Header, error) {
+	if err := c.result(); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.headerErr != nil {
+		return nil, c.headerErr
+	}
+
+	if number == nil {
+		return fakeHeader(c.head), nil
+	}
+
+	return fakeHeader(number.Uint64()), nil
+}
+
+func (c *fakeClient) push(n uint64) {
+	c.mu.Lock()
+	c.head = n
+	chs := c.subChs
+	c.mu.Unlock()
+
+	for _, ch := range chs {
+		ch <- fakeHeader(n)
+	}
+}
+
+func (c *fakeClient) fail(err error) {
+	c.mu.Lock()
+	c.err = err
+	errChs := c.subErrs
+	c.subChs, c.subErrs = nil, nil
+	c.mu.Unlock()
+
+	for _, errCh := range errChs {
+		errCh <- err
+	}
+}
+
+func (c *fakeClient) numCalls() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.calls
+}
+
+type FailoverClientTestSuite struct {
+	suite.Suite
+
+	primary   *fakeClient
+	secondary *fakeClient
+	client    *FailoverClient
+}
+
+func (ts *FailoverClientTestSuite) SetupTest() {
+	ts.primary = &fakeClient{head: 100}
+	ts.secondary = &fakeClient{head: 100, delay: 10 * time.Millisecond}
+
+	client, err := NewFailoverClient(zap.NewNop().Sugar(), []Endpoint{
+		{Name: "primary", Client: ts.primary},
+		{Name: "secondary", Client: ts.secondary},
+	})
+	ts.Require().NoError(err)
+	ts.client = client
+}
+
+func (ts *FailoverClientTestSuite) TestFailover() {
+	ctx := context.Background()
+
+	// Warm up stats, secondary is slower.
+	for range 3 {
+		_, err := ts.client.BlockNumber(ctx)
+		ts.Require().NoError(err)
+	}
+	ts.Require().Equal(3, ts.primary.numCalls())
+	ts.Require().Equal(0, ts.secondary.numCalls())
+
+	// Requests are retried on secondary when primary fails.
+	ts.primary.fail(errors.New("connection reset"))
+	n, err := ts.client.BlockNumber(ctx)
+	ts.Require().NoError(err)
+	ts.Assert().Equal(uint64(100), n)
+	ts.Assert().Equal(1, ts.secondary.numCalls())
+
+	// Primary is now penalized, so requests go to secondary first.
+	_, err = ts.client.BlockNumber(ctx)
+	ts.Require().NoError(err)
+	ts.Assert().Equal(4, ts.primary.numCalls())
+	ts.Assert().Equal(2, ts.secondary.numCalls())
+}
+
+func (ts *FailoverClientTestSuite) TestSubscribeNewHead() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := make(chan *types.Header, 16)
+	sub, err := ts.client.SubscribeNewHead(ctx, ch)
+	ts.Require().NoError(err)
+	defer sub.Unsubscribe()
+
+	ts.primary.push(101)
+	ts.Assert().Equal(uint64(101), (<-ch).Number.Uint64())
+
+	// Primary subscription drops, the secondary continues without gap.
+	ts.primary.fail(errors.New("websocket: close 1006"))
+	ts.Require().Eventually(func() bool {
+		ts.secondary.mu.Lock()
+		defer ts.secondary.mu.Unlock()
+
+		return len(ts.secondary.subChs) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	ts.secondary.push(104)
+	for _, expected := range []uint64{102, 103, 104} {
+		ts.Assert().Equal(expected, (<-ch).Number.Uint64())
+	}
+
+	// Subscription error is reported once all endpoints are down.
+	ts.secondary.fail(errors.New("websocket: close 1001"))
+	select {
+	case err = <-sub.Err():
+		ts.Assert().Error(err)
+	case <-time.After(time.Second):
+		ts.Fail("expect subscription error")
+	}
+}
+
+func (ts *FailoverClientTestSuite) TestFillGapFailure() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := make(chan *types.Header, 16)
+	sub, err := ts.client.SubscribeNewHead(ctx, ch)
+	ts.Require().NoError(err)
+	defer sub.Unsubscribe()
+
+	ts.primary.push(101)
+	ts.Require().Equal(uint64(101), (<-ch).Number.Uint64())
+
+	ts.primary.fail(errors.New("websocket: close 1006"))
+	ts.Require().Eventually(func() bool {
+		ts.secondary.mu.Lock()
+		defer ts.secondary.mu.Unlock()
+
+		return len(ts.secondary.subChs) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// Missing headers can not be fetched, the new header is sent alone rather than after a partial gap.
+	ts.secondary.mu.Lock()
+	ts.secondary.headerErr = errors.New("header not found")
+	ts.secondary.mu.Unlock()
+
+	ts.secondary.push(104)
+	ts.Assert().Equal(uint64(104), (<-ch).Number.Uint64())
+	ts.Assert().Empty(ch)
+}
+
+func (ts *FailoverClientTestSuite) TestSubscriptionErrorLatency() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	primary := ts.client.endpoints[0]
+	ts.primary.delay = 20 * time.Millisecond
+
+	ch := make(chan *types.Header, 16)
+	sub, err := ts.client.SubscribeNewHead(ctx, ch)
+	ts.Require().NoError(err)
+	defer sub.Unsubscribe()
+
+	primary.mu.Lock()
+	latency := primary.latency
+	primary.mu.Unlock()
+	ts.Require().Positive(latency)
+
+	// A subscription error counts as an error, but is not a latency sample.
+	ts.primary.fail(errors.New("websocket: close 1006"))
+	ts.Require().Eventually(func() bool {
+		ts.secondary.mu.Lock()
+		defer ts.secondary.mu.Unlock()
+
+		return len(ts.secondary.subChs) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	primary.mu.Lock()
+	defer primary.mu.Unlock()
+	ts.Assert().Equal(latency, primary.latency)
+	ts.Assert().Positive(primary.errorRate)
+}
+
+func (ts *FailoverClientTestSuite) TestExcludeServedEndpoints() {
+	ctx := WithEndpointTracking(context.Background())
+
+	_, err := ts.client.BlockNumber(ctx)
+	ts.Require().NoError(err)
+	ts.Assert().Equal(1, ts.primary.numCalls())
+
+	// Following requests avoid the endpoint which served the first one.
+	ts.Require().True(ExcludeServedEndpoints(ctx))
+	_, err = ts.client.BlockNumber(ctx)
+	ts.Require().NoError(err)
+	ts.Assert().Equal(1, ts.primary.numCalls())
+	ts.Assert().Equal(1, ts.secondary.numCalls())
+
+	// Excluded endpoints are still used when no other endpoint is available.
+	ts.Require().True(ExcludeServedEndpoints(ctx))
+	_, err = ts.client.BlockNumber(ctx)
+	ts.Require().NoError(err)
+	ts.Assert().Equal(2, ts.primary.numCalls())
+
+	// Other contexts are not affected.
+	_, err = ts.client.BlockNumber(context.Background())
+	ts.Require().NoError(err)
+	ts.Assert().Equal(3, ts.primary.numCalls())
+}
+
+// batchFakeClient is a fakeClient that supports batch requests.
+type batchFakeClient struct {
+	*fakeClient
+}
+
+func (c batchFakeClient) BatchHeadersByNumber(_ context.Context, numbers []*big.Int) ([]*types.Header, error) {
+	if err := c.result(); err != nil {
+		return nil, err
+	}
+
+	headers := make([]*types.Header, 0, len(numbers))
+	for _, n := range numbers {
+		headers = append(headers, fakeHeader(n.Uint64()))
+	}
+
+	return headers, nil
+}
+
+func (c batchFakeClient) BatchFilterLogs(_ context.Context, queries []FilterQuery) ([][]types.Log, error) {
+	return make([][]types.Log, len(queries)), c.result()
+}
+
+func (ts *FailoverClientTestSuite) TestSkipNotSupportedEndpoints() {
+	ctx := context.Background()
+	secondary := batchFakeClient{fakeClient: ts.secondary}
+	client, err := NewFailoverClient(zap.NewNop().Sugar(), []Endpoint{
+		{Name: "primary", Client: ts.primary},
+		{Name: "secondary", Client: secondary},
+	})
+	ts.Require().NoError(err)
+
+	for range 3 {
+		headers, err := client.BatchHeadersByNumber(ctx, []*big.Int{big.NewInt(99), big.NewInt(100)})
+		ts.Require().NoError(err)
+		ts.Assert().Len(headers, 2)
+	}
+
+	// Primary does not support batch requests, but it is not penalized for that.
+	ts.Assert().Zero(client.endpoints[0].errorRate)
+	ts.Assert().Zero(client.endpoints[1].errorRate)
+	_, err = client.BlockNumber(ctx)
+	ts.Require().NoError(err)
+	ts.Assert().Equal(1, ts.primary.numCalls())
+
+	// Missing capability is reported when no endpoint supports it.
+	_, err = client.BlockReceiptLogs(ctx, "0x1", "")
+	ts.Assert().ErrorIs(err, ErrReceiptsNotSupported)
+	ts.Assert().Zero(client.endpoints[0].errorRate)
+}
+
+func TestFailoverClientTestSuite(t *testing.T) {
+	suite.Run(t, new(FailoverClientTestSuite))
+}
@@ -0,0 +1,181 @@
+package evmclient
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/KyberNetwork/evmlistener/pkg/labels"
+	"github.com/KyberNetwork/evmlistener/pkg/retry"
+	"github.com/KyberNetwork/evmlistener/pkg/types"
+	pkgmetric "github.com/KyberNetwork/kyber-trace-go/pkg/metric"
+	pkgtracer "github.com/KyberNetwork/kyber-trace-go/pkg/tracer"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	metricNameRPCRequestDuration = "evmlistener_rpc_request_duration"
+	metricNameRPCRequestErrors   = "evmlistener_rpc_request_errors"
+)
+
+// ErrorClass returns the class of an error returned by a rpc request, for metrics.
+func ErrorClass(err error) string {
+	return string(retry.Classify(err))
+}
+
+// InstrumentedClient is an EVM client which records duration and errors of every request as metrics,
+// and a trace span for each request.
+type InstrumentedClient struct {
+	IClient
+
+	endpoint string
+	duration metric.Float64Histogram
+	errors   metric.Int64Counter
+}
+
+// NewInstrumentedClient returns an instrumented client for given client, labeled by endpoint name.
+func NewInstrumentedClient(client IClient, endpoint string) (*InstrumentedClient, error) {
+	duration, err := pkgmetric.Meter().Float64Histogram(metricNameRPCRequestDuration,
+		metric.WithDescription("Duration of rpc requests"),
+		metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+
+	errs, err := pkgmetric.Meter().Int64Counter(metricNameRPCRequestErrors,
+		metric.WithDescription("Number of failed rpc requests"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &InstrumentedClient{
+		IClient:  client,
+		endpoint: endpoint,
+		duration: duration,
+		errors:   errs,
+	}, nil
+}
+
+// observe runs fn for method inside a span, recording its duration and error.
+func (c *InstrumentedClient) observe(ctx context.Context, method string, fn func(context.Context) error) error {
+	attrs := labels.Attributes(ctx,
+		attribute.String("method", method),
+		attribute.String("endpoint", c.endpoint),
+	)
+
+	ctx, span := pkgtracer.Tracer().Start(ctx, "evmclient."+method,
+		trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	defer span.End()
+
+	start := time.Now()
+	err := fn(ctx)
+
+	// Metrics are recorded even if the request was canceled.
+	ctx = context.WithoutCancel(ctx)
+	c.duration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attrs...))
+	if err != nil {
+		class := ErrorClass(err)
+		c.errors.Add(ctx, 1, metric.WithAttributes(append(attrs, attribute.String("class", class))...))
+		span.RecordError(err, trace.WithAttributes(attribute.String("class", class)))
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	return err
+}
+
+func (c *InstrumentedClient) BlockNumber(ctx context.Context) (res uint64, err error) {
+	err = c.observe(ctx, "BlockNumber", func(ctx context.Context) error {
+		res, err = c.IClient.BlockNumber(ctx)
+
+		return err
+	})
+
+	return res, err
+}
+
+func (c *InstrumentedClient) FilterLogs(ctx context.Context, q FilterQuery) (res []types.Log, err error) {
+	err = c.observe(ctx, "FilterLogs", func(ctx context.Context) error {
+		res, err = c.IClient.FilterLogs(ctx, q)
+
+		return err
+	})
+
+	return res, err
+}
+
+func (c *InstrumentedClient) HeaderByHash(ctx context.Context, hash string) (res *types.Header, err error) {
+	err = c.observe(ctx, "HeaderByHash", func(ctx context.Context) error {
+		res, err = c.IClient.HeaderByHash(ctx, hash)
+
+		return err
+	})
+
+	return res, err
+}
+
+func (c *InstrumentedClient) HeaderByNumber(ctx context.Context, number *big.Int) (res *types.Header, err error) {
+	err = c.observe(ctx, "HeaderByNumber", func(ctx context.Context) error {
+		res, err = c.IClient.HeaderByNumber(ctx, number)
+
+		return err
+	})
+
+	return res, err
+}
+
+// BatchHeadersByNumber forwards the batch request if the underlying client supports it.
+func (c *InstrumentedClient) BatchHeadersByNumber(
+	ctx context.Context, numbers []*big.Int,
+) (res []*types.Header, err error) {
+	batchClient, ok := c.IClient.(BatchClient)
+	if !ok {
+		return nil, ErrBatchNotSupported
+	}
+
+	err = c.observe(ctx, "BatchHeadersByNumber", func(ctx context.Context) error {
+		res, err = batchClient.BatchHeadersByNumber(ctx, numbers)
+
+		return err
+	})
+
+	return res, err
+}
+
+// BatchFilterLogs forwards the batch request if the underlying client supports it.
+func (c *InstrumentedClient) BatchFilterLogs(
+	ctx context.Context, queries []FilterQuery,
+) (res [][]types.Log, err error) {
+	batchClient, ok := c.IClient.(BatchClient)
+	if !ok {
+		return nil, ErrBatchNotSupported
+	}
+
+	err = c.observe(ctx, "BatchFilterLogs", func(ctx context.Context) error {
+		res, err = batchClient.BatchFilterLogs(ctx, queries)
+
+		return err
+	})
+
+	return res, err
+}
+
+// BlockReceiptLogs forwards the request if the underlying client supports it.
+func (c *InstrumentedClient) BlockReceiptLogs(
+	ctx context.Context, hash string, receiptsRoot string,
+) (res []types.Log, err error) {
+	receiptsClient, ok := c.IClient.(ReceiptsClient)
+	if !ok {
+		return nil, ErrReceiptsNotSupported
+	}
+
+	err = c.observe(ctx, "BlockReceiptLogs", func(ctx context.Context) error {
+		res, err = receiptsClient.BlockReceiptLogs(ctx, hash, receiptsRoot)
+
+		return err
+	})
+
+	return res, err
+}
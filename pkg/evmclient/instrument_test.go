@@ -0,0 +1,54 @@
+package evmclient
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/suite"
+)
+
+type InstrumentedClientTestSuite struct {
+	suite.Suite
+
+	node   *fakeClient
+	client *InstrumentedClient
+}
+
+func (ts *InstrumentedClientTestSuite) SetupTest() {
+	ts.node = &fakeClient{head: 100}
+
+	var err error
+	ts.client, err = NewInstrumentedClient(ts.node, "http://localhost")
+	ts.Require().NoError(err)
+}
+
+func (ts *InstrumentedClientTestSuite) TestForward() {
+	ctx := context.Background()
+	number, err := ts.client.BlockNumber(ctx)
+	ts.Require().NoError(err)
+	ts.Assert().Equal(uint64(100), number)
+
+	header, err := ts.client.HeaderByNumber(ctx, big.NewInt(99))
+	ts.Require().NoError(err)
+	ts.Assert().Equal(fakeHeader(99), header)
+
+	ts.node.fail(ethereum.NotFound)
+	_, err = ts.client.HeaderByHash(ctx, "0x01")
+	ts.Assert().ErrorIs(err, ethereum.NotFound)
+
+	// The fake client does not support batch requests.
+	_, err = ts.client.BatchHeadersByNumber(ctx, []*big.Int{big.NewInt(1)})
+	ts.Assert().ErrorIs(err, ErrBatchNotSupported)
+}
+
+func (ts *InstrumentedClientTestSuite) TestErrorClass() {
+	ts.Assert().Equal("not_found", ErrorClass(ethereum.NotFound))
+	ts.Assert().Equal("rate_limited", ErrorClass(rpc.HTTPError{StatusCode: 429}))
+}
+
+func TestInstrumentedClientTestSuite(t *testing.T) {
+	suite.Run(t, new(InstrumentedClientTestSuite))
+}
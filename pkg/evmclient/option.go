@@ -0,0 +1,40 @@
+package evmclient
+
+import (
+	"github.com/KyberNetwork/evmlistener/pkg/chain"
+)
+
+// Option is an option for dialing a Client.
+type Option func(o *options)
+
+type options struct {
+	registry *chain.Registry
+	auth     Auth
+}
+
+func newOptions(opts ...Option) *options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.registry == nil {
+		o.registry = chain.DefaultRegistry()
+	}
+
+	return &o
+}
+
+// WithChainRegistry sets the registry for looking up profile of the connected chain.
+func WithChainRegistry(r *chain.Registry) Option {
+	return func(o *options) {
+		o.registry = r
+	}
+}
+
+// WithAuth sets authentication of the rpc endpoint.
+func WithAuth(auth Auth) Option {
+	return func(o *options) {
+		o.auth = auth
+	}
+}
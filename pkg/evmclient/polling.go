@@ -0,0 +1,155 @@
+package evmclient
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/KyberNetwork/evmlistener/pkg/types"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultPollBlockTime   = 2 * time.Second
+	defaultMinPollInterval = 100 * time.Millisecond
+	defaultMaxPollInterval = 10 * time.Second
+)
+
+// PollingClient is an EVM client which provides new heads by polling the node,
+// for providers that do not support websocket subscriptions.
+type PollingClient struct {
+	IClient
+
+	l           *zap.SugaredLogger
+	blockTime   time.Duration
+	minInterval time.Duration
+	maxInterval time.Duration
+	maxGapFill  uint64
+}
+
+// NewPollingClient returns a polling client over given client. The blockTime is the initial
+// estimation of the chain block time, the polling interval is adjusted to the observed block time.
+func NewPollingClient(l *zap.SugaredLogger, client IClient, blockTime time.Duration) *PollingClient {
+	if blockTime <= 0 {
+		blockTime = defaultPollBlockTime
+	}
+
+	return &PollingClient{
+		IClient:     client,
+		l:           l,
+		blockTime:   blockTime,
+		minInterval: defaultMinPollInterval,
+		maxInterval: defaultMaxPollInterval,
+		maxGapFill:  defaultMaxGapFill,
+	}
+}
+
+// SubscribeNewHead polls the node for new heads and sends them to given channel in order,
+// including every intermediate header between two polls.
+//
+//nolint:ireturn
+func (c *PollingClient) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (Subscription, error) {
+	// Make sure the node is reachable before returning.
+	if _, err := c.IClient.BlockNumber(ctx); err != nil {
+		return nil, err
+	}
+
+	sub := newManagedSubscription()
+	go c.poll(ctx, sub, ch)
+
+	return sub, nil
+}
+
+func (c *PollingClient) interval(blockTime time.Duration) time.Duration {
+	// Poll twice per block to keep the delay low without hammering the node.
+	return min(max(blockTime/2, c.minInterval), c.maxInterval) //nolint:gomnd
+}
+
+//nolint:cyclop
+func (c *PollingClient) poll(ctx context.Context, sub *managedSubscription, ch chan<- *types.Header) {
+	defer close(sub.done)
+
+	var last *types.Header
+	var lastTime time.Time
+	blockTime := c.blockTime
+	interval := c.interval(blockTime)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sub.quit:
+			return
+		case <-timer.C:
+		}
+
+		headers, err := c.newHeaders(ctx, last)
+		if err != nil {
+			// Transient errors are retried with backoff, the listener detects a stale head by itself.
+			c.l.Warnw("Fail to poll new heads", "error", err)
+			interval = min(interval*2, c.maxInterval) //nolint:gomnd
+			timer.Reset(interval)
+
+			continue
+		}
+
+		if len(headers) > 0 {
+			now := time.Now()
+			if last != nil {
+				observed := now.Sub(lastTime) / time.Duration(len(headers))
+				blockTime = time.Duration((1-ewmaWeight)*float64(blockTime) + ewmaWeight*float64(observed))
+			}
+			last, lastTime = headers[len(headers)-1], now
+
+			for _, header := range headers {
+				select {
+				case ch <- header:
+				case <-ctx.Done():
+					return
+				case <-sub.quit:
+					return
+				}
+			}
+		}
+
+		interval = c.interval(blockTime)
+		timer.Reset(interval)
+	}
+}
+
+// newHeaders returns headers after the last one up to the latest block, in order.
+func (c *PollingClient) newHeaders(ctx context.Context, last *types.Header) ([]*types.Header, error) {
+	blockNumber, err := c.IClient.BlockNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	from := blockNumber
+	if last != nil {
+		if blockNumber <= last.Number.Uint64() {
+			return nil, nil
+		}
+
+		from = last.Number.Uint64() + 1
+	}
+
+	if blockNumber-from >= c.maxGapFill {
+		c.l.Warnw("Too many new heads since last poll, skip the oldest ones",
+			"from", from, "to", blockNumber, "maxGapFill", c.maxGapFill)
+		from = blockNumber - c.maxGapFill + 1
+	}
+
+	headers := make([]*types.Header, 0, blockNumber-from+1)
+	for n := from; n <= blockNumber; n++ {
+		header, err := c.IClient.HeaderByNumber(ctx, new(big.Int).SetUint64(n))
+		if err != nil {
+			return nil, err
+		}
+
+		headers = append(headers, header)
+	}
+
+	return headers, nil
+}
@@ -0,0 +1,83 @@
+package evmclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/KyberNetwork/evmlistener/pkg/types"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+)
+
+type PollingClientTestSuite struct {
+	suite.Suite
+
+	node   *fakeClient
+	client *PollingClient
+}
+
+func (ts *PollingClientTestSuite) SetupTest() {
+	ts.node = &fakeClient{head: 100}
+	ts.client = NewPollingClient(zap.S(), ts.node, 10*time.Millisecond)
+	ts.client.minInterval = time.Millisecond
+	ts.client.maxInterval = 20 * time.Millisecond
+}
+
+func (ts *PollingClientTestSuite) receive(ch <-chan *types.Header, numbers ...uint64) {
+	for _, n := range numbers {
+		select {
+		case header := <-ch:
+			ts.Require().Equal(n, header.Number.Uint64())
+		case <-time.After(time.Second):
+			ts.FailNow("timeout waiting for header", "number", n)
+		}
+	}
+}
+
+func (ts *PollingClientTestSuite) TestSubscribeNewHead() {
+	ch := make(chan *types.Header, 1)
+	sub, err := ts.client.SubscribeNewHead(context.Background(), ch)
+	ts.Require().NoError(err)
+
+	// The latest header is sent first, then every new header in order.
+	ts.receive(ch, 100)
+	ts.node.push(105)
+	ts.receive(ch, 101, 102, 103, 104, 105)
+
+	// Polling errors are retried.
+	ts.node.fail(errors.New("connection refused"))
+	time.Sleep(30 * time.Millisecond)
+	ts.node.fail(nil)
+	ts.node.push(107)
+	ts.receive(ch, 106, 107)
+
+	sub.Unsubscribe()
+	_, ok := <-sub.Err()
+	ts.Assert().False(ok)
+}
+
+func (ts *PollingClientTestSuite) TestMaxGapFill() {
+	ts.client.maxGapFill = 3
+
+	ch := make(chan *types.Header, 1)
+	sub, err := ts.client.SubscribeNewHead(context.Background(), ch)
+	ts.Require().NoError(err)
+	defer sub.Unsubscribe()
+
+	ts.receive(ch, 100)
+	ts.node.push(110)
+	ts.receive(ch, 108, 109, 110)
+}
+
+func (ts *PollingClientTestSuite) TestSubscribeError() {
+	ts.node.fail(errors.New("connection refused"))
+
+	_, err := ts.client.SubscribeNewHead(context.Background(), make(chan *types.Header))
+	ts.Assert().Error(err)
+}
+
+func TestPollingClientTestSuite(t *testing.T) {
+	suite.Run(t, new(PollingClientTestSuite))
+}
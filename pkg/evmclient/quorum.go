@@ -0,0 +1,346 @@
+package evmclient
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KyberNetwork/evmlistener/pkg/errors"
+	"github.com/KyberNetwork/evmlistener/pkg/labels"
+	"github.com/KyberNetwork/evmlistener/pkg/types"
+	pkgmetric "github.com/KyberNetwork/kyber-trace-go/pkg/metric"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+)
+
+const (
+	metricNameQuorumDisagreements = "evmlistener_rpc_quorum_disagreements"
+
+	blockNumberGracePeriod = 200 * time.Millisecond
+)
+
+// ErrQuorumNotReached is returned when not enough providers agree on a result.
+var ErrQuorumNotReached = errors.New("quorum not reached")
+
+// QuorumClient is an EVM client that cross-checks results between providers. A request is sent
+// to quorum providers at first, and to the remaining ones only when some of them fail or disagree.
+// A result is accepted only when quorum providers return the same block hash (or the same log set).
+type QuorumClient struct {
+	l             *zap.SugaredLogger
+	endpoints     []Endpoint
+	quorum        int
+	disagreements metric.Int64Counter
+}
+
+// NewQuorumClient returns a new QuorumClient which requires quorum of the given endpoints to agree.
+func NewQuorumClient(l *zap.SugaredLogger, endpoints []Endpoint, quorum int) (*QuorumClient, error) {
+	if quorum <= 0 || quorum > len(endpoints) {
+		return nil, fmt.Errorf("%w: quorum %d with %d endpoints",
+			errors.ErrInvalidArgument, quorum, len(endpoints))
+	}
+
+	disagreements, err := pkgmetric.Meter().Int64Counter(metricNameQuorumDisagreements,
+		metric.WithDescription("Number of requests that providers returned different results"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &QuorumClient{
+		l:             l,
+		endpoints:     endpoints,
+		quorum:        quorum,
+		disagreements: disagreements,
+	}, nil
+}
+
+type quorumResult struct {
+	endpoint string
+	key      string
+	value    interface{}
+	err      error
+}
+
+// query sends fn to endpoints until quorum of them returns results with the same key.
+//
+//nolint:cyclop
+func (c *QuorumClient) query(
+	ctx context.Context, method string, fn func(context.Context, IClient) (string, interface{}, error),
+) (interface{}, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	resCh := make(chan quorumResult, len(c.endpoints))
+	launched, pending := 0, 0
+	launch := func(n int) {
+		for ; n > 0 && launched < len(c.endpoints); n-- {
+			e := c.endpoints[launched]
+			launched++
+			pending++
+			go func() {
+				key, value, err := fn(ctx, e.Client)
+				resCh <- quorumResult{endpoint: e.Name, key: key, value: value, err: err}
+			}()
+		}
+	}
+
+	launch(c.quorum)
+
+	var lastErr error
+	counts := make(map[string]int)
+	results := make([]quorumResult, 0, len(c.endpoints))
+	for pending > 0 {
+		res := <-resCh
+		pending--
+		results = append(results, res)
+
+		if res.err != nil {
+			lastErr = res.err
+		} else {
+			counts[res.key]++
+			if counts[res.key] >= c.quorum {
+				if len(counts) > 1 {
+					c.reportDisagreement(ctx, method, results)
+				}
+
+				return res.value, nil
+			}
+		}
+
+		// Launch more requests if quorum can not be reached with pending ones.
+		if needed := c.quorum - maxCount(counts); needed > pending {
+			launch(needed - pending)
+		}
+	}
+
+	if len(counts) > 1 {
+		c.reportDisagreement(ctx, method, results)
+	}
+
+	if len(counts) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+
+	return nil, fmt.Errorf("%w: method %s, %d of %d agreed",
+		ErrQuorumNotReached, method, maxCount(counts), c.quorum)
+}
+
+func maxCount(counts map[string]int) int {
+	best := 0
+	for _, n := range counts {
+		if n > best {
+			best = n
+		}
+	}
+
+	return best
+}
+
+func (c *QuorumClient) reportDisagreement(ctx context.Context, method string, results []quorumResult) {
+	//nolint:contextcheck
+	c.disagreements.Add(context.WithoutCancel(ctx), 1,
+		metric.WithAttributes(labels.Attributes(ctx, attribute.String("method", method))...))
+
+	keys := make([]string, 0, len(results))
+	for _, res := range results {
+		if res.err != nil {
+			keys = append(keys, res.endpoint+"=error:"+res.err.Error())
+		} else {
+			keys = append(keys, res.endpoint+"="+res.key)
+		}
+	}
+	c.l.Warnw("Providers disagree on result", "method", method, "results", keys)
+}
+
+// BlockNumber returns the highest block number that quorum providers have reached. Once quorum
+// providers have answered, the others are waited for at most blockNumberGracePeriod, so that a slow
+// provider does not delay the result.
+//
+//nolint:cyclop
+func (c *QuorumClient) BlockNumber(ctx context.Context) (uint64, error) {
+	type result struct {
+		number uint64
+		err    error
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ch := make(chan result, len(c.endpoints))
+	for _, e := range c.endpoints {
+		go func(client IClient) {
+			n, err := client.BlockNumber(ctx)
+			ch <- result{number: n, err: err}
+		}(e.Client)
+	}
+
+	var (
+		lastErr error
+		failed  int
+		grace   <-chan time.Time
+	)
+	numbers := make([]uint64, 0, len(c.endpoints))
+loop:
+	for range c.endpoints {
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-grace:
+			break loop
+		case res := <-ch:
+			if res.err != nil {
+				lastErr = res.err
+				failed++
+				if len(c.endpoints)-failed < c.quorum {
+					break loop
+				}
+
+				continue
+			}
+
+			numbers = append(numbers, res.number)
+			if len(numbers) == c.quorum {
+				timer := time.NewTimer(blockNumberGracePeriod)
+				defer timer.Stop()
+				grace = timer.C
+			}
+		}
+	}
+
+	if len(numbers) < c.quorum {
+		if lastErr == nil {
+			lastErr = ErrQuorumNotReached
+		}
+
+		return 0, lastErr
+	}
+
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] > numbers[j] })
+
+	return numbers[c.quorum-1], nil
+}
+
+// SubscribeNewHead subscribes on the first available provider. Headers from the subscription
+// are not cross-checked, but fetching their logs and ancestors goes through quorum.
+//
+//nolint:ireturn
+func (c *QuorumClient) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (Subscription, error) {
+	var err error
+	for _, e := range c.endpoints {
+		var sub Subscription
+		sub, err = e.Client.SubscribeNewHead(ctx, ch)
+		if err == nil {
+			return sub, nil
+		}
+
+		c.l.Warnw("Fail to subscribe new head on endpoint", "endpoint", e.Name, "error", err)
+	}
+
+	return nil, err
+}
+
+func logsKey(logs []types.Log) string {
+	var sb strings.Builder
+	for _, log := range logs {
+		sb.WriteString(log.BlockHash)
+		sb.WriteByte(':')
+		sb.WriteString(log.TxHash)
+		sb.WriteByte(':')
+		sb.WriteString(strconv.FormatUint(uint64(log.Index), 10))
+		sb.WriteByte(';')
+	}
+
+	return strconv.Itoa(len(logs)) + "|" + sb.String()
+}
+
+// FilterLogs executes a filter query, accepting the log set returned by quorum providers.
+func (c *QuorumClient) FilterLogs(ctx context.Context, q FilterQuery) ([]types.Log, error) {
+	v, err := c.query(ctx, "FilterLogs", func(ctx context.Context, client IClient) (string, interface{}, error) {
+		logs, err := client.FilterLogs(ctx, q)
+		if err != nil {
+			return "", nil, err
+		}
+
+		return logsKey(logs), logs, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logs, _ := v.([]types.Log)
+
+	return logs, nil
+}
+
+func (c *QuorumClient) header(
+	ctx context.Context, method string, fn func(context.Context, IClient) (*types.Header, error),
+) (*types.Header, error) {
+	v, err := c.query(ctx, method, func(ctx context.Context, client IClient) (string, interface{}, error) {
+		header, err := fn(ctx, client)
+		if err != nil {
+			return "", nil, err
+		}
+
+		return header.Hash, header, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	header, _ := v.(*types.Header)
+
+	return header, nil
+}
+
+// HeaderByHash returns the block header with the given hash.
+func (c *QuorumClient) HeaderByHash(ctx context.Context, hash string) (*types.Header, error) {
+	return c.header(ctx, "HeaderByHash", func(ctx context.Context, client IClient) (*types.Header, error) {
+		return client.HeaderByHash(ctx, hash)
+	})
+}
+
+// HeaderByNumber returns a block header from the canonical chain agreed by quorum providers.
+// If number is nil, the header of the highest block reached by quorum providers is returned.
+func (c *QuorumClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	if number == nil {
+		n, err := c.BlockNumber(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		number = new(big.Int).SetUint64(n)
+	}
+
+	return c.header(ctx, "HeaderByNumber", func(ctx context.Context, client IClient) (*types.Header, error) {
+		return client.HeaderByNumber(ctx, number)
+	})
+}
+
+// BlockReceiptLogs returns all logs of the block with given hash from its receipts, accepting
+// the log set returned by quorum providers.
+func (c *QuorumClient) BlockReceiptLogs(ctx context.Context, hash string, receiptsRoot string) ([]types.Log, error) {
+	v, err := c.query(ctx, "BlockReceiptLogs", func(ctx context.Context, client IClient) (string, interface{}, error) {
+		receiptsClient, ok := client.(ReceiptsClient)
+		if !ok {
+			return "", nil, ErrReceiptsNotSupported
+		}
+
+		logs, err := receiptsClient.BlockReceiptLogs(ctx, hash, receiptsRoot)
+		if err != nil {
+			return "", nil, err
+		}
+
+		return logsKey(logs), logs, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logs, _ := v.([]types.Log)
+
+	return logs, nil
+}
@@ -0,0 +1,119 @@
+package evmclient
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/KyberNetwork/evmlistener/pkg/types"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+)
+
+// staleForkClient serves headers of a different fork.
+type staleForkClient struct {
+	*fakeClient
+}
+
+func (c staleForkClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	header, err := c.fakeClient.HeaderByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+
+	header.Hash = "fork-" + header.Hash
+
+	return header, nil
+}
+
+type QuorumClientTestSuite struct {
+	suite.Suite
+
+	clients []*fakeClient
+	stale   *fakeClient
+	client  *QuorumClient
+}
+
+func (ts *QuorumClientTestSuite) SetupTest() {
+	ts.stale = &fakeClient{head: 90}
+	ts.clients = []*fakeClient{{head: 100}, {head: 101}}
+
+	client, err := NewQuorumClient(zap.NewNop().Sugar(), []Endpoint{
+		{Name: "stale", Client: staleForkClient{fakeClient: ts.stale}},
+		{Name: "node-1", Client: ts.clients[0]},
+		{Name: "node-2", Client: ts.clients[1]},
+	}, 2)
+	ts.Require().NoError(err)
+	ts.client = client
+}
+
+func (ts *QuorumClientTestSuite) TestHeaderByNumber() {
+	ctx := context.Background()
+
+	header, err := ts.client.HeaderByNumber(ctx, big.NewInt(80))
+	ts.Require().NoError(err)
+	ts.Assert().Equal(fakeHeader(80).Hash, header.Hash)
+	ts.Assert().Equal(1, ts.stale.numCalls())
+	ts.Assert().Equal(1, ts.clients[0].numCalls())
+	ts.Assert().Equal(1, ts.clients[1].numCalls())
+
+	// Latest header is the highest one that quorum providers have reached.
+	header, err = ts.client.HeaderByNumber(ctx, nil)
+	ts.Require().NoError(err)
+	ts.Assert().Equal(uint64(100), header.Number.Uint64())
+
+	// Quorum is not reached when one of honest providers fails.
+	ts.clients[1].fail(errors.New("connection reset"))
+	_, err = ts.client.HeaderByNumber(ctx, big.NewInt(80))
+	ts.Require().ErrorIs(err, ErrQuorumNotReached)
+}
+
+func (ts *QuorumClientTestSuite) TestBlockNumberSlowProvider() {
+	ts.clients[1].delay = time.Minute
+
+	// A slow provider does not delay the result once quorum providers have answered.
+	start := time.Now()
+	number, err := ts.client.BlockNumber(context.Background())
+	ts.Require().NoError(err)
+	ts.Assert().Equal(uint64(90), number)
+	ts.Assert().Less(time.Since(start), 10*time.Second)
+}
+
+// receiptsFakeClient is a fakeClient that serves a single log for each block.
+type receiptsFakeClient struct {
+	*fakeClient
+}
+
+func (c receiptsFakeClient) BlockReceiptLogs(_ context.Context, hash string, _ string) ([]types.Log, error) {
+	if err := c.result(); err != nil {
+		return nil, err
+	}
+
+	return []types.Log{{BlockHash: hash, TxHash: "0x1"}}, nil
+}
+
+func (ts *QuorumClientTestSuite) TestBlockReceiptLogs() {
+	ctx := context.Background()
+
+	// Stale provider does not serve receipts, the others agree on them.
+	client, err := NewQuorumClient(zap.NewNop().Sugar(), []Endpoint{
+		{Name: "stale", Client: ts.stale},
+		{Name: "node-1", Client: receiptsFakeClient{fakeClient: ts.clients[0]}},
+		{Name: "node-2", Client: receiptsFakeClient{fakeClient: ts.clients[1]}},
+	}, 2)
+	ts.Require().NoError(err)
+
+	logs, err := client.BlockReceiptLogs(ctx, "0xaa", "")
+	ts.Require().NoError(err)
+	ts.Assert().Equal([]types.Log{{BlockHash: "0xaa", TxHash: "0x1"}}, logs)
+
+	// Missing support is reported when no provider serves receipts.
+	_, err = ts.client.BlockReceiptLogs(ctx, "0xaa", "")
+	ts.Assert().ErrorIs(err, ErrReceiptsNotSupported)
+}
+
+func TestQuorumClientTestSuite(t *testing.T) {
+	suite.Run(t, new(QuorumClientTestSuite))
+}
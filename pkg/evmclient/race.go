@@ -0,0 +1,241 @@
+package evmclient
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/KyberNetwork/evmlistener/pkg/errors"
+	"github.com/KyberNetwork/evmlistener/pkg/labels"
+	"github.com/KyberNetwork/evmlistener/pkg/types"
+	pkgmetric "github.com/KyberNetwork/kyber-trace-go/pkg/metric"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+)
+
+const (
+	metricNameHeadArrivalLag   = "evmlistener_ws_head_arrival_lag"
+	metricNameHeadFirstArrival = "evmlistener_ws_head_first_arrivals"
+
+	defaultResubscribeInterval = 5 * time.Second
+	// defaultMaxTrackedHeads is the number of recent blocks whose hashes are kept for de-duplication.
+	defaultMaxTrackedHeads = 128
+)
+
+// RaceClient is an EVM client that subscribes to new heads on several endpoints at once and forwards
+// the first arrival of each header, other requests are sent to the underlying client.
+type RaceClient struct {
+	IClient
+
+	l                   *zap.SugaredLogger
+	endpoints           []Endpoint
+	resubscribeInterval time.Duration
+	maxTrackedHeads     uint64
+
+	arrivalLag    metric.Float64Histogram
+	firstArrivals metric.Int64Counter
+}
+
+// NewRaceClient returns a new RaceClient racing subscriptions on given endpoints,
+// the client is used for requests other than subscriptions.
+func NewRaceClient(l *zap.SugaredLogger, client IClient, endpoints []Endpoint) (*RaceClient, error) {
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("%w: no endpoint to race subscriptions", errors.ErrInvalidArgument)
+	}
+
+	arrivalLag, err := pkgmetric.Meter().Float64Histogram(metricNameHeadArrivalLag,
+		metric.WithDescription("Delay of new heads on an endpoint behind the fastest one"),
+		metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+
+	firstArrivals, err := pkgmetric.Meter().Int64Counter(metricNameHeadFirstArrival,
+		metric.WithDescription("Number of new heads that arrived first on an endpoint"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &RaceClient{
+		IClient:             client,
+		l:                   l,
+		endpoints:           endpoints,
+		resubscribeInterval: defaultResubscribeInterval,
+		maxTrackedHeads:     defaultMaxTrackedHeads,
+		arrivalLag:          arrivalLag,
+		firstArrivals:       firstArrivals,
+	}, nil
+}
+
+type raceHeader struct {
+	endpoint int
+	header   *types.Header
+	at       time.Time
+}
+
+type raceError struct {
+	endpoint int
+	err      error
+}
+
+type raceArrival struct {
+	number uint64
+	at     time.Time
+}
+
+// SubscribeNewHead subscribes to new heads on all endpoints. It fails only when no endpoint
+// accepts the subscription, and keeps running as long as one of the subscriptions is alive.
+//
+//nolint:ireturn
+func (c *RaceClient) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (Subscription, error) {
+	sub := newManagedSubscription()
+	ctx, cancel := context.WithCancel(ctx)
+	headerCh := make(chan raceHeader, len(c.endpoints))
+	errCh := make(chan raceError, len(c.endpoints))
+
+	var err error
+	inners := make([]Subscription, len(c.endpoints))
+	for i := range c.endpoints {
+		inners[i], err = c.subscribe(ctx, i, headerCh, errCh)
+	}
+
+	alive := 0
+	for _, inner := range inners {
+		if inner != nil {
+			alive++
+		}
+	}
+
+	if alive == 0 {
+		cancel()
+
+		return nil, err
+	}
+
+	go c.run(ctx, cancel, sub, ch, inners, headerCh, errCh)
+
+	return sub, nil
+}
+
+// subscribe subscribes to new heads on an endpoint, forwarding headers and error to given channels.
+//
+//nolint:ireturn
+func (c *RaceClient) subscribe(
+	ctx context.Context, i int, headerCh chan<- raceHeader, errCh chan<- raceError,
+) (Subscription, error) {
+	e := c.endpoints[i]
+	innerCh := make(chan *types.Header, 1)
+	inner, err := e.Client.SubscribeNewHead(ctx, innerCh)
+	if err != nil {
+		c.l.Warnw("Fail to subscribe new head on endpoint", "endpoint", e.Name, "error", err)
+
+		return nil, err
+	}
+
+	c.l.Infow("Subscribe new head on endpoint", "endpoint", e.Name)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case err := <-inner.Err():
+				select {
+				case errCh <- raceError{endpoint: i, err: err}:
+				case <-ctx.Done():
+				}
+
+				return
+			case header := <-innerCh:
+				select {
+				case headerCh <- raceHeader{endpoint: i, header: header, at: time.Now()}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return inner, nil
+}
+
+//nolint:cyclop,funlen
+func (c *RaceClient) run(
+	ctx context.Context, cancel context.CancelFunc, sub *managedSubscription, ch chan<- *types.Header,
+	inners []Subscription, headerCh chan raceHeader, errCh chan raceError,
+) {
+	defer close(sub.done)
+	defer func() {
+		cancel()
+		for _, inner := range inners {
+			if inner != nil {
+				inner.Unsubscribe()
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(c.resubscribeInterval)
+	defer ticker.Stop()
+
+	var highest uint64
+	seen := make(map[string]raceArrival)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sub.quit:
+			return
+		case e := <-errCh:
+			c.l.Warnw("Subscription on endpoint failed", "endpoint", c.endpoints[e.endpoint].Name, "error", e.err)
+			inners[e.endpoint].Unsubscribe()
+			inners[e.endpoint] = nil
+
+			alive := false
+			for _, inner := range inners {
+				alive = alive || inner != nil
+			}
+
+			if !alive {
+				sub.errCh <- e.err
+
+				return
+			}
+		case <-ticker.C:
+			for i, inner := range inners {
+				if inner == nil {
+					inners[i], _ = c.subscribe(ctx, i, headerCh, errCh)
+				}
+			}
+		case h := <-headerCh:
+			attrs := metric.WithAttributes(labels.Attributes(ctx,
+				attribute.String("endpoint", c.endpoints[h.endpoint].Name))...)
+			if first, ok := seen[h.header.Hash]; ok {
+				c.arrivalLag.Record(ctx, h.at.Sub(first.at).Seconds(), attrs)
+
+				continue
+			}
+
+			number := h.header.Number.Uint64()
+			seen[h.header.Hash] = raceArrival{number: number, at: h.at}
+			c.arrivalLag.Record(ctx, 0, attrs)
+			c.firstArrivals.Add(ctx, 1, attrs)
+
+			highest = max(highest, number)
+			if uint64(len(seen)) > 2*c.maxTrackedHeads { //nolint:gomnd
+				for hash, arrival := range seen {
+					if arrival.number+c.maxTrackedHeads < highest {
+						delete(seen, hash)
+					}
+				}
+			}
+
+			select {
+			case ch <- h.header:
+			case <-ctx.Done():
+				return
+			case <-sub.quit:
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package evmclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/KyberNetwork/evmlistener/pkg/types"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+)
+
+type RaceClientTestSuite struct {
+	suite.Suite
+
+	fast   *fakeClient
+	slow   *fakeClient
+	client *RaceClient
+}
+
+func (ts *RaceClientTestSuite) SetupTest() {
+	ts.fast = &fakeClient{head: 100}
+	ts.slow = &fakeClient{head: 100}
+
+	var err error
+	ts.client, err = NewRaceClient(zap.S(), ts.fast, []Endpoint{
+		{Name: "fast", Client: ts.fast},
+		{Name: "slow", Client: ts.slow},
+	})
+	ts.Require().NoError(err)
+	ts.client.resubscribeInterval = 10 * time.Millisecond
+}
+
+func (ts *RaceClientTestSuite) receive(ch <-chan *types.Header, n uint64) {
+	select {
+	case header := <-ch:
+		ts.Require().Equal(n, header.Number.Uint64())
+	case <-time.After(time.Second):
+		ts.FailNow("timeout waiting for header", "number", n)
+	}
+}
+
+func (ts *RaceClientTestSuite) noHeader(ch <-chan *types.Header) {
+	select {
+	case header := <-ch:
+		ts.FailNow("unexpected header", "number", header.Number)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func (ts *RaceClientTestSuite) TestDeduplicate() {
+	ch := make(chan *types.Header, 1)
+	sub, err := ts.client.SubscribeNewHead(context.Background(), ch)
+	ts.Require().NoError(err)
+	defer sub.Unsubscribe()
+
+	ts.fast.push(101)
+	ts.receive(ch, 101)
+	ts.slow.push(101)
+	ts.noHeader(ch)
+
+	// The slow endpoint may be the first one for some headers.
+	ts.slow.push(102)
+	ts.receive(ch, 102)
+	ts.fast.push(102)
+	ts.noHeader(ch)
+}
+
+func (ts *RaceClientTestSuite) TestEndpointFailure() {
+	ch := make(chan *types.Header, 1)
+	sub, err := ts.client.SubscribeNewHead(context.Background(), ch)
+	ts.Require().NoError(err)
+	defer sub.Unsubscribe()
+
+	// Keep running on the remaining endpoint.
+	ts.fast.fail(errors.New("connection reset"))
+	ts.slow.push(101)
+	ts.receive(ch, 101)
+
+	// Subscribe again to the endpoint once it recovers.
+	ts.fast.fail(nil)
+	ts.Require().Eventually(func() bool {
+		ts.fast.mu.Lock()
+		defer ts.fast.mu.Unlock()
+
+		return len(ts.fast.subChs) > 0
+	}, time.Second, 10*time.Millisecond)
+	ts.fast.push(102)
+	ts.receive(ch, 102)
+
+	// The subscription fails when all endpoints fail.
+	subErr := errors.New("connection reset")
+	ts.fast.fail(subErr)
+	ts.slow.fail(subErr)
+	select {
+	case err = <-sub.Err():
+		ts.Assert().Equal(subErr, err)
+	case <-time.After(time.Second):
+		ts.FailNow("timeout waiting for subscription error")
+	}
+}
+
+func (ts *RaceClientTestSuite) TestSubscribeError() {
+	ts.slow.fail(errors.New("connection refused"))
+
+	sub, err := ts.client.SubscribeNewHead(context.Background(), make(chan *types.Header))
+	ts.Require().NoError(err)
+	sub.Unsubscribe()
+
+	ts.fast.fail(errors.New("connection refused"))
+	_, err = ts.client.SubscribeNewHead(context.Background(), make(chan *types.Header))
+	ts.Assert().Error(err)
+}
+
+func TestRaceClientTestSuite(t *testing.T) {
+	suite.Run(t, new(RaceClientTestSuite))
+}
@@ -0,0 +1,60 @@
+package evmclient
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/KyberNetwork/evmlistener/pkg/chain"
+	"github.com/KyberNetwork/evmlistener/pkg/errors"
+	"github.com/KyberNetwork/evmlistener/pkg/types"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+var (
+	// ErrReceiptsNotSupported is returned when fetching receipts of a block is not supported by the client.
+	ErrReceiptsNotSupported = errors.New("block receipts are not supported")
+	// ErrReceiptsRootMismatch is returned when receipts returned by the node do not match receipts root
+	// of the block.
+	ErrReceiptsRootMismatch = errors.New("receipts do not match receipts root")
+)
+
+// ReceiptsClient is an interface for EVM client that can fetch all logs of a block from its receipts.
+type ReceiptsClient interface {
+	// BlockReceiptLogs returns all logs of the block with given hash from its receipts.
+	// The receipts are verified against receiptsRoot if it is not empty and the chain profile enables it.
+	BlockReceiptLogs(ctx context.Context, hash string, receiptsRoot string) ([]types.Log, error)
+}
+
+// BlockReceiptLogs returns all logs of the block with given hash from its receipts, using
+// eth_getBlockReceipts if the chain supports it.
+func (c *Client) BlockReceiptLogs(ctx context.Context, hash string, receiptsRoot string) ([]types.Log, error) {
+	if !c.profile.Methods.GetBlockReceipts {
+		return nil, ErrReceiptsNotSupported
+	}
+
+	var receipts []*ethtypes.Receipt
+	err := c.rpcClient.CallContext(ctx, &receipts, "eth_getBlockReceipts", hash)
+	if err != nil {
+		return nil, err
+	}
+
+	// Receipts of chains with custom headers are not guaranteed to be encoded as Ethereum ones.
+	if receiptsRoot != "" && c.profile.VerifyReceiptsRoot && c.profile.HeaderMode != chain.HeaderModeCustom {
+		root := ethtypes.DeriveSha(ethtypes.Receipts(receipts), trie.NewStackTrie(nil))
+		if root != ethcommon.HexToHash(receiptsRoot) {
+			return nil, fmt.Errorf("%w: block %s, got %s, expect %s",
+				ErrReceiptsRootMismatch, hash, root, receiptsRoot)
+		}
+	}
+
+	var logs []ethtypes.Log
+	for _, receipt := range receipts {
+		for _, log := range receipt.Logs {
+			logs = append(logs, *log)
+		}
+	}
+
+	return fromEthereumLogs(logs), nil
+}
@@ -0,0 +1,106 @@
+package evmclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KyberNetwork/evmlistener/pkg/chain"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	optimismChainID = 10
+	depositBlock    = "0x3f0c1e4b1a2a09f2c5d6d0b0b3a3c4d9e8e7f6a5b4c3d2e1f0a9b8c7d6e5f4a3"
+	depositRoot     = "0x7c1b6e2b0f4b8e2a9c3d5e7f9a1b3c5d7e9f1a3b5c7d9e1f3a5b7c9d1e3f5a7b"
+)
+
+// depositReceipts is the response of eth_getBlockReceipts for a block with an OP deposit
+// transaction, whose receipt type 0x7e can not be encoded by go-ethereum.
+var depositReceipts = json.RawMessage(`[{
+	"type": "0x7e",
+	"status": "0x1",
+	"cumulativeGasUsed": "0xb71b",
+	"logsBloom": "0x` + strings.Repeat("0", 512) + `",
+	"logs": [{
+		"address": "0x4200000000000000000000000000000000000015",
+		"topics": ["0x6e7a4b4bb1a1ef3c6c1ce7e1a5c3d1c0e7b9d2b6c8f4a4a0b3c2d1e0f9a8b7c6"],
+		"data": "0x",
+		"blockNumber": "0x7b",
+		"transactionHash": "0x1d2d3d4d5d6d7d8d9dadbdcdddedfd0d1d2d3d4d5d6d7d8d9dadbdcdddedfd0d",
+		"transactionIndex": "0x0",
+		"blockHash": "` + depositBlock + `",
+		"logIndex": "0x0",
+		"removed": false
+	}],
+	"transactionHash": "0x1d2d3d4d5d6d7d8d9dadbdcdddedfd0d1d2d3d4d5d6d7d8d9dadbdcdddedfd0d",
+	"contractAddress": null,
+	"gasUsed": "0xb71b",
+	"effectiveGasPrice": "0x0",
+	"blockHash": "` + depositBlock + `",
+	"blockNumber": "0x7b",
+	"transactionIndex": "0x0",
+	"depositNonce": "0x7b"
+}]`)
+
+type receiptsService struct{}
+
+//nolint:revive,stylecheck
+func (receiptsService) ChainId() hexutil.Uint64 {
+	return optimismChainID
+}
+
+func (receiptsService) GetBlockReceipts(string) json.RawMessage {
+	return depositReceipts
+}
+
+type ReceiptsTestSuite struct {
+	suite.Suite
+
+	server *httptest.Server
+}
+
+func (ts *ReceiptsTestSuite) SetupTest() {
+	rpcServer := rpc.NewServer()
+	ts.Require().NoError(rpcServer.RegisterName("eth", receiptsService{}))
+
+	ts.server = httptest.NewServer(rpcServer)
+}
+
+func (ts *ReceiptsTestSuite) TearDownTest() {
+	ts.server.Close()
+}
+
+func (ts *ReceiptsTestSuite) TestDepositReceipts() {
+	ctx := context.Background()
+
+	// Receipts root of the chain is not verified, as go-ethereum can not derive it.
+	client, err := Dial(ts.server.URL, http.DefaultClient)
+	ts.Require().NoError(err)
+	ts.Require().False(client.Profile().VerifyReceiptsRoot)
+
+	logs, err := client.BlockReceiptLogs(ctx, depositBlock, depositRoot)
+	ts.Require().NoError(err)
+	ts.Require().Len(logs, 1)
+	ts.Assert().Equal(depositBlock, logs[0].BlockHash)
+
+	profile := chain.DefaultRegistry().Get(optimismChainID)
+	profile.VerifyReceiptsRoot = true
+	registry, err := chain.NewRegistry(profile)
+	ts.Require().NoError(err)
+
+	client, err = Dial(ts.server.URL, http.DefaultClient, WithChainRegistry(registry))
+	ts.Require().NoError(err)
+
+	_, err = client.BlockReceiptLogs(ctx, depositBlock, depositRoot)
+	ts.Assert().ErrorIs(err, ErrReceiptsRootMismatch)
+}
+
+func TestReceiptsTestSuite(t *testing.T) {
+	suite.Run(t, new(ReceiptsTestSuite))
+}
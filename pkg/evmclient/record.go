@@ -0,0 +1,188 @@
+package evmclient
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/KyberNetwork/evmlistener/pkg/types"
+	"github.com/ethereum/go-ethereum"
+)
+
+// Recorded methods and events.
+const (
+	RecordChainID          = "ChainID"
+	RecordBlockNumber      = "BlockNumber"
+	RecordHeaderByHash     = "HeaderByHash"
+	RecordHeaderByNumber   = "HeaderByNumber"
+	RecordFilterLogs       = "FilterLogs"
+	RecordSubscribeNewHead = "SubscribeNewHead"
+	RecordNewHead          = "NewHead"
+	RecordSubscriptionErr  = "SubscriptionError"
+)
+
+// Record is a request to an EVM client with its response, or a subscription event.
+type Record struct {
+	// At is the time elapsed since the recording started.
+	At     time.Duration   `json:"at"`
+	Method string          `json:"method"`
+	Sub    int             `json:"sub,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// Recorder writes records as JSON lines, it is safe for concurrent use.
+type Recorder struct {
+	mu    sync.Mutex
+	w     io.Writer
+	start time.Time
+	subs  int
+}
+
+// NewRecorder returns a recorder writing to w.
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{w: w, start: time.Now()}
+}
+
+// Record writes a record of method with its params, result and error.
+func (r *Recorder) Record(method string, sub int, params, result interface{}, err error) error {
+	rec := Record{Method: method, Sub: sub}
+
+	var e error
+	if params != nil {
+		if rec.Params, e = json.Marshal(params); e != nil {
+			return e
+		}
+	}
+
+	if err != nil {
+		rec.Error = err.Error()
+	} else if result != nil {
+		if rec.Result, e = json.Marshal(result); e != nil {
+			return e
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec.At = time.Since(r.start)
+	data, e := json.Marshal(rec)
+	if e != nil {
+		return e
+	}
+
+	// Write the whole line at once, so that the file is still readable after a crash.
+	_, e = r.w.Write(append(data, '\n'))
+
+	return e
+}
+
+func (r *Recorder) newSubscription() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.subs++
+
+	return r.subs
+}
+
+// RecordingClient is an EVM client which records every request and response,
+// including subscription events, with the given recorder.
+type RecordingClient struct {
+	client   IClient
+	recorder *Recorder
+}
+
+// NewRecordingClient returns a client recording requests to given client.
+func NewRecordingClient(client IClient, recorder *Recorder) *RecordingClient {
+	return &RecordingClient{client: client, recorder: recorder}
+}
+
+func (c *RecordingClient) record(method string, sub int, params, result interface{}, err error) {
+	// Recording is best effort, it must never break the client.
+	_ = c.recorder.Record(method, sub, params, result, err)
+}
+
+func (c *RecordingClient) BlockNumber(ctx context.Context) (uint64, error) {
+	res, err := c.client.BlockNumber(ctx)
+	c.record(RecordBlockNumber, 0, nil, res, err)
+
+	return res, err
+}
+
+//nolint:ireturn
+func (c *RecordingClient) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (Subscription, error) {
+	id := c.recorder.newSubscription()
+	innerCh := make(chan *types.Header, cap(ch))
+	inner, err := c.client.SubscribeNewHead(ctx, innerCh)
+	c.record(RecordSubscribeNewHead, id, nil, nil, err)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := newManagedSubscription()
+	go func() {
+		defer close(sub.done)
+		defer inner.Unsubscribe()
+
+		for {
+			select {
+			case <-sub.quit:
+				return
+			case err := <-inner.Err():
+				c.record(RecordSubscriptionErr, id, nil, nil, err)
+				sub.errCh <- err
+
+				return
+			case header := <-innerCh:
+				c.record(RecordNewHead, id, nil, header, nil)
+				select {
+				case ch <- header:
+				case <-sub.quit:
+					return
+				}
+			}
+		}
+	}()
+
+	return sub, nil
+}
+
+func (c *RecordingClient) FilterLogs(ctx context.Context, q FilterQuery) ([]types.Log, error) {
+	res, err := c.client.FilterLogs(ctx, q)
+	c.record(RecordFilterLogs, 0, q, res, err)
+
+	return res, err
+}
+
+func (c *RecordingClient) HeaderByHash(ctx context.Context, hash string) (*types.Header, error) {
+	res, err := c.client.HeaderByHash(ctx, hash)
+	c.record(RecordHeaderByHash, 0, hash, res, err)
+
+	return res, err
+}
+
+func (c *RecordingClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	res, err := c.client.HeaderByNumber(ctx, number)
+	c.record(RecordHeaderByNumber, 0, number, res, err)
+
+	return res, err
+}
+
+// recordedError restores well-known errors from recorded messages.
+func recordedError(msg string) error {
+	if msg == "" {
+		return nil
+	}
+
+	if msg == ethereum.NotFound.Error() {
+		return ethereum.NotFound
+	}
+
+	return &ReplayError{Message: msg}
+}
@@ -0,0 +1,124 @@
+package evmclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/KyberNetwork/evmlistener/pkg/types"
+	"github.com/ethereum/go-ethereum"
+	"github.com/stretchr/testify/suite"
+)
+
+type RecordReplayTestSuite struct {
+	suite.Suite
+
+	node *fakeClient
+	buf  *bytes.Buffer
+}
+
+func (ts *RecordReplayTestSuite) SetupTest() {
+	ts.node = &fakeClient{head: 100}
+	ts.buf = &bytes.Buffer{}
+}
+
+func (ts *RecordReplayTestSuite) receive(ch <-chan *types.Header) *types.Header {
+	select {
+	case header := <-ch:
+		return header
+	case <-time.After(time.Second):
+		ts.FailNow("timeout waiting for header")
+	}
+
+	return nil
+}
+
+func (ts *RecordReplayTestSuite) TestReplayRequests() {
+	ctx := context.Background()
+	recorder := NewRecorder(ts.buf)
+	ts.Require().NoError(recorder.Record(RecordChainID, 0, nil, 137, nil))
+	client := NewRecordingClient(ts.node, recorder)
+
+	number, err := client.BlockNumber(ctx)
+	ts.Require().NoError(err)
+	ts.node.push(101)
+	_, err = client.BlockNumber(ctx)
+	ts.Require().NoError(err)
+	header, err := client.HeaderByNumber(ctx, big.NewInt(99))
+	ts.Require().NoError(err)
+	ts.node.fail(ethereum.NotFound)
+	_, err = client.HeaderByHash(ctx, "0x01")
+	ts.Require().ErrorIs(err, ethereum.NotFound)
+
+	replay, err := NewReplayClient(ts.buf, 0)
+	ts.Require().NoError(err)
+	ts.Assert().Equal(big.NewInt(137), replay.ChainID())
+
+	// Responses are served in recorded order, the last one is repeated.
+	res, err := replay.BlockNumber(ctx)
+	ts.Require().NoError(err)
+	ts.Assert().Equal(number, res)
+	for range 2 {
+		res, err = replay.BlockNumber(ctx)
+		ts.Require().NoError(err)
+		ts.Assert().Equal(uint64(101), res)
+	}
+
+	h, err := replay.HeaderByNumber(ctx, big.NewInt(99))
+	ts.Require().NoError(err)
+	ts.Assert().Equal(header, h)
+
+	_, err = replay.HeaderByHash(ctx, "0x01")
+	ts.Assert().ErrorIs(err, ethereum.NotFound)
+
+	_, err = replay.HeaderByNumber(ctx, big.NewInt(98))
+	ts.Assert().ErrorIs(err, ErrNoRecord)
+}
+
+func (ts *RecordReplayTestSuite) TestReplaySubscription() {
+	client := NewRecordingClient(ts.node, NewRecorder(ts.buf))
+
+	ch := make(chan *types.Header, 1)
+	sub, err := client.SubscribeNewHead(context.Background(), ch)
+	ts.Require().NoError(err)
+	ts.node.push(101)
+	ts.receive(ch)
+	ts.node.push(102)
+	ts.receive(ch)
+
+	subErr := errors.New("connection reset")
+	ts.node.fail(subErr)
+	select {
+	case err = <-sub.Err():
+		ts.Require().Equal(subErr, err)
+	case <-time.After(time.Second):
+		ts.FailNow("timeout waiting for subscription error")
+	}
+	sub.Unsubscribe()
+
+	replay, err := NewReplayClient(ts.buf, 0)
+	ts.Require().NoError(err)
+
+	sub, err = replay.SubscribeNewHead(context.Background(), ch)
+	ts.Require().NoError(err)
+	ts.Assert().Equal(fakeHeader(101), ts.receive(ch))
+	ts.Assert().Equal(fakeHeader(102), ts.receive(ch))
+	select {
+	case err = <-sub.Err():
+		ts.Assert().EqualError(err, subErr.Error())
+	case <-time.After(time.Second):
+		ts.FailNow("timeout waiting for subscription error")
+	}
+	sub.Unsubscribe()
+
+	// There is no more recorded subscription.
+	_, err = replay.SubscribeNewHead(context.Background(), ch)
+	ts.Assert().ErrorIs(err, ErrNoRecord)
+}
+
+func TestRecordReplayTestSuite(t *testing.T) {
+	suite.Run(t, new(RecordReplayTestSuite))
+}
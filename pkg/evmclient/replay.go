@@ -0,0 +1,240 @@
+package evmclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/KyberNetwork/evmlistener/pkg/errors"
+	"github.com/KyberNetwork/evmlistener/pkg/types"
+)
+
+// ErrNoRecord is returned by ReplayClient when a request was not recorded.
+var ErrNoRecord = errors.New("no record for request")
+
+// ReplayError is an error replayed from a record.
+type ReplayError struct {
+	Message string
+}
+
+func (e *ReplayError) Error() string {
+	return e.Message
+}
+
+// ReplayClient is an EVM client serving records written by a RecordingClient. Responses of
+// a request are served in recorded order, the last one is repeated once they are exhausted.
+// Subscriptions are replayed in recorded order, with recorded timing scaled by speed.
+type ReplayClient struct {
+	mu        sync.Mutex
+	speed     float64
+	responses map[string][]Record
+	subs      []Record
+	events    map[int][]Record
+	chainID   *big.Int
+}
+
+// NewReplayClient returns a replay client serving records read from r. Subscription events are
+// sent at speed times the recorded pace, or as fast as possible if speed is not positive.
+func NewReplayClient(r io.Reader, speed float64) (*ReplayClient, error) {
+	c := &ReplayClient{
+		speed:     speed,
+		responses: make(map[string][]Record),
+		events:    make(map[int][]Record),
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64<<20) //nolint:gomnd
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("%w: invalid record at line %d: %w", errors.ErrInvalidArgument, line, err)
+		}
+
+		switch rec.Method {
+		case RecordChainID:
+			c.chainID = new(big.Int)
+			if err := json.Unmarshal(rec.Result, c.chainID); err != nil {
+				return nil, fmt.Errorf("%w: invalid chain id at line %d: %w", errors.ErrInvalidArgument, line, err)
+			}
+		case RecordSubscribeNewHead:
+			c.subs = append(c.subs, rec)
+		case RecordNewHead, RecordSubscriptionErr:
+			c.events[rec.Sub] = append(c.events[rec.Sub], rec)
+		default:
+			key := replayKey(rec.Method, rec.Params)
+			c.responses[key] = append(c.responses[key], rec)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// LoadReplayClient returns a replay client serving records from given file.
+func LoadReplayClient(path string, speed float64) (*ReplayClient, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return NewReplayClient(f, speed)
+}
+
+func replayKey(method string, params json.RawMessage) string {
+	return method + ":" + string(params)
+}
+
+// ChainID returns the recorded chain id, or nil if it was not recorded.
+func (c *ReplayClient) ChainID() *big.Int {
+	return c.chainID
+}
+
+func (c *ReplayClient) replay(method string, params interface{}, result interface{}) error {
+	var key string
+	if params == nil {
+		key = replayKey(method, nil)
+	} else {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		key = replayKey(method, data)
+	}
+
+	c.mu.Lock()
+	records := c.responses[key]
+	if len(records) == 0 {
+		c.mu.Unlock()
+
+		return fmt.Errorf("%w: %s", ErrNoRecord, key)
+	}
+
+	rec := records[0]
+	if len(records) > 1 {
+		c.responses[key] = records[1:]
+	}
+	c.mu.Unlock()
+
+	if err := recordedError(rec.Error); err != nil {
+		return err
+	}
+
+	return json.Unmarshal(rec.Result, result)
+}
+
+func (c *ReplayClient) BlockNumber(context.Context) (uint64, error) {
+	var res uint64
+	err := c.replay(RecordBlockNumber, nil, &res)
+
+	return res, err
+}
+
+func (c *ReplayClient) FilterLogs(_ context.Context, q FilterQuery) ([]types.Log, error) {
+	var res []types.Log
+	err := c.replay(RecordFilterLogs, q, &res)
+
+	return res, err
+}
+
+func (c *ReplayClient) HeaderByHash(_ context.Context, hash string) (*types.Header, error) {
+	var res *types.Header
+	err := c.replay(RecordHeaderByHash, hash, &res)
+
+	return res, err
+}
+
+func (c *ReplayClient) HeaderByNumber(_ context.Context, number *big.Int) (*types.Header, error) {
+	var res *types.Header
+	err := c.replay(RecordHeaderByNumber, number, &res)
+
+	return res, err
+}
+
+// SubscribeNewHead replays the next recorded subscription.
+//
+//nolint:ireturn
+func (c *ReplayClient) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (Subscription, error) {
+	c.mu.Lock()
+	if len(c.subs) == 0 {
+		c.mu.Unlock()
+
+		return nil, fmt.Errorf("%w: %s", ErrNoRecord, RecordSubscribeNewHead)
+	}
+
+	rec := c.subs[0]
+	c.subs = c.subs[1:]
+	events := c.events[rec.Sub]
+	c.mu.Unlock()
+
+	if err := recordedError(rec.Error); err != nil {
+		return nil, err
+	}
+
+	sub := newManagedSubscription()
+	go c.replaySubscription(ctx, sub, ch, rec.At, events)
+
+	return sub, nil
+}
+
+func (c *ReplayClient) replaySubscription(ctx context.Context, sub *managedSubscription,
+	ch chan<- *types.Header, start time.Duration, events []Record,
+) {
+	defer close(sub.done)
+
+	begin := time.Now()
+	for _, event := range events {
+		if c.speed > 0 {
+			delay := time.Duration(float64(event.At-start)/c.speed) - time.Since(begin)
+			if delay > 0 {
+				select {
+				case <-time.After(delay):
+				case <-ctx.Done():
+					return
+				case <-sub.quit:
+					return
+				}
+			}
+		}
+
+		if event.Method == RecordSubscriptionErr {
+			sub.errCh <- recordedError(event.Error)
+
+			return
+		}
+
+		var header *types.Header
+		if err := json.Unmarshal(event.Result, &header); err != nil {
+			sub.errCh <- err
+
+			return
+		}
+
+		select {
+		case ch <- header:
+		case <-ctx.Done():
+			return
+		case <-sub.quit:
+			return
+		}
+	}
+
+	// Keep the subscription open once all events are replayed, as a live one would be.
+	select {
+	case <-ctx.Done():
+	case <-sub.quit:
+	}
+}
@@ -4,12 +4,32 @@ import (
 	"context"
 
 	"github.com/KyberNetwork/evmlistener/pkg/common"
+	commonclient "github.com/KyberNetwork/evmlistener/pkg/evmclient/common"
 	"github.com/KyberNetwork/evmlistener/pkg/types"
 	"github.com/ethereum/go-ethereum"
 	ethcommon "github.com/ethereum/go-ethereum/common"
 	ethtypes "github.com/ethereum/go-ethereum/core/types"
 )
 
+func fromEthereumHeader(header *ethtypes.Header) *types.Header {
+	return toHeader(header, header.Hash())
+}
+
+func fromCustomHeader(header *commonclient.Header) *types.Header {
+	return toHeader(&header.Header, header.Hash)
+}
+
+func toHeader(header *ethtypes.Header, hash ethcommon.Hash) *types.Header {
+	return &types.Header{
+		Hash:         common.ToHex(hash),
+		ParentHash:   common.ToHex(header.ParentHash),
+		Number:       header.Number,
+		Time:         header.Time,
+		LogsBloom:    header.Bloom.Bytes(),
+		ReceiptsRoot: common.ToHex(header.ReceiptHash),
+	}
+}
+
 func filterLogs(ctx context.Context, client ethereum.LogFilterer, q FilterQuery) ([]types.Log, error) {
 	logs, err := client.FilterLogs(ctx, toEthereumFilterQuery(q))
 	if err != nil {
@@ -0,0 +1,100 @@
+// Package health serves liveness and readiness of the service over HTTP for orchestrators.
+package health
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/KyberNetwork/evmlistener/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	readHeaderTimeout = 5 * time.Second
+	shutdownTimeout   = 5 * time.Second
+)
+
+// Checker reports the health of the service.
+type Checker interface {
+	// Healthy returns nil if the service is alive, the service should be restarted otherwise.
+	Healthy() error
+	// Ready returns nil if the service does its work in time.
+	Ready() error
+}
+
+// Server serves /healthz and /readyz, which respond 200 when the check succeeds and 503 along with
+// the failure otherwise.
+type Server struct {
+	l       *zap.SugaredLogger
+	addr    string
+	checker Checker
+}
+
+// NewServer returns a new Server object listening on addr.
+func NewServer(l *zap.SugaredLogger, addr string, checker Checker) *Server {
+	return &Server{
+		l:       l,
+		addr:    addr,
+		checker: checker,
+	}
+}
+
+// Handler returns the handler of health endpoints.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", s.handle(s.checker.Healthy))
+	mux.HandleFunc("GET /readyz", s.handle(s.checker.Ready))
+
+	return mux
+}
+
+func (s *Server) handle(check func() error) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if err := check(); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(err.Error() + "\n"))
+
+			return
+		}
+
+		_, _ = w.Write([]byte("ok\n"))
+	}
+}
+
+// Run serves health endpoints until ctx is done.
+func (s *Server) Run(ctx context.Context) error {
+	server := &http.Server{
+		Addr:              s.addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: readHeaderTimeout,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		s.l.Infow("Serve health endpoints", "addr", s.addr)
+		errCh <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		s.l.Errorw("Fail to serve health endpoints", "addr", s.addr, "error", err)
+
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		s.l.Errorw("Fail to shutdown health server", "error", err)
+
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package health
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/KyberNetwork/evmlistener/pkg/errors"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+)
+
+type checkerMock struct {
+	healthErr error
+	readyErr  error
+}
+
+func (c *checkerMock) Healthy() error {
+	return c.healthErr
+}
+
+func (c *checkerMock) Ready() error {
+	return c.readyErr
+}
+
+type HealthTestSuite struct {
+	suite.Suite
+
+	checker *checkerMock
+	server  *httptest.Server
+}
+
+func (ts *HealthTestSuite) SetupTest() {
+	ts.checker = &checkerMock{}
+	ts.server = httptest.NewServer(NewServer(zap.S(), "", ts.checker).Handler())
+}
+
+func (ts *HealthTestSuite) TearDownTest() {
+	ts.server.Close()
+}
+
+func (ts *HealthTestSuite) get(path string) (int, string) {
+	resp, err := http.Get(ts.server.URL + path) //nolint:noctx
+	ts.Require().NoError(err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	ts.Require().NoError(err)
+
+	return resp.StatusCode, string(body)
+}
+
+func (ts *HealthTestSuite) TestHealthy() {
+	code, body := ts.get("/healthz")
+	ts.Assert().Equal(http.StatusOK, code)
+	ts.Assert().Equal("ok\n", body)
+
+	ts.checker.healthErr = errors.New("not running")
+	code, body = ts.get("/healthz")
+	ts.Assert().Equal(http.StatusServiceUnavailable, code)
+	ts.Assert().Equal("not running\n", body)
+}
+
+func (ts *HealthTestSuite) TestReady() {
+	ts.checker.readyErr = errors.New("catching up")
+	code, body := ts.get("/readyz")
+	ts.Assert().Equal(http.StatusServiceUnavailable, code)
+	ts.Assert().Equal("catching up\n", body)
+
+	// Readiness does not affect liveness.
+	code, _ = ts.get("/healthz")
+	ts.Assert().Equal(http.StatusOK, code)
+
+	code, _ = ts.get("/unknown")
+	ts.Assert().Equal(http.StatusNotFound, code)
+}
+
+func TestHealthTestSuite(t *testing.T) {
+	suite.Run(t, new(HealthTestSuite))
+}
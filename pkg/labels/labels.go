@@ -0,0 +1,35 @@
+// Package labels carries the chain a request is made for through contexts, so that metrics
+// of a process listening to several chains are labeled by chain.
+package labels
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ChainKey is the attribute key of the chain label.
+const ChainKey = "chain"
+
+type chainKey struct{}
+
+// WithChain returns a copy of ctx labeled with the chain name.
+func WithChain(ctx context.Context, chain string) context.Context {
+	return context.WithValue(ctx, chainKey{}, chain)
+}
+
+// Chain returns the chain name ctx is labeled with, empty if there is none.
+func Chain(ctx context.Context) string {
+	chain, _ := ctx.Value(chainKey{}).(string)
+
+	return chain
+}
+
+// Attributes returns attrs along with the chain label of ctx, if any.
+func Attributes(ctx context.Context, attrs ...attribute.KeyValue) []attribute.KeyValue {
+	if chain := Chain(ctx); chain != "" {
+		return append(attrs, attribute.String(ChainKey, chain))
+	}
+
+	return attrs
+}
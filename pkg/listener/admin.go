@@ -0,0 +1,216 @@
+package listener
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/KyberNetwork/evmlistener/pkg/errors"
+	"github.com/KyberNetwork/evmlistener/pkg/types"
+)
+
+// ErrPaused means the operation can not be done while publishing is paused.
+var ErrPaused = errors.New("publishing is paused")
+
+// control is an operation run by the loop handling blocks, between two blocks.
+type control struct {
+	fn   func(ctx context.Context) error
+	done chan error
+}
+
+// BlockStatus is the number and hash of a block.
+type BlockStatus struct {
+	Number *big.Int `json:"number"`
+	Hash   string   `json:"hash"`
+}
+
+// Status is the state of a listener for inspection.
+type Status struct {
+	Chain              string       `json:"chain"`
+	Running            bool         `json:"running"`
+	Paused             bool         `json:"paused"`
+	Resuming           bool         `json:"resuming"`
+	Leader             *bool        `json:"leader,omitempty"`
+	Head               *BlockStatus `json:"head,omitempty"`
+	LastReceivedBlock  *BlockStatus `json:"lastReceivedBlock,omitempty"`
+	LastHandledBlock   *big.Int     `json:"lastHandledBlock,omitempty"`
+	LastCheckedBlock   *big.Int     `json:"lastCheckedBlock,omitempty"`
+	Lag                *big.Int     `json:"lag,omitempty"`
+	KeeperLen          int          `json:"keeperLen"`
+	KeeperCap          int          `json:"keeperCap"`
+	QueueSize          int          `json:"queueSize"`
+	QueueSequence      uint64       `json:"queueSequence"`
+	PendingBlocks      int          `json:"pendingBlocks"`
+	CatchUpRemaining   uint64       `json:"catchUpRemaining,omitempty"`
+	ReadinessError     string       `json:"readinessError,omitempty"`
+	LastHandledSeconds float64      `json:"lastHandledSeconds,omitempty"`
+}
+
+// ChainName returns the chain name the listener is labeled with.
+func (l *Listener) ChainName() string {
+	return l.option.chainName
+}
+
+// Status returns the current state of the listener.
+func (l *Listener) Status() Status {
+	status := Status{
+		Chain:         l.option.chainName,
+		KeeperLen:     l.handler.blockKeeper.Len(),
+		KeeperCap:     l.handler.blockKeeper.Cap(),
+		QueueSize:     l.queue.Size(),
+		QueueSequence: l.queue.SequenceNumber(),
+	}
+	if err := l.Ready(); err != nil {
+		status.ReadinessError = err.Error()
+	}
+
+	if head, err := l.handler.blockKeeper.Head(); err == nil {
+		status.Head = &BlockStatus{Number: head.Number, Hash: head.Hash}
+	}
+
+	if lease := l.option.lease; lease != nil {
+		leader := lease.Token() != 0
+		status.Leader = &leader
+	}
+
+	if progress := l.getCatchUpProgress(); progress != nil {
+		status.CatchUpRemaining = progress.remaining()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	status.Running = l.running
+	status.Paused = l.paused
+	status.Resuming = l.resuming
+	status.PendingBlocks = len(l.blockCh)
+	if b := l.lastReceivedBlock; b != nil {
+		status.LastReceivedBlock = &BlockStatus{Number: b.Number, Hash: b.Hash}
+	}
+	status.LastHandledBlock = l.lastHandledBlockNumber
+	status.LastCheckedBlock = l.lastCheckedBlockNumber
+	if !l.lastHandledTime.IsZero() {
+		status.LastHandledSeconds = time.Since(l.lastHandledTime).Seconds()
+	}
+
+	head := l.lastCheckedBlockNumber
+	if l.lastReceivedBlock != nil && (head == nil || l.lastReceivedBlock.Number.Cmp(head) > 0) {
+		head = l.lastReceivedBlock.Number
+	}
+	if head != nil && l.lastHandledBlockNumber != nil {
+		status.Lag = new(big.Int).Sub(head, l.lastHandledBlockNumber)
+	}
+
+	return status
+}
+
+// RecentBlocks returns the n most recent blocks of the block keeper.
+func (l *Listener) RecentBlocks(n int) ([]types.Block, error) {
+	return l.handler.blockKeeper.GetRecentBlocks(n)
+}
+
+// Pause stops handling and publishing blocks, which are held until Resume is called. The head of
+// the block keeper does not move meanwhile, so that held blocks are fetched again after a restart.
+// Once the held blocks fill the buffer, the subscription for new heads is stopped, and following
+// blocks are fetched when catching up after Resume.
+func (l *Listener) Pause() {
+	l.setPaused(true)
+}
+
+// Resume resumes handling and publishing blocks, starting with the blocks held while paused.
+func (l *Listener) Resume() {
+	l.setPaused(false)
+}
+
+func (l *Listener) setPaused(v bool) {
+	l.mu.Lock()
+	changed := l.paused != v
+	l.paused = v
+	if changed && v {
+		l.resumeCh = make(chan struct{})
+	} else if changed {
+		close(l.resumeCh)
+	}
+	l.mu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	if v {
+		l.l.Warnw("Pause publishing blocks")
+	} else {
+		l.l.Infow("Resume publishing blocks")
+	}
+
+	select {
+	case l.pauseCh <- struct{}{}:
+	default:
+	}
+}
+
+func (l *Listener) isPaused() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.paused
+}
+
+// waitResumed waits until publishing is resumed or ctx is done.
+func (l *Listener) waitResumed(ctx context.Context) error {
+	l.mu.Lock()
+	paused, resumeCh := l.paused, l.resumeCh
+	l.mu.Unlock()
+
+	if !paused {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-resumeCh:
+		return nil
+	}
+}
+
+// Resync publishes a resync message from block number fromBlock up to the block keeper head, see
+// Handler.ForceResync. It is run between two blocks, so the listener must be running and not paused.
+func (l *Listener) Resync(ctx context.Context, fromBlock uint64) error {
+	return l.runControl(ctx, func(ctx context.Context) error {
+		return l.handler.ForceResync(ctx, fromBlock)
+	})
+}
+
+// Rewind re-publishes blocks after block number number, see Handler.Rewind. It is run between two
+// blocks, so the listener must be running and not paused.
+func (l *Listener) Rewind(ctx context.Context, number uint64) error {
+	return l.runControl(ctx, func(ctx context.Context) error {
+		return l.handler.Rewind(ctx, number)
+	})
+}
+
+// runControl runs fn in the loop handling blocks, and waits for its result.
+func (l *Listener) runControl(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := l.Healthy(); err != nil {
+		return err
+	}
+
+	if l.isPaused() {
+		return ErrPaused
+	}
+
+	c := control{fn: fn, done: make(chan error, 1)}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case l.controlCh <- c:
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-c.done:
+		return err
+	}
+}
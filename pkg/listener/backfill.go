@@ -0,0 +1,213 @@
+package listener
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/KyberNetwork/evmlistener/pkg/errors"
+	"github.com/KyberNetwork/evmlistener/pkg/evmclient"
+	"github.com/KyberNetwork/evmlistener/pkg/pubsub"
+	"github.com/KyberNetwork/evmlistener/pkg/types"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	defaultBackfillConcurrency = 4
+	defaultBackfillChunkSize   = 32
+)
+
+// Checkpoint stores progress of a backfill, so that an interrupted backfill can be resumed.
+type Checkpoint interface {
+	// Load returns the next block to backfill, ok is false if no progress was saved.
+	Load(ctx context.Context) (next uint64, ok bool, err error)
+	// Save saves the next block to backfill.
+	Save(ctx context.Context, next uint64) error
+}
+
+// BackfillConfig contains the range and settings of a backfill.
+type BackfillConfig struct {
+	Topic     string
+	FromBlock uint64
+	ToBlock   uint64
+	// Concurrency is the maximum number of chunks fetched at the same time.
+	Concurrency int
+	// ChunkSize is the number of blocks published in a message.
+	ChunkSize uint64
+}
+
+// Backfiller re-publishes blocks of a past range. Blocks are fetched by chunks concurrently
+// and published in order, one message per chunk. It does not use the block keeper of the
+// live listener.
+type Backfiller struct {
+	l          *zap.SugaredLogger
+	evmClient  evmclient.IClient
+	publisher  pubsub.Publisher
+	checkpoint Checkpoint
+	cfg        BackfillConfig
+	option     *FilterOption
+	batcher    *blockBatcher
+}
+
+// NewBackfiller returns a new Backfiller, checkpoint is optional.
+func NewBackfiller(
+	l *zap.SugaredLogger, evmClient evmclient.IClient, publisher pubsub.Publisher,
+	checkpoint Checkpoint, cfg BackfillConfig, opts ...Option,
+) *Backfiller {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = defaultBackfillConcurrency
+	}
+	if cfg.ChunkSize == 0 {
+		cfg.ChunkSize = defaultBackfillChunkSize
+	}
+
+	option := newFilterOption(opts...)
+
+	return &Backfiller{
+		l:          l,
+		evmClient:  evmClient,
+		publisher:  publisher,
+		checkpoint: checkpoint,
+		cfg:        cfg,
+		option:     option,
+		batcher:    newBlockBatcher(l, option.batchSize),
+	}
+}
+
+// start returns the first block to backfill, resuming from the checkpoint if there is one.
+func (b *Backfiller) start(ctx context.Context) (uint64, error) {
+	if b.checkpoint == nil {
+		return b.cfg.FromBlock, nil
+	}
+
+	next, ok, err := b.checkpoint.Load(ctx)
+	if err != nil {
+		b.l.Errorw("Fail to load backfill checkpoint", "error", err)
+
+		return 0, err
+	}
+
+	if !ok || next < b.cfg.FromBlock || next > b.cfg.ToBlock+1 {
+		return b.cfg.FromBlock, nil
+	}
+
+	b.l.Infow("Resume backfill from checkpoint", "next", next)
+
+	return next, nil
+}
+
+func (b *Backfiller) fetch(ctx context.Context, fromBlock, toBlock uint64) ([]types.Block, error) {
+	if blocks, ok := b.batcher.getBlocks(ctx, b.evmClient, fromBlock, toBlock, b.option); ok {
+		return blocks, nil
+	}
+
+	return fetchBlocks(ctx, b.evmClient, fromBlock, toBlock, b.option)
+}
+
+// Run backfills the range and returns when all blocks are published.
+func (b *Backfiller) Run(ctx context.Context) error {
+	if b.cfg.FromBlock > b.cfg.ToBlock {
+		return fmt.Errorf("%w: from block %d is after to block %d",
+			errors.ErrInvalidArgument, b.cfg.FromBlock, b.cfg.ToBlock)
+	}
+
+	start, err := b.start(ctx)
+	if err != nil {
+		return err
+	}
+
+	if start > b.cfg.ToBlock {
+		b.l.Infow("Backfill was already completed", "from", b.cfg.FromBlock, "to", b.cfg.ToBlock)
+
+		return nil
+	}
+
+	b.l.Infow("Start backfill", "from", start, "to", b.cfg.ToBlock, "topic", b.cfg.Topic,
+		"concurrency", b.cfg.Concurrency, "chunkSize", b.cfg.ChunkSize)
+
+	g, ctx := errgroup.WithContext(ctx)
+
+	// Chunks are fetched concurrently, their results are queued in order so that they are
+	// published in order, the queue bounds the number of chunks in flight.
+	results := make(chan chan []types.Block, b.cfg.Concurrency)
+	g.Go(func() error {
+		defer close(results)
+
+		for from := start; from <= b.cfg.ToBlock; from += b.cfg.ChunkSize {
+			to := min(from+b.cfg.ChunkSize-1, b.cfg.ToBlock)
+			ch := make(chan []types.Block, 1)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case results <- ch:
+			}
+
+			g.Go(func() error {
+				blocks, err := b.fetch(ctx, from, to)
+				if err != nil {
+					b.l.Errorw("Fail to get blocks", "from", from, "to", to, "error", err)
+
+					return err
+				}
+				ch <- blocks
+
+				return nil
+			})
+		}
+
+		return nil
+	})
+
+	g.Go(func() error {
+		for ch := range results {
+			var blocks []types.Block
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case blocks = <-ch:
+			}
+
+			if err := b.publish(ctx, blocks); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err := g.Wait(); err != nil {
+		return err
+	}
+
+	b.l.Infow("Finish backfill", "from", b.cfg.FromBlock, "to", b.cfg.ToBlock)
+
+	return nil
+}
+
+func (b *Backfiller) publish(ctx context.Context, blocks []types.Block) error {
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	last := blocks[len(blocks)-1].Number.Uint64()
+	err := b.publisher.Publish(ctx, b.cfg.Topic, types.Message{NewBlocks: blocks})
+	if err != nil {
+		b.l.Errorw("Fail to publish message", "to", last, "error", err)
+
+		return err
+	}
+
+	if b.checkpoint != nil {
+		if err = b.checkpoint.Save(ctx, last+1); err != nil {
+			b.l.Errorw("Fail to save backfill checkpoint", "next", last+1, "error", err)
+
+			return err
+		}
+	}
+
+	total := b.cfg.ToBlock - b.cfg.FromBlock + 1
+	b.l.Infow("Backfill progress", "block", last, "to", b.cfg.ToBlock,
+		"progress", fmt.Sprintf("%.2f%%", float64(last-b.cfg.FromBlock+1)*100/float64(total))) //nolint:gomnd
+
+	return nil
+}
@@ -0,0 +1,141 @@
+package listener
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/KyberNetwork/evmlistener/pkg/errors"
+	"github.com/KyberNetwork/evmlistener/pkg/evmclient"
+	"github.com/KyberNetwork/evmlistener/pkg/simnode"
+	"github.com/KyberNetwork/evmlistener/pkg/types"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+)
+
+type memoryCheckpoint struct {
+	mu   sync.Mutex
+	next uint64
+	ok   bool
+}
+
+func (c *memoryCheckpoint) Load(context.Context) (uint64, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.next, c.ok, nil
+}
+
+func (c *memoryCheckpoint) Save(_ context.Context, next uint64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.next, c.ok = next, true
+
+	return nil
+}
+
+type BackfillTestSuite struct {
+	suite.Suite
+
+	node   *simnode.Node
+	client *evmclient.Client
+}
+
+func (ts *BackfillTestSuite) SetupTest() {
+	var err error
+	ts.node, err = simnode.New(simnode.WithInitialBlocks(40))
+	ts.Require().NoError(err)
+	ts.Require().NoError(ts.node.Start())
+
+	ts.client, err = evmclient.Dial(ts.node.HTTPURL(), http.DefaultClient)
+	ts.Require().NoError(err)
+}
+
+func (ts *BackfillTestSuite) TearDownTest() {
+	ts.node.Close()
+}
+
+func (ts *BackfillTestSuite) run(checkpoint Checkpoint, from, to uint64) []types.Block {
+	publisher := NewPublisherMock(64)
+	backfiller := NewBackfiller(zap.S(), ts.client, publisher, checkpoint, BackfillConfig{
+		Topic:       "backfill",
+		FromBlock:   from,
+		ToBlock:     to,
+		Concurrency: 3,
+		ChunkSize:   4,
+	}, WithEventLogs(nil, nil))
+	ts.Require().NoError(backfiller.Run(context.Background()))
+	close(publisher.ch)
+
+	var blocks []types.Block
+	for msg := range publisher.ch {
+		blocks = append(blocks, msg.(types.Message).NewBlocks...) //nolint:forcetypeassert
+	}
+
+	return blocks
+}
+
+func (ts *BackfillTestSuite) TestRun() {
+	checkpoint := &memoryCheckpoint{}
+	blocks := ts.run(checkpoint, 5, 30)
+	ts.Require().Len(blocks, 26)
+	for i, b := range blocks {
+		ts.Assert().Equal(uint64(5+i), b.Number.Uint64())
+		ts.Assert().NotEmpty(b.Logs)
+		if i > 0 {
+			ts.Assert().Equal(blocks[i-1].Hash, b.ParentHash)
+		}
+	}
+
+	next, ok, _ := checkpoint.Load(context.Background())
+	ts.Assert().True(ok)
+	ts.Assert().Equal(uint64(31), next)
+
+	// Completed backfill publishes nothing.
+	ts.Assert().Empty(ts.run(checkpoint, 5, 30))
+}
+
+func (ts *BackfillTestSuite) TestResume() {
+	checkpoint := &memoryCheckpoint{next: 21, ok: true}
+	blocks := ts.run(checkpoint, 5, 30)
+	ts.Require().Len(blocks, 10)
+	ts.Assert().Equal(uint64(21), blocks[0].Number.Uint64())
+
+	// Checkpoint out of the range is ignored.
+	checkpoint = &memoryCheckpoint{next: 2, ok: true}
+	ts.Assert().Len(ts.run(checkpoint, 5, 30), 26)
+}
+
+func (ts *BackfillTestSuite) TestInvalidRange() {
+	backfiller := NewBackfiller(zap.S(), ts.client, NewPublisherMock(1), nil, BackfillConfig{
+		FromBlock: 10,
+		ToBlock:   5,
+	})
+	ts.Assert().ErrorIs(backfiller.Run(context.Background()), errors.ErrInvalidArgument)
+}
+
+func (ts *BackfillTestSuite) TestFindBlockByTimestamp() {
+	ctx := context.Background()
+	header, err := ts.client.HeaderByNumber(ctx, big.NewInt(17))
+	ts.Require().NoError(err)
+
+	number, err := FindBlockByTimestamp(ctx, ts.client, header.Time)
+	ts.Require().NoError(err)
+	ts.Assert().Equal(uint64(17), number)
+
+	number, err = FindBlockByTimestamp(ctx, ts.client, 0)
+	ts.Require().NoError(err)
+	ts.Assert().Equal(uint64(0), number)
+
+	head, err := ts.client.HeaderByNumber(ctx, nil)
+	ts.Require().NoError(err)
+	_, err = FindBlockByTimestamp(ctx, ts.client, head.Time+1)
+	ts.Assert().ErrorIs(err, errors.ErrNotFound)
+}
+
+func TestBackfillTestSuite(t *testing.T) {
+	suite.Run(t, new(BackfillTestSuite))
+}
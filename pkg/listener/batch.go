@@ -0,0 +1,188 @@
+package listener
+
+import (
+	"context"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/KyberNetwork/evmlistener/pkg/errors"
+	"github.com/KyberNetwork/evmlistener/pkg/evmclient"
+	"github.com/KyberNetwork/evmlistener/pkg/retry"
+	"github.com/KyberNetwork/evmlistener/pkg/types"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxBatchFailures is the number of consecutive rejected batches after which
+	// batch requests are disabled, e.g. when the provider does not support batches.
+	maxBatchFailures = 3
+)
+
+var errInconsistentBlocks = errors.New("blocks are not consistent")
+
+// blockBatcher fetches a range of blocks using JSON-RPC batch requests.
+type blockBatcher struct {
+	l        *zap.SugaredLogger
+	size     int
+	failures atomic.Int32
+}
+
+func newBlockBatcher(l *zap.SugaredLogger, size int) *blockBatcher {
+	if size <= 0 {
+		return nil
+	}
+
+	return &blockBatcher{l: l, size: size}
+}
+
+// available returns the batch client if batch requests can be used.
+func (b *blockBatcher) available(evmClient evmclient.IClient) (evmclient.BatchClient, bool) {
+	if b == nil || b.failures.Load() >= maxBatchFailures {
+		return nil, false
+	}
+
+	batchClient, ok := evmClient.(evmclient.BatchClient)
+
+	return batchClient, ok
+}
+
+// done records result of batch requests, batching is disabled after consecutive rejected batches.
+// Retryable errors, such as rate limits or blocks not found at the chain tip, and re-organizations
+// between batches do not count as rejections.
+func (b *blockBatcher) done(ctx context.Context, fromBlock, toBlock uint64, err error) bool {
+	if err == nil {
+		b.failures.Store(0)
+
+		return true
+	}
+
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if errors.Is(err, evmclient.ErrBatchNotSupported) {
+		b.failures.Store(maxBatchFailures)
+		b.l.Warnw("Disable batch requests as they are not supported by the node client")
+
+		return false
+	}
+
+	if errors.Is(err, errInconsistentBlocks) || retry.Classify(err).Retryable() {
+		b.l.Warnw("Fail to get blocks with batch requests, fall back to single requests",
+			"from", fromBlock, "to", toBlock, "error", err)
+
+		return false
+	}
+
+	failures := b.failures.Add(1)
+	b.l.Warnw("Fail to get blocks with batch requests, fall back to single requests",
+		"from", fromBlock, "to", toBlock, "failures", failures, "error", err)
+	if failures >= maxBatchFailures {
+		b.l.Warnw("Disable batch requests after consecutive failures", "failures", failures)
+	}
+
+	return false
+}
+
+// getHeaders returns headers in range [fromBlock, toBlock]. The second return value is false
+// if batch requests can not be used, in that case caller should fall back to fetch headers one by one.
+func (b *blockBatcher) getHeaders(ctx context.Context, evmClient evmclient.IClient,
+	fromBlock, toBlock uint64,
+) ([]*types.Header, bool) {
+	batchClient, ok := b.available(evmClient)
+	if !ok {
+		return nil, false
+	}
+
+	headers, err := b.fetchHeaders(ctx, batchClient, fromBlock, toBlock)
+
+	return headers, b.done(ctx, fromBlock, toBlock, err)
+}
+
+// getBlocks returns blocks in range [fromBlock, toBlock]. The second return value is false
+// if batch requests can not be used, in that case caller should fall back to fetch blocks one by one.
+func (b *blockBatcher) getBlocks(ctx context.Context, evmClient evmclient.IClient,
+	fromBlock, toBlock uint64, opts *FilterOption,
+) ([]types.Block, bool) {
+	batchClient, ok := b.available(evmClient)
+	if !ok {
+		return nil, false
+	}
+
+	blocks, err := b.fetch(ctx, evmClient, batchClient, fromBlock, toBlock, opts)
+
+	return blocks, b.done(ctx, fromBlock, toBlock, err)
+}
+
+func (b *blockBatcher) fetchHeaders(ctx context.Context, batchClient evmclient.BatchClient,
+	fromBlock, toBlock uint64,
+) ([]*types.Header, error) {
+	headers := make([]*types.Header, 0, toBlock-fromBlock+1)
+	for from := fromBlock; from <= toBlock; from += uint64(b.size) {
+		to := min(from+uint64(b.size)-1, toBlock)
+		numbers := make([]*big.Int, 0, to-from+1)
+		for n := from; n <= to; n++ {
+			numbers = append(numbers, new(big.Int).SetUint64(n))
+		}
+
+		res, err := batchClient.BatchHeadersByNumber(ctx, numbers)
+		if err != nil {
+			return nil, err
+		}
+
+		headers = append(headers, res...)
+	}
+
+	// Make sure the headers belong to the same chain, a re-organization may happen between batches.
+	if err := checkHeaders(headers); err != nil {
+		return nil, err
+	}
+
+	return headers, nil
+}
+
+func (b *blockBatcher) fetch(ctx context.Context, evmClient evmclient.IClient,
+	batchClient evmclient.BatchClient, fromBlock, toBlock uint64, opts *FilterOption,
+) ([]types.Block, error) {
+	headers, err := b.fetchHeaders(ctx, batchClient, fromBlock, toBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	logs := make([][]types.Log, len(headers))
+	if opts.withLogs {
+		for from := 0; from < len(headers); from += b.size {
+			to := min(from+b.size, len(headers))
+			queries := make([]evmclient.FilterQuery, 0, to-from)
+			for _, header := range headers[from:to] {
+				queries = append(queries, evmclient.FilterQuery{
+					BlockHash: &header.Hash,
+					Addresses: opts.filterContracts,
+					Topics:    opts.filterTopics,
+				})
+			}
+
+			res, err := batchClient.BatchFilterLogs(ctx, queries)
+			if err != nil {
+				return nil, err
+			}
+
+			copy(logs[from:to], res)
+		}
+	}
+
+	blocks := make([]types.Block, 0, len(headers))
+	for i, header := range headers {
+		if opts.withLogs && verifyLogs(ctx, evmClient, header, logs[i], opts) != nil {
+			// Node may return incomplete logs for a block it has not fully processed, re-check it with retries.
+			logs[i], err = getLogsByBlockHash(ctx, evmClient, header, opts)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		blocks = append(blocks, headerToBlock(header, logs[i]))
+	}
+
+	return blocks, nil
+}
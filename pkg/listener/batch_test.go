@@ -0,0 +1,82 @@
+package listener
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+)
+
+type BlockBatcherTestSuite struct {
+	suite.Suite
+
+	client *ChainClientMock
+	opts   *FilterOption
+}
+
+func (ts *BlockBatcherTestSuite) SetupTest() {
+	ts.client = NewChainClientMock(1000, 100, 2)
+	ts.opts = newFilterOption(WithEventLogs(nil, nil), WithBatchSize(10))
+}
+
+func (ts *BlockBatcherTestSuite) TestGetBlocks() {
+	batcher := newBlockBatcher(zap.S(), ts.opts.batchSize)
+
+	blocks, ok := batcher.getBlocks(context.Background(), ts.client, 1010, 1034, ts.opts)
+	ts.Require().True(ok)
+	ts.Require().Len(blocks, 25)
+	for i, b := range blocks {
+		ts.Assert().Equal(ts.client.Header(uint64(1010+i)).Hash, b.Hash)
+		ts.Assert().Len(b.Logs, 2)
+	}
+
+	ts.Assert().Equal(3, ts.client.Calls("BatchHeadersByNumber"))
+	ts.Assert().Equal(3, ts.client.Calls("BatchFilterLogs"))
+	ts.Assert().Equal(0, ts.client.Calls("HeaderByNumber"))
+	ts.Assert().Equal(0, ts.client.Calls("FilterLogs"))
+}
+
+func (ts *BlockBatcherTestSuite) TestFallback() {
+	batcher := newBlockBatcher(zap.S(), ts.opts.batchSize)
+	ts.client.SetBatchError(errors.New("batch is not allowed"))
+
+	for range maxBatchFailures {
+		_, ok := batcher.getBlocks(context.Background(), ts.client, 1010, 1019, ts.opts)
+		ts.Require().False(ok)
+	}
+	ts.Assert().Equal(maxBatchFailures, ts.client.Calls("BatchHeadersByNumber"))
+
+	// Batch requests are disabled after consecutive failures.
+	_, ok := batcher.getBlocks(context.Background(), ts.client, 1010, 1019, ts.opts)
+	ts.Require().False(ok)
+	ts.Assert().Equal(maxBatchFailures, ts.client.Calls("BatchHeadersByNumber"))
+
+	// Nil batcher means batching is disabled.
+	_, ok = newBlockBatcher(zap.S(), 0).getBlocks(context.Background(), ts.client, 1010, 1019, ts.opts)
+	ts.Assert().False(ok)
+}
+
+func (ts *BlockBatcherTestSuite) TestRetryableFailures() {
+	batcher := newBlockBatcher(zap.S(), ts.opts.batchSize)
+	ts.client.SetBatchError(rpc.HTTPError{StatusCode: http.StatusTooManyRequests, Status: "429 Too Many Requests"})
+
+	// Rate limited batches fall back to single requests without disabling batching.
+	for range maxBatchFailures + 1 {
+		_, ok := batcher.getBlocks(context.Background(), ts.client, 1010, 1019, ts.opts)
+		ts.Require().False(ok)
+	}
+	ts.Assert().Equal(maxBatchFailures+1, ts.client.Calls("BatchHeadersByNumber"))
+
+	ts.client.SetBatchError(nil)
+	blocks, ok := batcher.getBlocks(context.Background(), ts.client, 1010, 1019, ts.opts)
+	ts.Require().True(ok)
+	ts.Assert().Len(blocks, 10)
+}
+
+func TestBlockBatcherTestSuite(t *testing.T) {
+	suite.Run(t, new(BlockBatcherTestSuite))
+}
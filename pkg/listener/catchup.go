@@ -0,0 +1,253 @@
+package listener
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/KyberNetwork/evmlistener/pkg/types"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	defaultCatchUpWindow = 256
+
+	// A fetch slower than slowLatencyFactor times the average latency is a sign of an overloaded node.
+	slowLatencyFactor = 2
+	latencyEWMAWeight = 0.2
+
+	catchUpLogInterval = 10 * time.Second
+)
+
+// aimdLimiter limits the number of concurrent fetches, the limit is increased by one after every
+// fast success and halved after an error or a slow response.
+type aimdLimiter struct {
+	mu       sync.Mutex
+	limit    int
+	maxLimit int
+	inFlight int
+	latency  time.Duration
+	notify   chan struct{}
+}
+
+func newAIMDLimiter(maxLimit int) *aimdLimiter {
+	return &aimdLimiter{
+		limit:    max(maxLimit/2, 1), //nolint:gomnd
+		maxLimit: max(maxLimit, 1),
+		notify:   make(chan struct{}),
+	}
+}
+
+// acquire waits until a fetch is allowed.
+func (a *aimdLimiter) acquire(ctx context.Context) error {
+	for {
+		a.mu.Lock()
+		if a.inFlight < a.limit {
+			a.inFlight++
+			a.mu.Unlock()
+
+			return nil
+		}
+		notify := a.notify
+		a.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-notify:
+		}
+	}
+}
+
+// release ends a fetch, adjusting the limit to its latency and error.
+func (a *aimdLimiter) release(latency time.Duration, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.inFlight--
+	switch {
+	case err != nil, a.latency > 0 && latency > slowLatencyFactor*a.latency:
+		a.limit = max(a.limit/2, 1) //nolint:gomnd
+	default:
+		a.limit = min(a.limit+1, a.maxLimit)
+	}
+
+	if err == nil {
+		if a.latency == 0 {
+			a.latency = latency
+		} else {
+			a.latency += time.Duration(latencyEWMAWeight * float64(latency-a.latency))
+		}
+	}
+
+	close(a.notify)
+	a.notify = make(chan struct{})
+}
+
+func (a *aimdLimiter) current() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.limit
+}
+
+// catchUpProgress tracks progress of catching up with the chain.
+type catchUpProgress struct {
+	limiter *aimdLimiter
+
+	mu        sync.Mutex
+	fromBlock uint64
+	toBlock   uint64
+	lastBlock uint64
+	startTime time.Time
+}
+
+func (p *catchUpProgress) update(lastBlock uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.lastBlock = lastBlock
+}
+
+// remaining returns the number of blocks left to emit.
+func (p *catchUpProgress) remaining() uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.toBlock - p.lastBlock
+}
+
+// rate returns the number of blocks emitted per second.
+func (p *catchUpProgress) rate() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return float64(p.lastBlock+1-p.fromBlock) / time.Since(p.startTime).Seconds()
+}
+
+// eta returns the estimated time left to catch up, at the average rate so far.
+func (p *catchUpProgress) eta() time.Duration {
+	rate := p.rate()
+	if rate <= 0 {
+		return 0
+	}
+
+	return time.Duration(float64(p.remaining()) / rate * float64(time.Second))
+}
+
+// catchUp fetches blocks in range [fromBlock, toBlock] and sends them to blockCh in order.
+// Chunks of blocks are fetched concurrently, with at most the catch up window of blocks in flight,
+// and the concurrency adapts to errors and latency of the node.
+func (l *Listener) catchUp(ctx context.Context, fromBlock, toBlock uint64, blockCh chan<- types.Block) error {
+	window := l.option.catchUpWindow()
+	chunkSize := uint64(min(max(defaultBatchSize, l.option.batchSize), window))
+	maxConcurrency := max(window/int(chunkSize), 1)
+
+	progress := &catchUpProgress{
+		limiter:   newAIMDLimiter(maxConcurrency),
+		fromBlock: fromBlock,
+		toBlock:   toBlock,
+		lastBlock: fromBlock - 1,
+		startTime: time.Now(),
+	}
+	l.mu.Lock()
+	l.catchUpProgress = progress
+	l.mu.Unlock()
+
+	g, ctx := errgroup.WithContext(ctx)
+
+	// Results of chunks are queued in order, so that blocks are emitted in order however long
+	// each chunk takes. The queue bounds the number of chunks in flight.
+	results := make(chan chan []types.Block, maxConcurrency)
+	g.Go(func() error {
+		defer close(results)
+
+		for from := fromBlock; from <= toBlock; from += chunkSize {
+			to := min(from+chunkSize-1, toBlock)
+			ch := make(chan []types.Block, 1)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case results <- ch:
+			}
+
+			g.Go(func() error {
+				blocks, err := l.fetchChunk(ctx, progress.limiter, from, to)
+				if err != nil {
+					l.l.Errorw("Fail to get blocks", "from", from, "to", to, "error", err)
+
+					return err
+				}
+				ch <- blocks
+
+				return nil
+			})
+		}
+
+		return nil
+	})
+
+	g.Go(func() error {
+		lastLog := time.Now()
+		for ch := range results {
+			var blocks []types.Block
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case blocks = <-ch:
+			}
+
+			if len(blocks) == 0 {
+				continue
+			}
+
+			l.mu.Lock()
+			l.lastReceivedBlock = &blocks[len(blocks)-1]
+			l.mu.Unlock()
+
+			for i := range blocks {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case blockCh <- blocks[i]:
+				}
+			}
+
+			last := blocks[len(blocks)-1].Number.Uint64()
+			progress.update(last)
+			if time.Since(lastLog) >= catchUpLogInterval || last == toBlock {
+				lastLog = time.Now()
+				l.l.Infow("Catch up progress", "block", last, "toBlock", toBlock,
+					"progress", fmt.Sprintf("%.2f%%", float64(last+1-fromBlock)*100/float64(toBlock+1-fromBlock)), //nolint:gomnd
+					"blocksPerSecond", progress.rate(), "eta", progress.eta(),
+					"concurrency", progress.limiter.current())
+			}
+		}
+
+		return nil
+	})
+
+	return g.Wait()
+}
+
+// fetchChunk fetches blocks in range [fromBlock, toBlock], retrying with the retry policy.
+func (l *Listener) fetchChunk(
+	ctx context.Context, limiter *aimdLimiter, fromBlock, toBlock uint64,
+) ([]types.Block, error) {
+	var blocks []types.Block
+	err := l.option.retry().Do(ctx, "catch up", func(ctx context.Context) error {
+		if err := limiter.acquire(ctx); err != nil {
+			return err
+		}
+
+		start := time.Now()
+		var err error
+		blocks, err = l.getBlocks(ctx, fromBlock, toBlock)
+		limiter.release(time.Since(start), err)
+
+		return err
+	})
+
+	return blocks, err
+}
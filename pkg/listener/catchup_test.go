@@ -0,0 +1,145 @@
+package listener
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KyberNetwork/evmlistener/pkg/retry"
+	"github.com/KyberNetwork/evmlistener/pkg/types"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+)
+
+// flakyClientMock delays and fails header requests of chosen blocks.
+type flakyClientMock struct {
+	*ChainClientMock
+
+	mu       sync.Mutex
+	delays   map[uint64]time.Duration
+	failures map[uint64]int
+}
+
+func (c *flakyClientMock) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	if number != nil {
+		c.mu.Lock()
+		delay := c.delays[number.Uint64()]
+		fail := c.failures[number.Uint64()] > 0
+		if fail {
+			c.failures[number.Uint64()]--
+		}
+		c.mu.Unlock()
+
+		time.Sleep(delay)
+		if fail {
+			return nil, retry.WithClass(errors.New("too many requests"), retry.ClassRateLimited)
+		}
+	}
+
+	return c.ChainClientMock.HeaderByNumber(ctx, number)
+}
+
+type CatchUpTestSuite struct {
+	suite.Suite
+
+	client *flakyClientMock
+}
+
+func (ts *CatchUpTestSuite) SetupTest() {
+	ts.client = &flakyClientMock{
+		ChainClientMock: NewChainClientMock(1000, 300, 1),
+		delays:          make(map[uint64]time.Duration),
+		failures:        make(map[uint64]int),
+	}
+}
+
+func (ts *CatchUpTestSuite) catchUp(fromBlock, toBlock uint64, opts ...Option) []types.Block {
+	opts = append([]Option{
+		WithEventLogs(nil, nil),
+		WithCatchUpWindow(128),
+		WithRetryPolicy(retry.Policy{MaxAttempts: 2, InitialInterval: time.Millisecond}),
+	}, opts...)
+	l := New(zap.S(), ts.client, ts.client, nil, nil, 0, opts...)
+
+	blockCh := make(chan types.Block, toBlock-fromBlock+1)
+	ts.Require().NoError(l.catchUp(context.Background(), fromBlock, toBlock, blockCh))
+	close(blockCh)
+
+	var blocks []types.Block
+	for b := range blockCh {
+		blocks = append(blocks, b)
+	}
+
+	progress := l.getCatchUpProgress()
+	ts.Require().NotNil(progress)
+	ts.Assert().Zero(progress.remaining())
+
+	return blocks
+}
+
+func (ts *CatchUpTestSuite) assertBlocks(blocks []types.Block, fromBlock, toBlock uint64) {
+	ts.Require().Len(blocks, int(toBlock-fromBlock+1))
+	for i, b := range blocks {
+		ts.Assert().Equal(ts.client.Header(fromBlock+uint64(i)).Hash, b.Hash)
+		ts.Assert().Len(b.Logs, 1)
+	}
+}
+
+func (ts *CatchUpTestSuite) TestOrdered() {
+	// A slow block does not reorder blocks.
+	ts.client.delays[1010] = 200 * time.Millisecond
+	blocks := ts.catchUp(1001, 1250)
+	ts.assertBlocks(blocks, 1001, 1250)
+}
+
+func (ts *CatchUpTestSuite) TestRetry() {
+	// Block fails more often than the retry policy allows for a request, so the chunk is retried.
+	ts.client.failures[1100] = 3
+	blocks := ts.catchUp(1001, 1150)
+	ts.assertBlocks(blocks, 1001, 1150)
+	ts.Assert().Zero(ts.client.failures[1100])
+}
+
+func (ts *CatchUpTestSuite) TestBatch() {
+	blocks := ts.catchUp(1001, 1100, WithBatchSize(10))
+	ts.assertBlocks(blocks, 1001, 1100)
+	ts.Assert().Zero(ts.client.Calls("HeaderByNumber"))
+}
+
+func (ts *CatchUpTestSuite) TestAIMDLimiter() {
+	limiter := newAIMDLimiter(8)
+	ts.Assert().Equal(4, limiter.current())
+
+	ctx := context.Background()
+	for range 10 {
+		ts.Require().NoError(limiter.acquire(ctx))
+		limiter.release(10*time.Millisecond, nil)
+	}
+	ts.Assert().Equal(8, limiter.current())
+
+	// Errors and slow responses halve the limit.
+	ts.Require().NoError(limiter.acquire(ctx))
+	limiter.release(10*time.Millisecond, errors.New("timeout"))
+	ts.Assert().Equal(4, limiter.current())
+
+	ts.Require().NoError(limiter.acquire(ctx))
+	limiter.release(time.Second, nil)
+	ts.Assert().Equal(2, limiter.current())
+
+	// Acquire waits for a release when the limit is reached.
+	ts.Require().NoError(limiter.acquire(ctx))
+	ts.Require().NoError(limiter.acquire(ctx))
+	timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	ts.Assert().ErrorIs(limiter.acquire(timeoutCtx), context.DeadlineExceeded)
+
+	go limiter.release(10*time.Millisecond, nil)
+	ts.Assert().NoError(limiter.acquire(ctx))
+}
+
+func TestCatchUpTestSuite(t *testing.T) {
+	suite.Run(t, new(CatchUpTestSuite))
+}
@@ -0,0 +1,251 @@
+package listener
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/KyberNetwork/evmlistener/pkg/evmclient"
+	"github.com/KyberNetwork/evmlistener/pkg/types"
+	"github.com/ethereum/go-ethereum"
+)
+
+// ChainClientMock is an in-memory EVM client serving a generated chain.
+type ChainClientMock struct {
+	mu        sync.Mutex
+	seq       int
+	base      uint64
+	headers   []*types.Header
+	byHash    map[string]*types.Header
+	logs      map[string][]types.Log
+	numLogs   int
+	calls     map[string]int
+	batchErr  error
+	subHeadCh []chan<- *types.Header
+}
+
+// NewChainClientMock returns a mock with n blocks starting from block number base,
+// each block has numLogs logs.
+func NewChainClientMock(base uint64, n int, numLogs int) *ChainClientMock {
+	c := &ChainClientMock{
+		base:    base,
+		byHash:  make(map[string]*types.Header),
+		logs:    make(map[string][]types.Log),
+		numLogs: numLogs,
+		calls:   make(map[string]int),
+	}
+	c.extend(n)
+
+	return c
+}
+
+func (c *ChainClientMock) newHash() string {
+	c.seq++
+
+	return fmt.Sprintf("0x%064x", c.seq)
+}
+
+func (c *ChainClientMock) extend(n int) []*types.Header {
+	res := make([]*types.Header, 0, n)
+	for range n {
+		number := c.base + uint64(len(c.headers))
+		parentHash := "0x" + fmt.Sprintf("%064x", 0)
+		if len(c.headers) > 0 {
+			parentHash = c.headers[len(c.headers)-1].Hash
+		}
+
+		header := &types.Header{
+			Hash:       c.newHash(),
+			ParentHash: parentHash,
+			Number:     new(big.Int).SetUint64(number),
+			Time:       number,
+		}
+		c.headers = append(c.headers, header)
+		c.byHash[header.Hash] = header
+
+		logs := make([]types.Log, 0, c.numLogs)
+		for i := range c.numLogs {
+			logs = append(logs, types.Log{
+				Address:     "0x0000000000000000000000000000000000000001",
+				Topics:      []string{},
+				BlockNumber: number,
+				TxHash:      c.newHash(),
+				BlockHash:   header.Hash,
+				Index:       uint(i),
+			})
+		}
+		c.logs[header.Hash] = logs
+		res = append(res, header)
+	}
+
+	return res
+}
+
+// Extend appends n new blocks to the chain and notifies subscribers.
+func (c *ChainClientMock) Extend(n int) {
+	c.mu.Lock()
+	headers := c.extend(n)
+	chs := c.subHeadCh
+	c.mu.Unlock()
+
+	for _, header := range headers {
+		for _, ch := range chs {
+			ch <- header
+		}
+	}
+}
+
+// Reorg replaces the last depth blocks of the chain with new ones.
+func (c *ChainClientMock) Reorg(depth int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.headers = c.headers[:len(c.headers)-depth]
+	c.extend(depth)
+}
+
+// Header returns canonical header by block number.
+func (c *ChainClientMock) Header(number uint64) *types.Header {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.headers[number-c.base]
+}
+
+// SetBatchError makes batch requests fail with given error.
+func (c *ChainClientMock) SetBatchError(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.batchErr = err
+}
+
+// Calls returns number of calls for a method.
+func (c *ChainClientMock) Calls(method string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.calls[method]
+}
+
+func (c *ChainClientMock) headerByNumber(number *big.Int) (*types.Header, error) {
+	if number == nil {
+		return c.headers[len(c.headers)-1], nil
+	}
+
+	n := number.Uint64()
+	if n < c.base || n-c.base >= uint64(len(c.headers)) {
+		return nil, ethereum.NotFound
+	}
+
+	return c.headers[n-c.base], nil
+}
+
+func (c *ChainClientMock) filterLogs(q evmclient.FilterQuery) []types.Log {
+	if q.BlockHash != nil {
+		return c.logs[*q.BlockHash]
+	}
+
+	var res []types.Log
+	for _, header := range c.headers {
+		if q.FromBlock != nil && header.Number.Cmp(q.FromBlock) < 0 {
+			continue
+		}
+		if q.ToBlock != nil && header.Number.Cmp(q.ToBlock) > 0 {
+			continue
+		}
+		res = append(res, c.logs[header.Hash]...)
+	}
+
+	return res
+}
+
+func (c *ChainClientMock) BlockNumber(context.Context) (uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.calls["BlockNumber"]++
+
+	return c.headers[len(c.headers)-1].Number.Uint64(), nil
+}
+
+//nolint:ireturn
+func (c *ChainClientMock) SubscribeNewHead(_ context.Context, ch chan<- *types.Header) (evmclient.Subscription, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.calls["SubscribeNewHead"]++
+	c.subHeadCh = append(c.subHeadCh, ch)
+
+	return &ClientSubscription{errCh: make(chan error)}, nil
+}
+
+func (c *ChainClientMock) FilterLogs(_ context.Context, q evmclient.FilterQuery) ([]types.Log, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.calls["FilterLogs"]++
+
+	return c.filterLogs(q), nil
+}
+
+func (c *ChainClientMock) HeaderByHash(_ context.Context, hash string) (*types.Header, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.calls["HeaderByHash"]++
+	header, ok := c.byHash[hash]
+	if !ok {
+		return nil, ethereum.NotFound
+	}
+
+	return header, nil
+}
+
+func (c *ChainClientMock) HeaderByNumber(_ context.Context, number *big.Int) (*types.Header, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.calls["HeaderByNumber"]++
+
+	return c.headerByNumber(number)
+}
+
+func (c *ChainClientMock) BatchHeadersByNumber(_ context.Context, numbers []*big.Int) ([]*types.Header, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.calls["BatchHeadersByNumber"]++
+	if c.batchErr != nil {
+		return nil, c.batchErr
+	}
+
+	headers := make([]*types.Header, 0, len(numbers))
+	for _, number := range numbers {
+		header, err := c.headerByNumber(number)
+		if err != nil {
+			return nil, err
+		}
+		headers = append(headers, header)
+	}
+
+	return headers, nil
+}
+
+func (c *ChainClientMock) BatchFilterLogs(_ context.Context, queries []evmclient.FilterQuery) ([][]types.Log, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.calls["BatchFilterLogs"]++
+	if c.batchErr != nil {
+		return nil, c.batchErr
+	}
+
+	res := make([][]types.Log, 0, len(queries))
+	for _, q := range queries {
+		res = append(res, c.filterLogs(q))
+	}
+
+	return res, nil
+}
@@ -1,11 +1,90 @@
 package listener
 
+import (
+	"time"
+
+	"github.com/KyberNetwork/evmlistener/pkg/retry"
+)
+
+const (
+	defaultLogRetryAttempts = 5
+	defaultShutdownTimeout  = 20 * time.Second
+)
+
 type Option func(opt *FilterOption)
 
 type FilterOption struct {
 	filterContracts []string
 	filterTopics    [][]string
 	withLogs        bool
+
+	logRetryAttempts int
+	noRetryOnEmpty   bool
+	retryPolicy      *retry.Policy
+	restartPolicy    RestartPolicy
+	readinessPolicy  ReadinessPolicy
+	shutdownDeadline time.Duration
+	logVerification  LogVerification
+
+	batchSize     int
+	logRange      uint64
+	catchUpBlocks int
+
+	startBlock     *uint64
+	startBlockHash string
+	startTime      uint64
+
+	lease     Lease
+	chainName string
+}
+
+func newFilterOption(opts ...Option) *FilterOption {
+	var o FilterOption
+	for _, v := range opts {
+		v(&o)
+	}
+
+	return &o
+}
+
+func (o *FilterOption) logAttempts() int {
+	if o.logRetryAttempts <= 0 {
+		return defaultLogRetryAttempts
+	}
+
+	return o.logRetryAttempts
+}
+
+func (o *FilterOption) retry() retry.Policy {
+	if o.retryPolicy == nil {
+		return retry.DefaultPolicy()
+	}
+
+	return *o.retryPolicy
+}
+
+func (o *FilterOption) catchUpWindow() int {
+	if o.catchUpBlocks <= 0 {
+		return defaultCatchUpWindow
+	}
+
+	return o.catchUpBlocks
+}
+
+func (o *FilterOption) shutdownTimeout() time.Duration {
+	if o.shutdownDeadline <= 0 {
+		return defaultShutdownTimeout
+	}
+
+	return o.shutdownDeadline
+}
+
+func (o *FilterOption) verification() LogVerification {
+	if o.logVerification == "" {
+		return LogVerificationBloom
+	}
+
+	return o.logVerification
 }
 
 func WithEventLogs(contracts []string, topics [][]string) Option {
@@ -15,3 +94,104 @@ func WithEventLogs(contracts []string, topics [][]string) Option {
 		opt.filterTopics = topics
 	}
 }
+
+// WithLogRetry sets maximum number of attempts for fetching logs of a block,
+// and whether an empty list of logs should be retried.
+func WithLogRetry(maxAttempts int, retryOnEmpty bool) Option {
+	return func(opt *FilterOption) {
+		opt.logRetryAttempts = maxAttempts
+		opt.noRetryOnEmpty = !retryOnEmpty
+	}
+}
+
+// WithBatchSize enables JSON-RPC batch requests with given maximum number of requests per batch
+// for fetching a range of blocks. Batching is disabled if size is not positive.
+func WithBatchSize(size int) Option {
+	return func(opt *FilterOption) {
+		opt.batchSize = size
+	}
+}
+
+// WithLogRange enables fetching logs by block ranges of at most maxRange blocks when
+// catching up with the chain. The range is reduced automatically when the node rejects it.
+func WithLogRange(maxRange uint64) Option {
+	return func(opt *FilterOption) {
+		opt.logRange = maxRange
+	}
+}
+
+// WithCatchUpWindow sets maximum number of blocks in flight when catching up with the chain.
+func WithCatchUpWindow(blocks int) Option {
+	return func(opt *FilterOption) {
+		opt.catchUpBlocks = blocks
+	}
+}
+
+// WithRetryPolicy sets the policy for retrying failed requests to the node,
+// and re-subscribing for new heads.
+func WithRetryPolicy(policy retry.Policy) Option {
+	return func(opt *FilterOption) {
+		opt.retryPolicy = &policy
+	}
+}
+
+// WithRestartPolicy sets how often the subscription for new heads may be restarted after transient
+// failures before the listener stops. Restarts are not limited by default.
+func WithRestartPolicy(policy RestartPolicy) Option {
+	return func(opt *FilterOption) {
+		opt.restartPolicy = policy
+	}
+}
+
+// WithShutdownTimeout sets how long blocks already fetched are still handled after the listener is stopped.
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(opt *FilterOption) {
+		opt.shutdownDeadline = timeout
+	}
+}
+
+// WithLogVerification sets how logs of a block are verified for completeness before being published.
+// Logs are verified against the logs bloom of the block by default.
+func WithLogVerification(mode LogVerification) Option {
+	return func(opt *FilterOption) {
+		opt.logVerification = mode
+	}
+}
+
+// WithStartBlock makes a fresh deployment, without any saved block, start publishing from the block.
+func WithStartBlock(number uint64) Option {
+	return func(opt *FilterOption) {
+		opt.startBlock = &number
+	}
+}
+
+// WithStartBlockHash makes a fresh deployment, without any saved block, start publishing from
+// the block with given hash.
+func WithStartBlockHash(hash string) Option {
+	return func(opt *FilterOption) {
+		opt.startBlockHash = hash
+	}
+}
+
+// WithStartTime makes a fresh deployment, without any saved block, start publishing from
+// the first block whose timestamp is not before given unix time.
+func WithStartTime(ts uint64) Option {
+	return func(opt *FilterOption) {
+		opt.startTime = ts
+	}
+}
+
+// WithLeaderElection makes replicas of the listener elect a leader with the lease, only the leader publishes
+// messages while the others keep handling new blocks to take over quickly.
+func WithLeaderElection(lease Lease) Option {
+	return func(opt *FilterOption) {
+		opt.lease = lease
+	}
+}
+
+// WithChainName labels metrics of the listener, and node rpc requests it makes, with the chain name.
+func WithChainName(name string) Option {
+	return func(opt *FilterOption) {
+		opt.chainName = name
+	}
+}
@@ -0,0 +1,185 @@
+package listener
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/KyberNetwork/evmlistener/pkg/errors"
+	"github.com/KyberNetwork/evmlistener/pkg/retry"
+	"go.uber.org/zap"
+)
+
+// groupStableRun is the duration after which a listener which stops again is no longer
+// considered failing repeatedly, so that it is run again without backoff.
+const groupStableRun = time.Minute
+
+type groupMember struct {
+	name        string
+	newListener func() (*Listener, error)
+
+	mu       sync.Mutex
+	listener *Listener
+}
+
+func (m *groupMember) getListener() *Listener {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.listener
+}
+
+func (m *groupMember) setListener(listener *Listener) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.listener = listener
+}
+
+// Group runs listeners of several chains in a process. A listener which can not be set up or stops
+// with a failure is run again with backoff, without stopping the others.
+type Group struct {
+	l       *zap.SugaredLogger
+	policy  retry.Policy
+	members []*groupMember
+	running atomic.Bool
+}
+
+// NewGroup returns a new Group object, failed listeners are run again with the backoff of policy.
+func NewGroup(l *zap.SugaredLogger, policy retry.Policy) *Group {
+	return &Group{
+		l:      l,
+		policy: policy,
+	}
+}
+
+// Add adds a chain to the group, its listener is set up by newListener when the group runs.
+func (g *Group) Add(name string, newListener func() (*Listener, error)) {
+	g.members = append(g.members, &groupMember{name: name, newListener: newListener})
+}
+
+// Run runs listeners of all chains until ctx is done, it returns errors of listeners which did not
+// stop gracefully.
+func (g *Group) Run(ctx context.Context) error {
+	g.running.Store(true)
+	defer g.running.Store(false)
+
+	errs := make([]error, len(g.members))
+	var wg sync.WaitGroup
+	for i, m := range g.members {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = g.run(ctx, m)
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+func (g *Group) run(ctx context.Context, m *groupMember) error {
+	l := g.l.With("chainName", m.name)
+
+	var listener *Listener
+	for attempt := 0; ; attempt++ {
+		start := time.Now()
+		var err error
+		if listener == nil {
+			listener, err = m.newListener()
+			if err != nil {
+				l.Errorw("Fail to setup listener", "error", err)
+			}
+			m.setListener(listener)
+		}
+
+		if listener != nil {
+			err = listener.Run(ctx)
+			if ctx.Err() != nil {
+				return err
+			}
+		}
+
+		if time.Since(start) > groupStableRun {
+			attempt = 0
+		}
+
+		delay := g.policy.Backoff(attempt)
+		l.Errorw("Listener stopped, run it again", "delay", delay, "error", err)
+		if retry.Sleep(ctx, delay) != nil {
+			return nil
+		}
+	}
+}
+
+// Close closes listeners of all chains, once Run returned.
+func (g *Group) Close() error {
+	var errs []error
+	for _, m := range g.members {
+		if listener := m.getListener(); listener != nil {
+			if err := listener.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", m.name, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Healthy returns ErrNotRunning unless Run is in progress. Listeners which failed are run again by the
+// group, so that they are reported by Ready only.
+func (g *Group) Healthy() error {
+	if !g.running.Load() {
+		return ErrNotRunning
+	}
+
+	return nil
+}
+
+// Ready returns nil if listeners of all chains are ready, see Listener.Ready.
+func (g *Group) Ready() error {
+	if err := g.Healthy(); err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, m := range g.members {
+		listener := m.getListener()
+		if listener == nil {
+			errs = append(errs, fmt.Errorf("%s: %w: listener is not set up", m.name, ErrNotRunning))
+
+			continue
+		}
+
+		if err := listener.Ready(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", m.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Chains returns names of chains of the group.
+func (g *Group) Chains() []string {
+	names := make([]string, 0, len(g.members))
+	for _, m := range g.members {
+		names = append(names, m.name)
+	}
+
+	return names
+}
+
+// Listener returns the listener of the chain, false if the chain is unknown or its listener is not set up.
+func (g *Group) Listener(chain string) (*Listener, bool) {
+	for _, m := range g.members {
+		if m.name == chain {
+			listener := m.getListener()
+
+			return listener, listener != nil
+		}
+	}
+
+	return nil, false
+}
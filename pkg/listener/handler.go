@@ -2,6 +2,8 @@ package listener
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"math/big"
 
 	"github.com/KyberNetwork/evmlistener/pkg/block"
@@ -21,6 +23,13 @@ type Handler struct {
 	publisher   pubsub.Publisher
 	l           *zap.SugaredLogger
 	option      *FilterOption
+	batcher     *blockBatcher
+
+	// leaderToken is the fencing token of the lease messages are published with, zero while standby.
+	leaderToken int64
+	// rewindPending is set while a stored rewind is not finished, it is finished before handling
+	// any new block so that blocks after the rewound one are never published without their revert.
+	rewindPending bool
 }
 
 // NewHandler ...
@@ -28,10 +37,7 @@ func NewHandler(
 	l *zap.SugaredLogger, topic string, evmClient evmclient.IClient,
 	blockKeeper block.Keeper, publisher pubsub.Publisher, options ...Option,
 ) *Handler {
-	var opts FilterOption
-	for _, v := range options {
-		v(&opts)
-	}
+	option := newFilterOption(options...)
 
 	return &Handler{
 		topic:       topic,
@@ -39,7 +45,8 @@ func NewHandler(
 		blockKeeper: blockKeeper,
 		publisher:   publisher,
 		l:           l,
-		option:      &opts,
+		option:      option,
+		batcher:     newBlockBatcher(l, option.batchSize),
 	}
 }
 
@@ -47,21 +54,68 @@ func (h *Handler) getBlockNumber(ctx context.Context) (uint64, error) {
 	hash := h.blockKeeper.GetHead()
 	if hash != "" {
 		h.l.Infow("Get header from block hash", "hash", hash)
-		header, err := getHeaderByHash(ctx, h.evmClient, hash)
+		header, err := getHeaderByHash(ctx, h.evmClient, hash, h.option)
 		if err != nil {
 			h.l.Errorw("Fail to get header by hash", "hash", hash, "error", err)
 
 			return 0, err
 		}
 
+		if h.hasStartBlock() {
+			h.l.Infow("Ignore start block, resume from saved head", "number", header.Number)
+		}
+
 		return header.Number.Uint64(), nil
 	}
 
+	if h.hasStartBlock() {
+		start, err := h.getStartBlock(ctx)
+		if err != nil {
+			h.l.Errorw("Fail to get start block", "error", err)
+
+			return 0, err
+		}
+
+		// Blocks before the start block are only stored, so that blocks from the start block
+		// are published when catching up with the chain.
+		h.l.Infow("Start from block", "number", start)
+		if start == 0 {
+			h.l.Warnw("Genesis block is not published")
+
+			return 0, nil
+		}
+
+		return start - 1, nil
+	}
+
 	h.l.Infow("Get latest block number from node")
 
 	return h.evmClient.BlockNumber(ctx)
 }
 
+func (h *Handler) hasStartBlock() bool {
+	return h.option.startBlock != nil || h.option.startBlockHash != "" || h.option.startTime > 0
+}
+
+// getStartBlock returns number of the block to start publishing from.
+func (h *Handler) getStartBlock(ctx context.Context) (uint64, error) {
+	switch {
+	case h.option.startBlock != nil:
+		return *h.option.startBlock, nil
+	case h.option.startBlockHash != "":
+		header, err := getHeaderByHash(ctx, h.evmClient, h.option.startBlockHash, h.option)
+		if err != nil {
+			return 0, err
+		}
+
+		return header.Number.Uint64(), nil
+	default:
+		h.l.Infow("Find start block by timestamp", "timestamp", h.option.startTime)
+
+		return FindBlockByTimestamp(ctx, h.evmClient, h.option.startTime)
+	}
+}
+
 // Init ...
 func (h *Handler) Init(ctx context.Context) error {
 	h.l.Info("Init block keeper")
@@ -72,6 +126,13 @@ func (h *Handler) Init(ctx context.Context) error {
 		return err
 	}
 
+	err = h.resumeRewind(ctx)
+	if err != nil {
+		h.l.Errorw("Fail to resume rewind", "error", err)
+
+		return err
+	}
+
 	if h.blockKeeper.Len() > 0 {
 		return nil
 	}
@@ -84,11 +145,16 @@ func (h *Handler) Init(ctx context.Context) error {
 		return err
 	}
 
-	fromBlock := toBlock - uint64(h.blockKeeper.Cap()) + 1
+	var fromBlock uint64
+	if n := uint64(h.blockKeeper.Cap()); toBlock+1 > n {
+		fromBlock = toBlock + 1 - n
+	}
 
 	h.l.Infow("Get blocks from node", "from", fromBlock, "to", toBlock)
-	blocks, err := GetBlocks(ctx, h.evmClient, fromBlock, toBlock, h.option.withLogs,
-		h.option.filterContracts, h.option.filterTopics)
+	blocks, ok := h.batcher.getBlocks(ctx, h.evmClient, fromBlock, toBlock, h.option)
+	if !ok {
+		blocks, err = fetchBlocks(ctx, h.evmClient, fromBlock, toBlock, h.option)
+	}
 	if err != nil {
 		h.l.Errorw("Fail to get blocks", "from", fromBlock, "to", toBlock, "error", err)
 
@@ -121,8 +187,7 @@ func (h *Handler) getBlock(ctx context.Context, hash string) (types.Block, error
 		return types.Block{}, err
 	}
 
-	b, err = getBlockByHash(ctx, h.evmClient, hash, h.option.withLogs, h.option.filterContracts,
-		h.option.filterTopics)
+	b, err = getBlockByHash(ctx, h.evmClient, hash, h.option)
 	if err != nil {
 		h.l.Errorw("Fail to get block from ndoe", "hash", hash, "error", err)
 
@@ -152,6 +217,9 @@ func (h *Handler) findReorgBlocks(
 		if storedNumber >= newNumber {
 			reorgBlocks = append(reorgBlocks, storedBlock)
 			storedBlock, err = h.blockKeeper.Get(storedBlock.ParentHash)
+			if errors.Is(err, errors.ErrNotFound) {
+				return nil, nil, fmt.Errorf("%w: %w", errReorgTooDeep, err)
+			}
 			if err != nil {
 				h.l.Errorw("Fail to get stored block",
 					"number", storedNumber, "error", err)
@@ -220,6 +288,9 @@ func (h *Handler) handleNewBlock(ctx context.Context, b types.Block) error {
 	if isReorg {
 		log.Infow("Handle re-organization block")
 		revertedBlocks, newBlocks, err = h.handleReorgBlock(ctx, b)
+		if errors.Is(err, errReorgTooDeep) {
+			return h.resync(ctx, b)
+		}
 		if err != nil {
 			log.Errorw("Fail to handle re-organization block", "error", err)
 
@@ -229,15 +300,11 @@ func (h *Handler) handleNewBlock(ctx context.Context, b types.Block) error {
 		newBlocks = []types.Block{b}
 	}
 
-	log.Infow("Publish message to queue",
-		"topic", h.topic,
-		"numRevertedBlocks", len(revertedBlocks),
-		"numNewBlocks", len(newBlocks))
-	msg := types.Message{
+	msg := &types.Message{
 		RevertedBlocks: revertedBlocks,
 		NewBlocks:      newBlocks,
 	}
-	err = h.publisher.Publish(ctx, h.topic, msg)
+	err = h.publish(ctx, b, msg)
 	if err != nil {
 		log.Errorw("Fail to publish message", "error", err)
 
@@ -277,6 +344,16 @@ func (h *Handler) Handle(ctx context.Context, b types.Block) error {
 		return nil
 	}
 
+	if h.rewindPending {
+		log.Warnw("Finish rewind before handling new block")
+		err = h.resumeRewind(ctx)
+		if err != nil {
+			log.Errorw("Fail to finish rewind", "error", err)
+
+			return err
+		}
+	}
+
 	blockHead, err := h.blockKeeper.Head()
 	if err == nil {
 		blockDiff := new(big.Int).Sub(blockHead.Number, b.Number).Int64()
@@ -293,3 +370,12 @@ func (h *Handler) Handle(ctx context.Context, b types.Block) error {
 
 	return h.handleNewBlock(ctx, b)
 }
+
+// Close closes the publisher if it holds resources.
+func (h *Handler) Close() error {
+	if closer, ok := h.publisher.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package listener
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/KyberNetwork/evmlistener/pkg/errors"
+)
+
+var (
+	// ErrNotRunning means the listener is not running, or one of its loops stopped.
+	ErrNotRunning = errors.New("not running")
+	// ErrNotReady means the listener is running but not publishing new blocks in time.
+	ErrNotReady = errors.New("not ready")
+)
+
+// ReadinessPolicy decides whether a running listener is ready, i.e. publishes new blocks in time.
+type ReadinessPolicy struct {
+	// MaxLag is the maximum number of blocks the last handled block may be behind the head of the chain.
+	MaxLag uint64
+	// MaxIdle is the maximum duration since the last handled block. Zero means no limit.
+	MaxIdle time.Duration
+}
+
+// WithReadinessPolicy sets when the listener is reported as ready, by default it is as soon as it has
+// caught up with the chain.
+func WithReadinessPolicy(policy ReadinessPolicy) Option {
+	return func(opt *FilterOption) {
+		opt.readinessPolicy = policy
+	}
+}
+
+func (l *Listener) setRunning(v bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.running = v
+}
+
+// Healthy returns ErrNotRunning unless Run is in progress and all its loops are running.
+func (l *Listener) Healthy() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.running {
+		return ErrNotRunning
+	}
+
+	return nil
+}
+
+// Ready returns nil if the listener is healthy, its block keeper is initialised, it is neither paused
+// nor catching up with the chain, and the last handled block is recent as allowed by the readiness policy.
+func (l *Listener) Ready() error {
+	if err := l.Healthy(); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.initialised {
+		return fmt.Errorf("%w: block keeper is not initialised", ErrNotReady)
+	}
+
+	if l.paused {
+		return fmt.Errorf("%w: %w", ErrNotReady, ErrPaused)
+	}
+
+	if l.resuming {
+		return fmt.Errorf("%w: catching up with the chain", ErrNotReady)
+	}
+
+	if l.lastHandledBlockNumber == nil {
+		return fmt.Errorf("%w: no block was handled yet", ErrNotReady)
+	}
+
+	head := l.lastCheckedBlockNumber
+	if l.lastReceivedBlock != nil && (head == nil || l.lastReceivedBlock.Number.Cmp(head) > 0) {
+		head = l.lastReceivedBlock.Number
+	}
+	if head != nil {
+		lag := new(big.Int).Sub(head, l.lastHandledBlockNumber)
+		if lag.Cmp(new(big.Int).SetUint64(l.option.readinessPolicy.MaxLag)) > 0 {
+			return fmt.Errorf("%w: last handled block %v is %v blocks behind head %v",
+				ErrNotReady, l.lastHandledBlockNumber, lag, head)
+		}
+	}
+
+	maxIdle := l.option.readinessPolicy.MaxIdle
+	if idle := time.Since(l.lastHandledTime); maxIdle > 0 && idle > maxIdle {
+		return fmt.Errorf("%w: last block was handled %s ago", ErrNotReady, idle.Round(time.Second))
+	}
+
+	return nil
+}
@@ -0,0 +1,187 @@
+package listener
+
+import (
+	"context"
+	"time"
+
+	"github.com/KyberNetwork/evmlistener/pkg/errors"
+	"github.com/KyberNetwork/evmlistener/pkg/types"
+)
+
+// Lease is a lease for leader election between replicas of the listener. All replicas handle new
+// blocks, but only the holder of the lease publishes messages.
+type Lease interface {
+	// TTL returns the duration of the lease.
+	TTL() time.Duration
+	// Acquire acquires the lease, or renews it if it is already held.
+	Acquire(ctx context.Context) (bool, error)
+	// Release releases the lease if it is held.
+	Release(ctx context.Context) error
+	// Token returns the fencing token of the lease, zero if the lease is not held.
+	Token() int64
+}
+
+// campaign acquires or renews the lease every third of its duration until ctx is done.
+func (l *Listener) campaign(ctx context.Context) {
+	lease := l.option.lease
+	interval := lease.TTL() / 3 //nolint:gomnd
+	leader := false
+	for {
+		ok, err := lease.Acquire(ctx)
+		if err != nil && ctx.Err() == nil {
+			l.l.Warnw("Fail to acquire leader lease", "error", err)
+		}
+
+		if ok != leader {
+			if ok {
+				l.l.Infow("Become leader", "token", lease.Token())
+			} else {
+				l.l.Warnw("Lose leadership")
+			}
+			leader = ok
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// leaderMessage returns the message to publish for new block b, nil if this replica is not the leader
+// or b was already published. A new leader publishes blocks from the head saved by the former leader
+// up to b instead, so that blocks handled while it was standby are not missed.
+func (h *Handler) leaderMessage(ctx context.Context, b types.Block, msg *types.Message) (*types.Message, error) {
+	token := h.option.lease.Token()
+	if token == 0 {
+		if h.leaderToken != 0 {
+			h.l.Warnw("Stop publishing, lease is not held")
+			h.leaderToken = 0
+		}
+
+		return nil, nil //nolint:nilnil
+	}
+
+	if token == h.leaderToken {
+		return msg, nil
+	}
+
+	saved, err := h.blockKeeper.SavedHead()
+	if err != nil {
+		h.l.Errorw("Fail to get saved block head", "error", err)
+
+		return nil, err
+	}
+
+	if saved == "" {
+		h.l.Infow("Start publishing as leader", "token", token)
+		h.leaderToken = token
+
+		return msg, nil
+	}
+
+	// The block was already published by the former leader.
+	if saved == b.Hash {
+		h.l.Infow("Start publishing as leader after saved head", "token", token, "savedHead", saved)
+		h.leaderToken = token
+
+		return nil, nil //nolint:nilnil
+	}
+
+	savedBlock, err := h.blockKeeper.Get(saved)
+	if errors.Is(err, errors.ErrNotFound) {
+		h.l.Warnw("Start publishing as leader, blocks after unknown saved head may be missed",
+			"token", token, "savedHead", saved)
+		h.leaderToken = token
+
+		return msg, nil
+	}
+	if err != nil {
+		h.l.Errorw("Fail to get saved block head", "hash", saved, "error", err)
+
+		return nil, err
+	}
+
+	// Blocks up to the saved head were already published by the former leader.
+	if savedBlock.Number.Cmp(b.Number) >= 0 {
+		h.l.Debugw("Wait for blocks after saved head", "savedHead", saved, "savedNumber", savedBlock.Number)
+
+		return nil, nil //nolint:nilnil
+	}
+
+	revertedBlocks, newBlocks, err := h.findReorgBlocks(ctx, savedBlock, b)
+	if errors.Is(err, errReorgTooDeep) {
+		h.l.Warnw("Start publishing as leader, saved head is too old", "token", token, "savedHead", saved)
+		h.leaderToken = token
+
+		return msg, nil
+	}
+	if err != nil {
+		h.l.Errorw("Fail to get blocks after saved head", "hash", saved, "error", err)
+
+		return nil, err
+	}
+
+	h.l.Infow("Take over publishing from former leader",
+		"token", token, "savedHead", saved,
+		"numRevertedBlocks", len(revertedBlocks), "numNewBlocks", len(newBlocks))
+	h.leaderToken = token
+
+	return &types.Message{RevertedBlocks: revertedBlocks, NewBlocks: newBlocks}, nil
+}
+
+// publish publishes the message if this replica is the leader, see leaderMessage.
+func (h *Handler) publish(ctx context.Context, b types.Block, msg *types.Message) error {
+	if h.option.lease != nil {
+		var err error
+		msg, err = h.leaderMessage(ctx, b, msg)
+		if err != nil {
+			return err
+		}
+
+		if msg == nil {
+			h.l.Debugw("Skip publishing message", "blockHash", b.Hash)
+
+			return nil
+		}
+	}
+
+	h.l.Infow("Publish message to queue",
+		"topic", h.topic,
+		"blockNumber", b.Number,
+		"numRevertedBlocks", len(msg.RevertedBlocks),
+		"numNewBlocks", len(msg.NewBlocks))
+	err := h.publisher.Publish(ctx, h.topic, *msg)
+	if errors.Is(err, errors.ErrFenced) {
+		h.l.Warnw("Message is not published, lease was taken over", "error", err)
+		h.leaderToken = 0
+
+		return nil
+	}
+
+	return err
+}
+
+// publishRevert publishes a message reverting blocks of a rewind if this replica is the leader. Unlike
+// new blocks, the revert does not depend on blocks published by a former leader, so it is published
+// as is by a new leader, which takes over publishing from the saved head with the next new block.
+func (h *Handler) publishRevert(ctx context.Context, reverted []types.Block) error {
+	if h.option.lease != nil && h.option.lease.Token() == 0 {
+		h.l.Debugw("Skip publishing revert, lease is not held", "numRevertedBlocks", len(reverted))
+
+		return nil
+	}
+
+	h.l.Infow("Publish message to queue",
+		"topic", h.topic,
+		"blockNumber", reverted[0].Number,
+		"numRevertedBlocks", len(reverted))
+	err := h.publisher.Publish(ctx, h.topic, types.Message{RevertedBlocks: reverted})
+	if errors.Is(err, errors.ErrFenced) {
+		h.l.Warnw("Message is not published, lease was taken over", "error", err)
+		h.leaderToken = 0
+	}
+
+	return err
+}
@@ -0,0 +1,214 @@
+package listener
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KyberNetwork/evmlistener/pkg/block"
+	"github.com/KyberNetwork/evmlistener/pkg/errors"
+	"github.com/KyberNetwork/evmlistener/pkg/types"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+)
+
+type leaseMock struct {
+	mu    sync.Mutex
+	token int64
+}
+
+func (l *leaseMock) TTL() time.Duration {
+	return time.Second
+}
+
+func (l *leaseMock) Acquire(context.Context) (bool, error) {
+	return l.Token() != 0, nil
+}
+
+func (l *leaseMock) Release(context.Context) error {
+	l.setToken(0)
+
+	return nil
+}
+
+func (l *leaseMock) Token() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.token
+}
+
+func (l *leaseMock) setToken(token int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.token = token
+}
+
+// savedHeadKeeperMock is a block keeper with blocks and head saved by the former leader.
+type savedHeadKeeperMock struct {
+	*block.BaseBlockKeeper
+
+	saved     map[string]types.Block
+	savedHead string
+}
+
+func (k *savedHeadKeeperMock) save(b types.Block) {
+	k.saved[b.Hash] = b
+	k.savedHead = b.Hash
+}
+
+func (k *savedHeadKeeperMock) Get(hash string) (types.Block, error) {
+	if b, ok := k.saved[hash]; ok {
+		return b, nil
+	}
+
+	return k.BaseBlockKeeper.Get(hash)
+}
+
+func (k *savedHeadKeeperMock) SavedHead() (string, error) {
+	return k.savedHead, nil
+}
+
+// fencedPublisherMock fails with errors.ErrFenced while fenced is set.
+type fencedPublisherMock struct {
+	*PublisherMock
+
+	fenced bool
+}
+
+func (p *fencedPublisherMock) Publish(ctx context.Context, topic string, msg interface{}) error {
+	if p.fenced {
+		return errors.ErrFenced
+	}
+
+	return p.PublisherMock.Publish(ctx, topic, msg)
+}
+
+type LeaderTestSuite struct {
+	suite.Suite
+
+	client    *ChainClientMock
+	keeper    *savedHeadKeeperMock
+	publisher *fencedPublisherMock
+	lease     *leaseMock
+	handler   *Handler
+}
+
+func (ts *LeaderTestSuite) SetupTest() {
+	ts.client = NewChainClientMock(1000, 100, 1)
+	ts.keeper = &savedHeadKeeperMock{
+		BaseBlockKeeper: block.NewBaseBlockKeeper(16),
+		saved:           make(map[string]types.Block),
+	}
+	ts.publisher = &fencedPublisherMock{PublisherMock: NewPublisherMock(10)}
+	ts.lease = &leaseMock{}
+	ts.handler = NewHandler(zap.S(), "test-topic", ts.client, ts.keeper, ts.publisher,
+		WithEventLogs(nil, nil), WithLeaderElection(ts.lease))
+	ts.Require().NoError(ts.handler.Init(context.Background()))
+}
+
+// mine extends the chain by n blocks and handles them.
+func (ts *LeaderTestSuite) mine(n int) {
+	for range n {
+		ts.client.Extend(1)
+		head, err := ts.client.BlockNumber(context.Background())
+		ts.Require().NoError(err)
+		b, err := getBlockByHash(context.Background(), ts.client, ts.client.Header(head).Hash, ts.handler.option)
+		ts.Require().NoError(err)
+		ts.Require().NoError(ts.handler.Handle(context.Background(), b))
+	}
+}
+
+func (ts *LeaderTestSuite) published() []types.Message {
+	var msgs []types.Message
+	for len(ts.publisher.ch) > 0 {
+		msg, ok := (<-ts.publisher.ch).(types.Message)
+		ts.Require().True(ok)
+		msgs = append(msgs, msg)
+	}
+
+	return msgs
+}
+
+func (ts *LeaderTestSuite) TestStandby() {
+	ts.mine(3)
+	ts.Assert().Empty(ts.published())
+
+	// Blocks are still kept to take over quickly.
+	head, err := ts.keeper.Head()
+	ts.Require().NoError(err)
+	ts.Assert().Equal(ts.client.Header(1102).Hash, head.Hash)
+}
+
+func (ts *LeaderTestSuite) TestTakeOver() {
+	ts.mine(3)
+
+	// The former leader published blocks up to 1100, blocks after it are published on take over.
+	ts.keeper.savedHead = ts.client.Header(1100).Hash
+	ts.lease.setToken(1)
+	ts.mine(1)
+	msgs := ts.published()
+	ts.Require().Len(msgs, 1)
+	ts.Assert().Empty(msgs[0].RevertedBlocks)
+	ts.Assert().Equal(ts.hashes(1101, 1103), blockHashes(msgs[0].NewBlocks))
+
+	ts.mine(1)
+	msgs = ts.published()
+	ts.Require().Len(msgs, 1)
+	ts.Assert().Equal(ts.hashes(1104, 1104), blockHashes(msgs[0].NewBlocks))
+}
+
+func (ts *LeaderTestSuite) TestTakeOverBehind() {
+	// The former leader published blocks which are not handled yet.
+	ts.client.Extend(2)
+	ts.keeper.save(ts.blockAt(1100))
+	ts.keeper.save(ts.blockAt(1101))
+	ts.lease.setToken(1)
+	ts.Require().NoError(ts.handler.Handle(context.Background(), ts.blockAt(1100)))
+	ts.Require().NoError(ts.handler.Handle(context.Background(), ts.blockAt(1101)))
+	ts.Assert().Empty(ts.published())
+
+	ts.mine(1)
+	msgs := ts.published()
+	ts.Require().Len(msgs, 1)
+	ts.Assert().Equal(ts.hashes(1102, 1102), blockHashes(msgs[0].NewBlocks))
+}
+
+func (ts *LeaderTestSuite) TestFenced() {
+	ts.lease.setToken(1)
+	ts.mine(1)
+	ts.Require().Len(ts.published(), 1)
+
+	// A former leader which was paused past the expiry of its lease does not fail.
+	ts.publisher.fenced = true
+	ts.mine(1)
+	ts.Assert().Empty(ts.published())
+	ts.Assert().Zero(ts.handler.leaderToken)
+
+	ts.lease.setToken(0)
+	ts.publisher.fenced = false
+	ts.mine(1)
+	ts.Assert().Empty(ts.published())
+}
+
+func (ts *LeaderTestSuite) hashes(fromBlock, toBlock uint64) []string {
+	var hashes []string
+	for number := fromBlock; number <= toBlock; number++ {
+		hashes = append(hashes, ts.client.Header(number).Hash)
+	}
+
+	return hashes
+}
+
+func (ts *LeaderTestSuite) blockAt(number uint64) types.Block {
+	b, err := getBlockByHash(context.Background(), ts.client, ts.client.Header(number).Hash, ts.handler.option)
+	ts.Require().NoError(err)
+
+	return b
+}
+
+func TestLeaderTestSuite(t *testing.T) {
+	suite.Run(t, new(LeaderTestSuite))
+}
@@ -2,33 +2,45 @@ package listener
 
 import (
 	"context"
+	"fmt"
 	"math/big"
 	"sync"
-	"syscall"
 	"time"
 
 	"github.com/KyberNetwork/evmlistener/pkg/errors"
 	"github.com/KyberNetwork/evmlistener/pkg/evmclient"
+	"github.com/KyberNetwork/evmlistener/pkg/labels"
+	"github.com/KyberNetwork/evmlistener/pkg/retry"
 	"github.com/KyberNetwork/evmlistener/pkg/types"
 	pkgmetric "github.com/KyberNetwork/kyber-trace-go/pkg/metric"
-	"github.com/ethereum/go-ethereum"
-	"github.com/gorilla/websocket"
+	pkgtracer "github.com/KyberNetwork/kyber-trace-go/pkg/tracer"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 )
 
 const (
 	bufLen                     = 100
+	defaultBatchSize           = 32
 	maxQueueLen                = 256
 	defaultSanityCheckInterval = time.Minute
 
 	metricNameLastReceivedBlockNumber = "evmlistener_last_received_block_number"
 	metricNameLastCheckedBlockNumber  = "evmlistener_last_checked_block_number"
 	metricNameLastHandledBlockNumber  = "evmlistener_last_handled_block_number"
+	metricNameCatchUpRemainingBlocks  = "evmlistener_catch_up_remaining_blocks"
+	metricNameCatchUpETA              = "evmlistener_catch_up_eta_seconds"
+	metricNameCatchUpConcurrency      = "evmlistener_catch_up_concurrency"
+	metricNameLeader                  = "evmlistener_leader"
 )
 
-var errConnectionCorrupted = errors.New("connection is corrupted")
+var (
+	errConnectionCorrupted = retry.WithClass(errors.New("connection is corrupted"), retry.ClassConnection)
+	// errPausedSubscription means the subscription stopped as blocks can not be held any more while paused.
+	errPausedSubscription = errors.New("subscription stopped while paused")
+)
 
 // Listener represents a listener service for on-chain events.
 type Listener struct {
@@ -45,12 +57,25 @@ type Listener struct {
 	lastReceivedBlock      *types.Block
 	lastHandledBlockNumber *big.Int
 	lastCheckedBlockNumber *big.Int
+	lastHandledTime        time.Time
+	catchUpProgress        *catchUpProgress
 	resuming               bool
+	running                bool
+	initialised            bool
+	paused                 bool
+	sanityErrCh            chan error
+	pauseCh                chan struct{}
+	resumeCh               chan struct{}
+	controlCh              chan control
+	blockCh                chan types.Block
 
 	queue       *Queue
 	maxQueueLen int
 
-	option FilterOption
+	option  *FilterOption
+	batcher *blockBatcher
+
+	metricsOnce sync.Once
 }
 
 // New ...
@@ -59,15 +84,12 @@ func New(
 	httpEVMClient evmclient.IClient, handler *Handler,
 	sanityEVMClient evmclient.IClient, sanityCheckInterval time.Duration, opts ...Option,
 ) *Listener {
-	var o FilterOption
-	for _, v := range opts {
-		v(&o)
-	}
-
 	if sanityCheckInterval == 0 {
 		sanityCheckInterval = defaultSanityCheckInterval
 	}
 
+	option := newFilterOption(opts...)
+
 	return &Listener{
 		l: l,
 
@@ -77,16 +99,30 @@ func New(
 
 		sanityEVMClient:     sanityEVMClient,
 		sanityCheckInterval: sanityCheckInterval,
+		sanityErrCh:         make(chan error, 1),
+		pauseCh:             make(chan struct{}, 1),
+		controlCh:           make(chan control),
 
 		queue:       NewQueue(maxQueueLen),
 		maxQueueLen: maxQueueLen,
-		option:      o,
+		option:      option,
+		batcher:     newBlockBatcher(l, option.batchSize),
 	}
 }
 
-func (l *Listener) publishBlock(ch chan<- types.Block, seq uint64, block *types.Block) {
+// publishBlock sends blocks to ch in order of their sequence numbers, it gives up when ctx is done.
+func (l *Listener) publishBlock(ctx context.Context, ch chan<- types.Block, seq uint64, block *types.Block) {
+	send := func(b *types.Block) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case ch <- *b:
+			return true
+		}
+	}
+
 	if l.queue == nil {
-		ch <- *block
+		send(block)
 
 		return
 	}
@@ -99,8 +135,8 @@ func (l *Listener) publishBlock(ch chan<- types.Block, seq uint64, block *types.
 	if int(seq-expectedSeq) >= l.maxQueueLen {
 		for i := 0; i <= int(seq-expectedSeq)-l.maxQueueLen; i++ {
 			b, _ := l.queue.Dequeue()
-			if b != nil {
-				ch <- *b
+			if b != nil && !send(b) {
+				return
 			}
 		}
 	}
@@ -108,23 +144,42 @@ func (l *Listener) publishBlock(ch chan<- types.Block, seq uint64, block *types.
 	l.queue.Insert(seq, block)
 	for !l.queue.Empty() {
 		b, _ := l.queue.Peek()
-		if b == nil {
+		if b == nil || !send(b) {
 			return
 		}
 
-		ch <- *b
 		l.queue.Dequeue()
 	}
 }
 
+// flushQueue sends blocks left in the queue in order, skipping blocks which were never received.
+func (l *Listener) flushQueue(ctx context.Context, ch chan<- types.Block) {
+	for !l.queue.Empty() {
+		b, _ := l.queue.Dequeue()
+		if b == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case ch <- *b:
+		}
+	}
+}
+
 func (l *Listener) handleNewHeader(ctx context.Context, header *types.Header) (types.Block, error) {
 	var err error
 	var logs []types.Log
 
+	ctx, span := pkgtracer.Tracer().Start(ctx, "listener.handleNewHeader",
+		trace.WithAttributes(attribute.String("hash", header.Hash)))
+	defer span.End()
+
 	l.l.Debugw("Handle for new head", "hash", header.Hash)
 	opts := l.option
 	if opts.withLogs {
-		logs, err = getLogsByBlockHash(ctx, l.httpEVMClient, header.Hash, opts.filterContracts, opts.filterTopics)
+		logs, err = getLogsByBlockHash(ctx, l.httpEVMClient, header, opts)
 		if err != nil {
 			l.l.Errorw("Fail to get logs by block hash", "hash", header.Hash, "error", err)
 
@@ -137,14 +192,31 @@ func (l *Listener) handleNewHeader(ctx context.Context, header *types.Header) (t
 }
 
 func (l *Listener) getBlocks(ctx context.Context, fromBlock, toBlock uint64) ([]types.Block, error) {
+	if l.option.withLogs && l.option.logRange > 0 {
+		blocks, err := l.getBlocksByRange(ctx, fromBlock, toBlock)
+		if err == nil {
+			return blocks, nil
+		}
+
+		if ctx.Err() != nil {
+			return nil, err
+		}
+
+		l.l.Warnw("Fail to get blocks with range log queries, fall back to block hash queries",
+			"from", fromBlock, "to", toBlock, "error", err)
+	}
+
+	if blocks, ok := l.batcher.getBlocks(ctx, l.httpEVMClient, fromBlock, toBlock, l.option); ok {
+		return blocks, nil
+	}
+
 	g, ctx := errgroup.WithContext(ctx)
 
 	blocks := make([]types.Block, toBlock-fromBlock+1)
 	for i := range blocks {
 		blkNum := uint64(i) + fromBlock
 		g.Go(func() error {
-			block, err := getBlockByNumber(ctx, l.httpEVMClient, new(big.Int).SetUint64(blkNum),
-				l.option.withLogs, l.option.filterContracts, l.option.filterTopics)
+			block, err := getBlockByNumber(ctx, l.httpEVMClient, new(big.Int).SetUint64(blkNum), l.option)
 			if err != nil {
 				l.l.Errorw("Fail to get block by number", "number", blkNum, "error", err)
 
@@ -186,46 +258,35 @@ func (l *Listener) handleOldHeaders(ctx context.Context, blockCh chan<- types.Bl
 		return nil
 	}
 
-	const batchSize = 32
-
 	l.l.Infow("Synchronize for new headers", "fromBlock", fromBlock, "toBlock", blockNumber)
-	for i := fromBlock + 1; i < blockNumber; i += batchSize {
-		toBlock := i + batchSize - 1
-		if toBlock >= blockNumber {
-			toBlock = blockNumber - 1
-		}
-
-		l.l.Infow("Get blocks from node", "from", i, "to", toBlock)
-		blocks, err := l.getBlocks(ctx, i, toBlock)
-		if err != nil {
-			return err
-		}
-
-		if len(blocks) > 0 {
-			l.mu.Lock()
-			l.lastReceivedBlock = &blocks[len(blocks)-1]
-			l.mu.Unlock()
-		}
-
-		for i := range blocks {
-			blockCh <- blocks[i]
-		}
+	if err = l.catchUp(ctx, fromBlock+1, blockNumber-1, blockCh); err != nil {
+		return err
 	}
 
 	l.l.Infow("Finish synchronize blocks", "fromBlock", fromBlock, "toBlock", blockNumber)
+	// Otherwise the sanity check tells when the listener caught up with the chain.
+	if l.sanityEVMClient == nil {
+		l.setResuming(false)
+	}
 
 	return nil
 }
 
+// subscribeNewBlockHead subscribes for new heads and sends their blocks to blockCh, until ctx is done
+// or a failure happens. Heads are handled with workCtx, which outlives ctx on shutdown.
+// When ctx is done, heads being handled are waited for and their blocks are sent in order.
+// On failure, they are abandoned: their context is canceled and they are waited for, the blocks are
+// fetched again when catching up after the subscription restarts.
+//
 //nolint:cyclop
-func (l *Listener) subscribeNewBlockHead(ctx context.Context, blockCh chan<- types.Block) error {
+func (l *Listener) subscribeNewBlockHead(ctx, workCtx context.Context, blockCh chan<- types.Block) error {
 	l.l.Info("Start subscribing for new head of the chain")
 	headerCh := make(chan *types.Header, 1)
 	sub, err := l.wsEVMClient.SubscribeNewHead(ctx, headerCh)
 	if err != nil {
 		l.l.Errorw("Fail to subscribe new head", "error", err)
 
-		return err
+		return newError(StageSubscribe, err)
 	}
 
 	defer sub.Unsubscribe()
@@ -235,7 +296,7 @@ func (l *Listener) subscribeNewBlockHead(ctx context.Context, blockCh chan<- typ
 	if err != nil {
 		l.l.Errorw("Fail to handle old headers", "error", err)
 
-		return err
+		return newError(StageCatchUp, err)
 	}
 
 	l.l.Infow("Start handling for new headers")
@@ -246,25 +307,54 @@ func (l *Listener) subscribeNewBlockHead(ctx context.Context, blockCh chan<- typ
 	seq := uint64(1)
 	l.queue.Clear()
 
+	headerCtx, cancel := context.WithCancel(workCtx)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+
+	// Drop a sanity check failure reported before the subscription restarted.
+	select {
+	case <-l.sanityErrCh:
+	default:
+	}
+
+	headerErrCh := make(chan error, 1)
 	lastReceivedTime := time.Now()
 	for {
 		select {
 		case <-ctx.Done():
-			l.l.Infow("Stop subscribing for new head")
+			l.l.Infow("Stop subscribing for new head, wait for heads being handled")
+			wg.Wait()
+			l.flushQueue(headerCtx, blockCh)
 
 			return nil
 		case err = <-sub.Err():
+			if ctx.Err() != nil {
+				// The subscription ends with ctx, handle the shutdown above.
+				continue
+			}
+
 			l.l.Errorw("Error while subscribing new head", "error", err)
 
-			return err
+			return newError(StageSubscribe, err)
+		case err = <-headerErrCh:
+			return newError(StageHandleHeader, err)
+		case err = <-l.sanityErrCh:
+			return newError(StageSanityCheck, err)
 		case <-ticker.C:
 			if time.Since(lastReceivedTime) > l.sanityCheckInterval {
 				l.l.Errorw("Websocket connection is corrupted", "lastReceivedTime", lastReceivedTime)
 
-				return errConnectionCorrupted
+				return newError(StageSubscribe, errConnectionCorrupted)
 			}
 		case header := <-headerCh:
 			l.l.Debugw("Receive new head of the chain", "header", header)
+			if l.isPaused() && len(blockCh) == cap(blockCh) {
+				// Stop fetching blocks which can not be held, they are fetched again after resume.
+				l.l.Warnw("Stop subscribing for new head while publishing is paused", "numBlocks", len(blockCh))
+
+				return errPausedSubscription
+			}
 
 			lastReceivedTime = time.Now()
 			l.mu.Lock()
@@ -278,13 +368,24 @@ func (l *Listener) subscribeNewBlockHead(ctx context.Context, blockCh chan<- typ
 			}
 			l.mu.Unlock()
 
+			wg.Add(1)
 			go func(seq uint64, head *types.Header) {
-				b, err := l.handleNewHeader(ctx, head)
+				defer wg.Done()
+
+				b, err := l.handleNewHeader(headerCtx, head)
 				if err != nil {
-					l.l.Fatalw("Fail to handle new head", "header", header, "error", err)
+					if headerCtx.Err() == nil {
+						l.l.Errorw("Fail to handle new head", "header", head, "error", err)
+						select {
+						case headerErrCh <- err:
+						default:
+						}
+					}
+
+					return
 				}
 
-				l.publishBlock(blockCh, seq, &b)
+				l.publishBlock(headerCtx, blockCh, seq, &b)
 			}(seq, header)
 
 			seq++
@@ -292,63 +393,91 @@ func (l *Listener) subscribeNewBlockHead(ctx context.Context, blockCh chan<- typ
 	}
 }
 
-func (l *Listener) syncBlocks(ctx context.Context, blockCh chan types.Block) error {
-	for {
-		err := l.subscribeNewBlockHead(ctx, blockCh)
-		if err == nil {
+// syncBlocks subscribes for new heads, restarting the subscription after transient failures as
+// allowed by the restart policy. It returns nil when ctx is done.
+func (l *Listener) syncBlocks(ctx, workCtx context.Context, blockCh chan types.Block) error {
+	policy := l.option.retry()
+	restarts := &restartTracker{policy: l.option.restartPolicy}
+	for attempt := 0; ; attempt++ {
+		start := time.Now()
+		err := l.subscribeNewBlockHead(ctx, workCtx, blockCh)
+		if err == nil || ctx.Err() != nil {
 			return nil
 		}
 
+		if errors.Is(err, errPausedSubscription) {
+			l.setResuming(true)
+			if err := l.waitResumed(ctx); err != nil {
+				return nil //nolint:nilerr
+			}
+
+			l.l.Infow("Re-subscribe for new block head after resume")
+
+			continue
+		}
+
 		l.l.Errorw("Error occur while sync blocks", "error", err)
-		if !websocket.IsCloseError(err, websocket.CloseAbnormalClosure,
-			websocket.CloseNormalClosure, websocket.CloseServiceRestart, websocket.CloseGoingAway) &&
-			!errors.Is(err, syscall.ECONNRESET) &&
-			!errors.Is(err, ethereum.NotFound) &&
-			err.Error() != errStringUnknownBlock &&
-			!errors.Is(err, errConnectionCorrupted) {
+		class := retry.Classify(err)
+		if !class.Retryable() {
 			return err
 		}
 
-		l.l.Infow("Re-subscribe for new block head from node")
+		if !restarts.allow(time.Now()) {
+			l.l.Errorw("Stop re-subscribing, too many restarts", "policy", l.option.restartPolicy)
+
+			return fmt.Errorf("%w: %w", ErrTooManyRestarts, err)
+		}
+
+		// The subscription was healthy for a while, so start backing off from scratch.
+		if time.Since(start) > policy.MaxInterval {
+			attempt = 0
+		}
+
+		// Sanity check waits until the listener catches up again.
+		l.setResuming(true)
+
+		delay := policy.Backoff(attempt)
+		l.l.Infow("Re-subscribe for new block head from node", "class", class, "delay", delay)
+		if err := retry.Sleep(ctx, delay); err != nil {
+			return nil //nolint:nilerr
+		}
 	}
 }
 
-// Run listens for new block head and handle it.
+// Run runs the listener until ctx is done or a failure stops it, the failure is returned as an *Error.
+// Failures of any goroutine cancel the others, and Run returns once all of them have returned.
+//
+// When ctx is done, new heads are no longer accepted, but blocks already fetched are still handled and
+// published until the shutdown timeout, then the head of the block keeper is flushed. Run returns
+// ErrShutdownTimeout if blocks were abandoned.
+//
+//nolint:cyclop
 func (l *Listener) Run(ctx context.Context) error {
+	if l.option.chainName != "" {
+		ctx = labels.WithChain(ctx, l.option.chainName)
+	}
+
 	l.l.Info("Start listener service")
 	defer l.l.Info("Stop listener service")
 
 	l.l.Info("Init handler")
-	returnErr := l.handler.Init(ctx)
-	if returnErr != nil {
-		l.l.Errorw("Fail to init handler", "error", returnErr)
+	if err := l.handler.Init(ctx); err != nil {
+		l.l.Errorw("Fail to init handler", "error", err)
 
-		return returnErr
+		return newError(StageInit, err)
 	}
 
-	l.setResuming(true)
-
-	// Start go routine for sanity checking.
-	go func() {
-		err := l.runSanityCheck(ctx)
-		if err != nil {
-			l.l.Fatalw("Sanity check failed", "error", err)
-		}
-	}()
-
-	// Synchronize blocks from node.
-	blockCh := make(chan types.Block, bufLen)
-	go func() {
-		returnErr = l.syncBlocks(ctx, blockCh)
-		if returnErr != nil {
-			l.l.Errorw("Fail to sync blocks", "error", returnErr)
-		}
-
-		close(blockCh)
-	}()
+	l.mu.Lock()
+	l.initialised = true
+	l.resuming = true
+	l.mu.Unlock()
 
-	// Start metrics collector.
-	if err := l.startMetricsCollector(ctx); err != nil {
+	// Start metrics collector, once as the listener may be run again after a failure.
+	var err error
+	l.metricsOnce.Do(func() {
+		err = l.startMetricsCollector(ctx)
+	})
+	if err != nil {
 		l.l.Errorw("Fail to start metrics collector", "error", err)
 
 		return err
@@ -357,26 +486,166 @@ func (l *Listener) Run(ctx context.Context) error {
 		_ = pkgmetric.Flush(context.Background())
 	}()
 
-	l.l.Info("Start handling for new blocks")
-	for b := range blockCh {
-		l.l.Debugw("Receive new block",
-			"hash", b.Hash, "parent", b.ParentHash, "numLogs", len(b.Logs))
-		err := l.handler.Handle(ctx, b)
+	// The lease is kept until blocks already fetched are published and the head is saved.
+	if l.option.lease != nil {
+		campaignCtx, stopCampaign := context.WithCancel(context.WithoutCancel(ctx))
+		campaignDone := make(chan struct{})
+		go func() {
+			defer close(campaignDone)
+			l.campaign(campaignCtx)
+		}()
+		defer func() {
+			stopCampaign()
+			<-campaignDone
+			if err := l.option.lease.Release(context.WithoutCancel(ctx)); err != nil {
+				l.l.Errorw("Fail to release leader lease", "error", err)
+			}
+		}()
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	l.setRunning(true)
+	defer l.setRunning(false)
+
+	// Blocks already fetched are handled with workCtx, which is only canceled when the shutdown
+	// timeout expires after ctx is done.
+	workCtx, cancelWork := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelWork()
+	drained := make(chan struct{})
+	g.Go(func() error {
+		select {
+		case <-drained:
+			l.setRunning(false)
+
+			return nil
+		case <-ctx.Done():
+			l.setRunning(false)
+		}
+
+		l.l.Infow("Shutting down, drain blocks already fetched", "timeout", l.option.shutdownTimeout())
+		timer := time.NewTimer(l.option.shutdownTimeout())
+		defer timer.Stop()
+
+		select {
+		case <-drained:
+		case <-timer.C:
+			cancelWork()
+		}
+
+		return nil
+	})
+
+	// Start go routine for sanity checking.
+	g.Go(func() error {
+		return l.runSanityCheck(ctx)
+	})
+
+	// Synchronize blocks from node.
+	blockCh := make(chan types.Block, bufLen)
+	l.mu.Lock()
+	l.blockCh = blockCh
+	l.mu.Unlock()
+	g.Go(func() error {
+		defer close(blockCh)
+
+		err := l.syncBlocks(ctx, workCtx, blockCh)
 		if err != nil {
-			l.l.Errorw("Fail to handle new block", "hash", b.Hash, "error", err)
+			l.l.Errorw("Fail to sync blocks", "error", err)
+		}
 
-			return err
+		return err
+	})
+
+	g.Go(func() error {
+		defer close(drained)
+		// Blocks can no longer be handled, so stop sending them.
+		defer cancelWork()
+
+		l.l.Info("Start handling for new blocks")
+		for {
+			// Blocks are held while paused, and abandoned if the listener stops meanwhile.
+			in, stopping := blockCh, (<-chan struct{})(nil)
+			if l.isPaused() {
+				in, stopping = nil, ctx.Done()
+			}
+
+			var b types.Block
+			var ok bool
+			select {
+			case <-workCtx.Done():
+				l.l.Errorw("Abandon blocks on shutdown", "numBlocks", len(blockCh))
+
+				return newError(StageShutdown, ErrShutdownTimeout)
+			case <-stopping:
+				l.l.Warnw("Abandon blocks on shutdown, publishing is paused", "numBlocks", len(blockCh))
+
+				return nil
+			case <-l.pauseCh:
+				continue
+			case c := <-l.controlCh:
+				c.done <- c.fn(workCtx) //nolint:contextcheck
+
+				continue
+			case b, ok = <-in:
+				if !ok {
+					return nil
+				}
+			}
+
+			l.l.Debugw("Receive new block",
+				"hash", b.Hash, "parent", b.ParentHash, "numLogs", len(b.Logs))
+			err := l.handler.Handle(workCtx, b) //nolint:contextcheck
+			if err != nil && workCtx.Err() != nil {
+				l.l.Errorw("Abandon blocks on shutdown", "numBlocks", len(blockCh)+1)
+
+				return newError(StageShutdown, ErrShutdownTimeout)
+			}
+			if err != nil {
+				l.l.Errorw("Fail to handle new block", "hash", b.Hash, "error", err)
+
+				return newError(StageHandleBlock, err)
+			}
+
+			l.mu.Lock()
+			l.lastHandledBlockNumber = b.Number
+			l.lastHandledTime = time.Now()
+			l.mu.Unlock()
+		}
+	})
+
+	err = g.Wait()
+
+	l.l.Infow("Flush block keeper head")
+	if flushErr := l.handler.blockKeeper.Flush(); flushErr != nil {
+		l.l.Errorw("Fail to flush block keeper head", "error", flushErr)
+		if err == nil {
+			err = newError(StageShutdown, flushErr)
 		}
+	}
 
-		l.mu.Lock()
-		l.lastHandledBlockNumber = b.Number
-		l.mu.Unlock()
+	if err == nil {
+		l.l.Infow("Listener stopped gracefully")
 	}
 
-	return returnErr
+	return err
+}
+
+// Close releases resources of the publisher, such as the spool. It is called once Run returned
+// for the last time, the listener can not be run again after it.
+func (l *Listener) Close() error {
+	return l.handler.Close()
+}
+
+func (l *Listener) getCatchUpProgress() *catchUpProgress {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.catchUpProgress
 }
 
-func (l *Listener) startMetricsCollector(_ context.Context) error {
+func (l *Listener) startMetricsCollector(ctx context.Context) error {
+	attrs := metric.WithAttributes(labels.Attributes(ctx)...)
+
 	// Register callback for collecting last received block number.
 	_, err := pkgmetric.Meter().Int64ObservableGauge(
 		metricNameLastReceivedBlockNumber,
@@ -386,7 +655,7 @@ func (l *Listener) startMetricsCollector(_ context.Context) error {
 			l.mu.Unlock()
 
 			if lastReceivedBlock != nil && lastReceivedBlock.Number != nil {
-				obsrv.Observe(lastReceivedBlock.Number.Int64())
+				obsrv.Observe(lastReceivedBlock.Number.Int64(), attrs)
 			}
 
 			return nil
@@ -407,7 +676,7 @@ func (l *Listener) startMetricsCollector(_ context.Context) error {
 			l.mu.Unlock()
 
 			if lastHandledBlockNumber != nil {
-				obsrv.Observe(lastHandledBlockNumber.Int64())
+				obsrv.Observe(lastHandledBlockNumber.Int64(), attrs)
 			}
 
 			return nil
@@ -428,7 +697,7 @@ func (l *Listener) startMetricsCollector(_ context.Context) error {
 			l.mu.Unlock()
 
 			if lastCheckedBlockNumber != nil {
-				obsrv.Observe(lastCheckedBlockNumber.Int64())
+				obsrv.Observe(lastCheckedBlockNumber.Int64(), attrs)
 			}
 
 			return nil
@@ -440,11 +709,82 @@ func (l *Listener) startMetricsCollector(_ context.Context) error {
 		return err
 	}
 
+	// Register callbacks for collecting progress of catching up with the chain.
+	_, err = pkgmetric.Meter().Int64ObservableGauge(
+		metricNameCatchUpRemainingBlocks,
+		metric.WithInt64Callback(func(_ context.Context, obsrv metric.Int64Observer) error {
+			if progress := l.getCatchUpProgress(); progress != nil {
+				obsrv.Observe(int64(progress.remaining()), attrs) //nolint:gosec
+			}
+
+			return nil
+		}),
+	)
+	if err != nil {
+		l.l.Errorw("Fail to register metrics collector for catch up remaining blocks", "error", err)
+
+		return err
+	}
+
+	_, err = pkgmetric.Meter().Float64ObservableGauge(
+		metricNameCatchUpETA,
+		metric.WithFloat64Callback(func(_ context.Context, obsrv metric.Float64Observer) error {
+			if progress := l.getCatchUpProgress(); progress != nil {
+				obsrv.Observe(progress.eta().Seconds(), attrs)
+			}
+
+			return nil
+		}),
+	)
+	if err != nil {
+		l.l.Errorw("Fail to register metrics collector for catch up eta", "error", err)
+
+		return err
+	}
+
+	_, err = pkgmetric.Meter().Int64ObservableGauge(
+		metricNameCatchUpConcurrency,
+		metric.WithInt64Callback(func(_ context.Context, obsrv metric.Int64Observer) error {
+			if progress := l.getCatchUpProgress(); progress != nil {
+				obsrv.Observe(int64(progress.limiter.current()), attrs)
+			}
+
+			return nil
+		}),
+	)
+	if err != nil {
+		l.l.Errorw("Fail to register metrics collector for catch up concurrency", "error", err)
+
+		return err
+	}
+
+	if l.option.lease == nil {
+		return nil
+	}
+
+	_, err = pkgmetric.Meter().Int64ObservableGauge(
+		metricNameLeader,
+		metric.WithInt64Callback(func(_ context.Context, obsrv metric.Int64Observer) error {
+			var leader int64
+			if l.option.lease.Token() != 0 {
+				leader = 1
+			}
+			obsrv.Observe(leader, attrs)
+
+			return nil
+		}),
+	)
+	if err != nil {
+		l.l.Errorw("Fail to register metrics collector for leader", "error", err)
+
+		return err
+	}
+
 	return nil
 }
 
 func (l *Listener) sanityCheck(ctx context.Context, validSecond uint64) error {
-	header, err := getHeaderByNumber(ctx, l.sanityEVMClient, nil)
+	header, err := getHeaderByNumber(ctx, l.sanityEVMClient, nil, l.option)
 	if err != nil {
 		l.l.Errorw("Fail to get latest block", "error", err)
 
@@ -469,7 +809,8 @@ func (l *Listener) sanityCheck(ctx context.Context, validSecond uint64) error {
 	}
 
 	if lastBlock.Timestamp < header.Time-validSecond {
-		return errors.New("sanity check failed")
+		return fmt.Errorf("%w: last received block %v at %d, sanity node block %v at %d", ErrSanityCheckFailed,
+			lastBlock.Number, lastBlock.Timestamp, header.Number, header.Time)
 	}
 
 	return nil
@@ -495,7 +836,11 @@ func (l *Listener) runSanityCheck(ctx context.Context) error {
 		case <-ticker.C:
 			err := l.sanityCheck(ctx, intervalSecond)
 			if err != nil {
-				return err
+				l.l.Errorw("Sanity check failed", "error", err)
+				select {
+				case l.sanityErrCh <- err:
+				default:
+				}
 			}
 		}
 	}
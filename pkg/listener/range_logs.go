@@ -0,0 +1,168 @@
+package listener
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/KyberNetwork/evmlistener/pkg/evmclient"
+	"github.com/KyberNetwork/evmlistener/pkg/types"
+	"golang.org/x/sync/errgroup"
+)
+
+// rangeTooLargeErrors contains error messages returned by providers when the block range
+// of a log query is too large, or the response has too many results.
+//
+//nolint:gochecknoglobals
+var rangeTooLargeErrors = []string{
+	"query returned more than",
+	"response size",
+	"too many results",
+	"block range is too wide",
+	"exceed maximum block range",
+	"exceeds maximum block range",
+	"range is too large",
+	"range too large",
+}
+
+// isRangeTooLarge reports whether the error indicates the range of a log query should be reduced.
+func isRangeTooLarge(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, s := range rangeTooLargeErrors {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getLogsByRange returns logs of given consecutive headers, grouped by block. It queries logs by
+// block ranges of at most maxRange blocks, the range is halved whenever the provider rejects it.
+// Logs are checked against the headers' hashes, so that logs from another fork are never mixed in.
+func getLogsByRange(ctx context.Context, evmClient evmclient.IClient, headers []*types.Header,
+	maxRange uint64, opts *FilterOption,
+) ([][]types.Log, error) {
+	if len(headers) == 0 {
+		return nil, nil
+	}
+
+	first := headers[0].Number.Uint64()
+	res := make([][]types.Log, len(headers))
+
+	size := maxRange
+	for from := uint64(0); from < uint64(len(headers)); {
+		to := min(from+size, uint64(len(headers))) - 1
+		logs, err := evmClient.FilterLogs(ctx, evmclient.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(first + from),
+			ToBlock:   new(big.Int).SetUint64(first + to),
+			Addresses: opts.filterContracts,
+			Topics:    opts.filterTopics,
+		})
+		if err != nil {
+			if size > 1 && isRangeTooLarge(err) {
+				size /= 2
+
+				continue
+			}
+
+			return nil, err
+		}
+
+		for _, log := range logs {
+			if log.BlockNumber < first+from || log.BlockNumber > first+to {
+				return nil, fmt.Errorf("%w: log of block %d is out of range [%d, %d]",
+					errInconsistentBlocks, log.BlockNumber, first+from, first+to)
+			}
+
+			i := log.BlockNumber - first
+			if log.BlockHash != headers[i].Hash {
+				return nil, fmt.Errorf("%w: log of block %d has hash %s, expect %s",
+					errInconsistentBlocks, log.BlockNumber, log.BlockHash, headers[i].Hash)
+			}
+
+			res[i] = append(res[i], log)
+		}
+
+		from = to + 1
+
+		// Grow the range back after a successful query.
+		size = min(size*2, maxRange)
+	}
+
+	return res, nil
+}
+
+// getHeaders returns consecutive headers in range [fromBlock, toBlock].
+func (l *Listener) getHeaders(ctx context.Context, fromBlock, toBlock uint64) ([]*types.Header, error) {
+	if headers, ok := l.batcher.getHeaders(ctx, l.httpEVMClient, fromBlock, toBlock); ok {
+		return headers, nil
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	headers := make([]*types.Header, toBlock-fromBlock+1)
+	for i := range headers {
+		number := new(big.Int).SetUint64(fromBlock + uint64(i))
+		g.Go(func() error {
+			header, err := getHeaderByNumber(ctx, l.httpEVMClient, number, l.option)
+			if err != nil {
+				return err
+			}
+
+			headers[i] = header
+
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	if err := checkHeaders(headers); err != nil {
+		return nil, err
+	}
+
+	return headers, nil
+}
+
+// getBlocksByRange returns blocks in range [fromBlock, toBlock], fetching logs by block ranges.
+func (l *Listener) getBlocksByRange(ctx context.Context, fromBlock, toBlock uint64) ([]types.Block, error) {
+	headers, err := l.getHeaders(ctx, fromBlock, toBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	logs, err := getLogsByRange(ctx, l.httpEVMClient, headers, l.option.logRange, l.option)
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure the chain was not re-organized while querying logs. As the headers are linked
+	// by parent hashes, the range is still canonical if its last block is.
+	last := headers[len(headers)-1]
+	header, err := getHeaderByNumber(ctx, l.httpEVMClient, last.Number, l.option)
+	if err != nil {
+		return nil, err
+	}
+
+	if header.Hash != last.Hash {
+		return nil, fmt.Errorf("%w: block %v was re-organized from %s to %s",
+			errInconsistentBlocks, last.Number, last.Hash, header.Hash)
+	}
+
+	blocks := make([]types.Block, 0, len(headers))
+	for i, header := range headers {
+		if verifyLogs(ctx, l.httpEVMClient, header, logs[i], l.option) != nil {
+			logs[i], err = getLogsByBlockHash(ctx, l.httpEVMClient, header, l.option)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		blocks = append(blocks, headerToBlock(header, logs[i]))
+	}
+
+	return blocks, nil
+}
@@ -0,0 +1,118 @@
+package listener
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/KyberNetwork/evmlistener/pkg/evmclient"
+	"github.com/KyberNetwork/evmlistener/pkg/types"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+)
+
+// limitedRangeClient rejects log queries having more than limit results.
+type limitedRangeClient struct {
+	*ChainClientMock
+
+	limit int
+}
+
+func (c *limitedRangeClient) FilterLogs(ctx context.Context, q evmclient.FilterQuery) ([]types.Log, error) {
+	logs, err := c.ChainClientMock.FilterLogs(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(logs) > c.limit {
+		return nil, errors.New("query returned more than 10000 results")
+	}
+
+	return logs, nil
+}
+
+type RangeLogsTestSuite struct {
+	suite.Suite
+
+	client *ChainClientMock
+	opts   *FilterOption
+}
+
+func (ts *RangeLogsTestSuite) SetupTest() {
+	ts.client = NewChainClientMock(1000, 100, 2)
+	ts.opts = newFilterOption(WithEventLogs(nil, nil), WithLogRange(20))
+}
+
+func (ts *RangeLogsTestSuite) headers(from, to uint64) []*types.Header {
+	headers := make([]*types.Header, 0, to-from+1)
+	for n := from; n <= to; n++ {
+		headers = append(headers, ts.client.Header(n))
+	}
+
+	return headers
+}
+
+func (ts *RangeLogsTestSuite) TestGetLogsByRange() {
+	logs, err := getLogsByRange(context.Background(), ts.client, ts.headers(1010, 1059), 20, ts.opts)
+	ts.Require().NoError(err)
+	ts.Require().Len(logs, 50)
+	for i, blockLogs := range logs {
+		ts.Require().Len(blockLogs, 2)
+		ts.Assert().Equal(ts.client.Header(uint64(1010+i)).Hash, blockLogs[0].BlockHash)
+	}
+
+	ts.Assert().Equal(3, ts.client.Calls("FilterLogs"))
+}
+
+func (ts *RangeLogsTestSuite) TestSplitRange() {
+	client := &limitedRangeClient{ChainClientMock: ts.client, limit: 10}
+
+	logs, err := getLogsByRange(context.Background(), client, ts.headers(1010, 1049), 20, ts.opts)
+	ts.Require().NoError(err)
+	ts.Require().Len(logs, 40)
+	for _, blockLogs := range logs {
+		ts.Assert().Len(blockLogs, 2)
+	}
+
+	// A single block which exceeds the limit can not be split anymore.
+	client.limit = 1
+	_, err = getLogsByRange(context.Background(), client, ts.headers(1010, 1019), 20, ts.opts)
+	ts.Assert().Error(err)
+}
+
+func (ts *RangeLogsTestSuite) TestReorg() {
+	headers := ts.headers(1080, 1099)
+	ts.client.Reorg(5)
+
+	_, err := getLogsByRange(context.Background(), ts.client, headers, 20, ts.opts)
+	ts.Assert().ErrorIs(err, errInconsistentBlocks)
+}
+
+func (ts *RangeLogsTestSuite) TestListenerGetBlocks() {
+	l := New(zap.S(), ts.client, ts.client, nil, ts.client, 0, WithEventLogs(nil, nil), WithLogRange(20))
+
+	blocks, err := l.getBlocks(context.Background(), 1010, 1049)
+	ts.Require().NoError(err)
+	ts.Require().Len(blocks, 40)
+	for i, b := range blocks {
+		ts.Assert().Equal(ts.client.Header(uint64(1010+i)).Hash, b.Hash)
+		ts.Assert().Len(b.Logs, 2)
+	}
+
+	ts.Assert().Equal(2, ts.client.Calls("FilterLogs"))
+}
+
+func (ts *RangeLogsTestSuite) TestIsRangeTooLarge() {
+	ts.Assert().True(isRangeTooLarge(errors.New("query returned more than 10000 results")))
+	ts.Assert().True(isRangeTooLarge(errors.New("Log response size exceeded")))
+	ts.Assert().True(isRangeTooLarge(errors.New("eth_getLogs block range is too large")))
+	ts.Assert().True(isRangeTooLarge(errors.New("block range is too wide")))
+	ts.Assert().True(isRangeTooLarge(errors.New("exceed maximum block range: 5000")))
+	ts.Assert().False(isRangeTooLarge(errors.New("connection reset by peer")))
+	ts.Assert().False(isRangeTooLarge(errors.New("invalid block range params")))
+	ts.Assert().False(isRangeTooLarge(errors.New("block range extends beyond current head block")))
+}
+
+func TestRangeLogsTestSuite(t *testing.T) {
+	suite.Run(t, new(RangeLogsTestSuite))
+}
@@ -0,0 +1,237 @@
+package listener
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/KyberNetwork/evmlistener/pkg/errors"
+	"github.com/KyberNetwork/evmlistener/pkg/types"
+	"go.uber.org/zap"
+)
+
+// maxForcedResyncBlocks is the maximum number of blocks a forced resync goes back from the head.
+const maxForcedResyncBlocks = 1024
+
+// errReorgTooDeep means the common ancestor of a re-organization is older than blocks in the block keeper.
+var errReorgTooDeep = errors.New("re-organization deeper than block keeper")
+
+// findCommonAncestor checks stored blocks from the head against canonical headers by number, until
+// the common ancestor with the chain of block number newNumber. It returns the stored blocks which
+// are not in the canonical chain in descending order, and the common ancestor, nil if it is older
+// than the stored blocks.
+func (h *Handler) findCommonAncestor(ctx context.Context, newNumber uint64) ([]types.Block, *types.Block, error) {
+	stored, err := h.blockKeeper.Head()
+	if err != nil {
+		h.l.Errorw("Fail to get stored block head", "error", err)
+
+		return nil, nil, err
+	}
+
+	var reverted []types.Block
+	for {
+		// Stored blocks after the new block are not in its chain.
+		if stored.Number.Uint64() <= newNumber {
+			header, err := getHeaderByNumber(ctx, h.evmClient, stored.Number, h.option)
+			if err != nil {
+				h.l.Errorw("Fail to get canonical header", "number", stored.Number, "error", err)
+
+				return nil, nil, err
+			}
+
+			if header.Hash == stored.Hash {
+				return reverted, &stored, nil
+			}
+		}
+
+		reverted = append(reverted, stored)
+		parentHash := stored.ParentHash
+		stored, err = h.blockKeeper.Get(parentHash)
+		if errors.Is(err, errors.ErrNotFound) {
+			return reverted, nil, nil
+		}
+		if err != nil {
+			h.l.Errorw("Fail to get stored block", "hash", parentHash, "error", err)
+
+			return nil, nil, err
+		}
+	}
+}
+
+// getCanonicalBlocks returns blocks of the chain of the new block in ascending order, from block number
+// fromBlock or more to fill the block keeper. The chain must contain the ancestor if it is known.
+func (h *Handler) getCanonicalBlocks(
+	ctx context.Context, b types.Block, fromBlock uint64, ancestor *types.Block,
+) ([]types.Block, error) {
+	n := h.blockKeeper.Cap()
+	blocks := []types.Block{b}
+	for {
+		last := blocks[len(blocks)-1]
+		number := last.Number.Uint64()
+		if ancestor != nil && number == ancestor.Number.Uint64()+1 && last.ParentHash != ancestor.Hash {
+			return nil, fmt.Errorf("block %v is not a child of common ancestor %v", last.Hash, ancestor.Hash)
+		}
+
+		if number == 0 || (number <= fromBlock && len(blocks) >= n) {
+			break
+		}
+
+		parent, err := h.getBlock(ctx, last.ParentHash)
+		if err != nil {
+			return nil, err
+		}
+
+		blocks = append(blocks, parent)
+	}
+
+	for i := range len(blocks) / 2 {
+		blocks[i], blocks[len(blocks)-i-1] = blocks[len(blocks)-i-1], blocks[i]
+	}
+
+	return blocks, nil
+}
+
+// resync handles a re-organization whose common ancestor is not in the block keeper. It publishes
+// a message with the stored blocks which are not in the canonical chain and the canonical blocks
+// from the common ancestor, or from the oldest stored block if the common ancestor is unknown,
+// then rebuilds the block keeper from the canonical chain. A new block which is not in the canonical
+// chain is dropped.
+func (h *Handler) resync(ctx context.Context, b types.Block) error {
+	log := h.l.With("blockNumber", b.Number, "blockHash", b.Hash)
+
+	log.Warnw("Re-organization is deeper than block keeper, resync with canonical chain")
+	newNumber := b.Number.Uint64()
+	reverted, ancestor, err := h.findCommonAncestor(ctx, newNumber)
+	if err != nil {
+		log.Errorw("Fail to find common ancestor", "error", err)
+
+		return err
+	}
+
+	var fromBlock uint64
+	switch {
+	case ancestor != nil && ancestor.Number.Uint64() >= newNumber:
+		// The new block is stale, the canonical chain is handled with the next head.
+		log.Warnw("Drop block which is not in canonical chain", "ancestorNumber", ancestor.Number)
+
+		return nil
+	case ancestor != nil:
+		fromBlock = ancestor.Number.Uint64() + 1
+		log.Infow("Found common ancestor", "number", ancestor.Number, "hash", ancestor.Hash)
+	default:
+		fromBlock = min(reverted[len(reverted)-1].Number.Uint64(), newNumber)
+		log.Warnw("Common ancestor is older than stored blocks, blocks before are not checked",
+			"fromBlock", fromBlock)
+	}
+
+	return h.resyncFrom(ctx, log, b, fromBlock, reverted, ancestor)
+}
+
+// ForceResync publishes a resync message from block number fromBlock, or from the common ancestor
+// with the canonical chain if the re-organization is deeper, up to the block keeper head. The block
+// keeper is then rebuilt from the canonical chain. It must not be called concurrently with Handle.
+func (h *Handler) ForceResync(ctx context.Context, fromBlock uint64) error {
+	head, err := h.blockKeeper.Head()
+	if err != nil {
+		h.l.Errorw("Fail to get stored block head", "error", err)
+
+		return err
+	}
+
+	headNumber := head.Number.Uint64()
+	if fromBlock > headNumber || headNumber-fromBlock >= maxForcedResyncBlocks {
+		return fmt.Errorf("%w: block %d is not within %d blocks before head %d",
+			errors.ErrInvalidArgument, fromBlock, maxForcedResyncBlocks, headNumber)
+	}
+
+	b, err := getBlockByNumber(ctx, h.evmClient, head.Number, h.option)
+	if err != nil {
+		h.l.Errorw("Fail to get canonical block", "number", head.Number, "error", err)
+
+		return err
+	}
+
+	log := h.l.With("blockNumber", b.Number, "blockHash", b.Hash)
+	log.Warnw("Force resync with canonical chain", "fromBlock", fromBlock)
+	reverted, ancestor, err := h.findCommonAncestor(ctx, headNumber)
+	if err != nil {
+		log.Errorw("Fail to find common ancestor", "error", err)
+
+		return err
+	}
+
+	switch {
+	case ancestor != nil:
+		fromBlock = min(fromBlock, ancestor.Number.Uint64()+1)
+	case len(reverted) > 0:
+		fromBlock = min(fromBlock, reverted[len(reverted)-1].Number.Uint64())
+	}
+
+	return h.resyncFrom(ctx, log, b, fromBlock, reverted, ancestor)
+}
+
+// resyncFrom publishes a resync message with the reverted blocks and the canonical blocks from block
+// number fromBlock up to b, then rebuilds the block keeper from the canonical chain.
+func (h *Handler) resyncFrom(
+	ctx context.Context, log *zap.SugaredLogger, b types.Block, fromBlock uint64,
+	reverted []types.Block, ancestor *types.Block,
+) error {
+	blocks, err := h.getCanonicalBlocks(ctx, b, fromBlock, ancestor)
+	if err != nil {
+		log.Errorw("Fail to get canonical blocks", "error", err)
+
+		return err
+	}
+
+	var newBlocks []types.Block
+	for i, block := range blocks {
+		if block.Number.Uint64() >= fromBlock {
+			newBlocks = blocks[i:]
+
+			break
+		}
+	}
+
+	revertedHashes := make([]string, 0, len(reverted))
+	for _, block := range reverted {
+		revertedHashes = append(revertedHashes, block.Hash)
+	}
+
+	log.Infow("Resync from block", "fromBlock", fromBlock, "numRevertedBlocks", len(reverted))
+	msg := &types.Message{
+		RevertedBlocks: reverted,
+		NewBlocks:      newBlocks,
+		Resync: &types.Resync{
+			FromBlock:           new(big.Int).SetUint64(fromBlock),
+			RevertedBlockHashes: revertedHashes,
+		},
+	}
+	err = h.publish(ctx, b, msg)
+	if err != nil {
+		log.Errorw("Fail to publish message", "error", err)
+
+		return err
+	}
+
+	log.Infow("Rebuild block keeper from canonical chain", "numBlocks", len(blocks))
+	err = h.blockKeeper.Reset()
+	if err != nil {
+		log.Errorw("Fail to reset block keeper", "error", err)
+
+		return err
+	}
+
+	if n := h.blockKeeper.Cap(); len(blocks) > n {
+		blocks = blocks[len(blocks)-n:]
+	}
+	for _, block := range blocks {
+		err = h.blockKeeper.Add(block)
+		if err != nil {
+			log.Errorw("Fail to add block", "hash", block.Hash, "error", err)
+
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,318 @@
+package listener
+
+import (
+	"context"
+	"testing"
+
+	"github.com/KyberNetwork/evmlistener/pkg/block"
+	"github.com/KyberNetwork/evmlistener/pkg/errors"
+	"github.com/KyberNetwork/evmlistener/pkg/types"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+)
+
+type ResyncTestSuite struct {
+	suite.Suite
+
+	client    *ChainClientMock
+	keeper    *block.BaseBlockKeeper
+	publisher *PublisherMock
+	handler   *Handler
+}
+
+func (ts *ResyncTestSuite) SetupTest() {
+	ts.client = NewChainClientMock(1000, 100, 1)
+	ts.keeper = block.NewBaseBlockKeeper(8)
+	ts.publisher = NewPublisherMock(10)
+	ts.handler = NewHandler(zap.S(), "test-topic", ts.client, ts.keeper, ts.publisher, WithEventLogs(nil, nil))
+	ts.Require().NoError(ts.handler.Init(context.Background()))
+}
+
+func (ts *ResyncTestSuite) getBlock(number uint64) types.Block {
+	b, err := getBlockByHash(context.Background(), ts.client, ts.client.Header(number).Hash, ts.handler.option)
+	ts.Require().NoError(err)
+
+	return b
+}
+
+func (ts *ResyncTestSuite) hashes(fromBlock, toBlock uint64) []string {
+	var hashes []string
+	for number := fromBlock; number <= toBlock; number++ {
+		hashes = append(hashes, ts.client.Header(number).Hash)
+	}
+
+	return hashes
+}
+
+func (ts *ResyncTestSuite) handle(b types.Block) types.Message {
+	ts.Require().NoError(ts.handler.Handle(context.Background(), b))
+	ts.Require().Len(ts.publisher.ch, 1)
+	msg, ok := (<-ts.publisher.ch).(types.Message)
+	ts.Require().True(ok)
+
+	return msg
+}
+
+func (ts *ResyncTestSuite) assertKeeper(fromBlock, toBlock uint64) {
+	blocks, err := ts.keeper.GetRecentBlocks(ts.keeper.Cap())
+	ts.Require().NoError(err)
+
+	var hashes []string
+	for i := len(blocks) - 1; i >= 0; i-- {
+		hashes = append(hashes, blocks[i].Hash)
+	}
+	ts.Assert().Equal(ts.hashes(fromBlock, toBlock), hashes)
+}
+
+func blockHashes(blocks []types.Block) []string {
+	hashes := make([]string, 0, len(blocks))
+	for _, b := range blocks {
+		hashes = append(hashes, b.Hash)
+	}
+
+	return hashes
+}
+
+func (ts *ResyncTestSuite) TestDeepReorg() {
+	oldHashes := ts.hashes(1092, 1099)
+	ts.client.Reorg(20)
+
+	msg := ts.handle(ts.getBlock(1099))
+	ts.Require().NotNil(msg.Resync)
+	ts.Assert().EqualValues(1092, msg.Resync.FromBlock.Uint64())
+	ts.Assert().ElementsMatch(oldHashes, msg.Resync.RevertedBlockHashes)
+	ts.Assert().ElementsMatch(oldHashes, blockHashes(msg.RevertedBlocks))
+	ts.Assert().Equal(ts.hashes(1092, 1099), blockHashes(msg.NewBlocks))
+	ts.assertKeeper(1092, 1099)
+
+	// Following blocks are handled from the rebuilt block keeper.
+	ts.client.Extend(1)
+	msg = ts.handle(ts.getBlock(1100))
+	ts.Assert().Nil(msg.Resync)
+	ts.Assert().Empty(msg.RevertedBlocks)
+	ts.Assert().Equal(ts.hashes(1100, 1100), blockHashes(msg.NewBlocks))
+}
+
+func (ts *ResyncTestSuite) TestShorterChain() {
+	oldHashes := ts.hashes(1092, 1099)
+	ts.client.Reorg(20)
+	ts.client.headers = ts.client.headers[:95]
+
+	// Stored blocks after the new head are reverted.
+	msg := ts.handle(ts.getBlock(1094))
+	ts.Require().NotNil(msg.Resync)
+	ts.Assert().EqualValues(1092, msg.Resync.FromBlock.Uint64())
+	ts.Assert().ElementsMatch(oldHashes, msg.Resync.RevertedBlockHashes)
+	ts.Assert().Equal(ts.hashes(1092, 1094), blockHashes(msg.NewBlocks))
+	ts.assertKeeper(1087, 1094)
+}
+
+func (ts *ResyncTestSuite) TestCommonAncestor() {
+	oldHashes := ts.hashes(1097, 1099)
+	ts.client.Reorg(3)
+
+	ts.Require().NoError(ts.handler.resync(context.Background(), ts.getBlock(1099)))
+	msg, ok := (<-ts.publisher.ch).(types.Message)
+	ts.Require().True(ok)
+	ts.Require().NotNil(msg.Resync)
+	ts.Assert().EqualValues(1097, msg.Resync.FromBlock.Uint64())
+	ts.Assert().Equal([]string{oldHashes[2], oldHashes[1], oldHashes[0]}, msg.Resync.RevertedBlockHashes)
+	ts.Assert().Equal(ts.hashes(1097, 1099), blockHashes(msg.NewBlocks))
+	ts.assertKeeper(1092, 1099)
+}
+
+func (ts *ResyncTestSuite) TestStaleBlock() {
+	b := ts.getBlock(1095)
+	b.Hash = "0xstale"
+
+	// A block which is not in the canonical chain is dropped, stored blocks are kept.
+	ts.Require().NoError(ts.handler.resync(context.Background(), b))
+	ts.Assert().Empty(ts.publisher.ch)
+	ts.assertKeeper(1092, 1099)
+}
+
+func (ts *ResyncTestSuite) TestForceResync() {
+	ts.Require().NoError(ts.handler.ForceResync(context.Background(), 1096))
+	msg, ok := (<-ts.publisher.ch).(types.Message)
+	ts.Require().True(ok)
+	ts.Require().NotNil(msg.Resync)
+	ts.Assert().EqualValues(1096, msg.Resync.FromBlock.Uint64())
+	ts.Assert().Empty(msg.Resync.RevertedBlockHashes)
+	ts.Assert().Empty(msg.RevertedBlocks)
+	ts.Assert().Equal(ts.hashes(1096, 1099), blockHashes(msg.NewBlocks))
+	ts.assertKeeper(1092, 1099)
+
+	// The resync goes back to the common ancestor of a deeper re-organization.
+	oldHashes := ts.hashes(1097, 1099)
+	ts.client.Reorg(3)
+	ts.Require().NoError(ts.handler.ForceResync(context.Background(), 1098))
+	msg, ok = (<-ts.publisher.ch).(types.Message)
+	ts.Require().True(ok)
+	ts.Assert().EqualValues(1097, msg.Resync.FromBlock.Uint64())
+	ts.Assert().ElementsMatch(oldHashes, msg.Resync.RevertedBlockHashes)
+	ts.Assert().Equal(ts.hashes(1097, 1099), blockHashes(msg.NewBlocks))
+	ts.assertKeeper(1092, 1099)
+
+	ts.Assert().ErrorIs(ts.handler.ForceResync(context.Background(), 1100), errors.ErrInvalidArgument)
+	ts.Assert().ErrorIs(ts.handler.ForceResync(context.Background(), 10), errors.ErrInvalidArgument)
+	ts.Assert().Empty(ts.publisher.ch)
+}
+
+func (ts *ResyncTestSuite) TestRewind() {
+	ts.Require().NoError(ts.handler.Rewind(context.Background(), 1096))
+	msg, ok := (<-ts.publisher.ch).(types.Message)
+	ts.Require().True(ok)
+	ts.Assert().Nil(msg.Resync)
+	ts.Assert().Empty(msg.NewBlocks)
+	ts.Assert().Equal([]string{ts.hashes(1099, 1099)[0], ts.hashes(1098, 1098)[0], ts.hashes(1097, 1097)[0]},
+		blockHashes(msg.RevertedBlocks))
+	ts.assertKeeper(1092, 1096)
+
+	// Blocks after the rewound block are published again by the normal flow.
+	ts.client.Extend(1)
+	msg = ts.handle(ts.getBlock(1100))
+	ts.Assert().Empty(msg.RevertedBlocks)
+	ts.Assert().Equal(ts.hashes(1097, 1100), blockHashes(msg.NewBlocks))
+
+	ts.Assert().ErrorIs(ts.handler.Rewind(context.Background(), 1100), errors.ErrInvalidArgument)
+	ts.Assert().ErrorIs(ts.handler.Rewind(context.Background(), 1000), errors.ErrInvalidArgument)
+	ts.Assert().Empty(ts.publisher.ch)
+}
+
+type failingPublisher struct {
+	err error
+}
+
+func (p failingPublisher) Publish(context.Context, string, interface{}) error {
+	return p.err
+}
+
+// unstablePublisher fails with err if it is set, and publishes to next otherwise.
+type unstablePublisher struct {
+	err  error
+	next *PublisherMock
+}
+
+func (p *unstablePublisher) Publish(ctx context.Context, topic string, msg interface{}) error {
+	if p.err != nil {
+		return p.err
+	}
+
+	return p.next.Publish(ctx, topic, msg)
+}
+
+func (ts *ResyncTestSuite) TestRewindPublishFailure() {
+	blocks, err := ts.keeper.GetRecentBlocks(ts.keeper.Cap())
+	ts.Require().NoError(err)
+	reverted := blocks[:3]
+
+	publisher := &unstablePublisher{err: errors.New("publisher is unavailable"), next: ts.publisher}
+	handler := NewHandler(zap.S(), "test-topic", ts.client, ts.keeper, publisher, WithEventLogs(nil, nil))
+	ts.Require().Error(handler.Rewind(context.Background(), 1096))
+	ts.assertKeeper(1092, 1099)
+
+	// New blocks are not handled while the revert can not be published.
+	ts.client.Extend(1)
+	ts.Require().Error(handler.Handle(context.Background(), ts.getBlock(1100)))
+	ts.Assert().Empty(ts.publisher.ch)
+
+	// The revert is published before the blocks after the rewound block.
+	publisher.err = nil
+	ts.Require().NoError(handler.Handle(context.Background(), ts.getBlock(1100)))
+	ts.Require().Len(ts.publisher.ch, 2)
+	msg, ok := (<-ts.publisher.ch).(types.Message)
+	ts.Require().True(ok)
+	ts.Assert().Equal(blockHashes(reverted), blockHashes(msg.RevertedBlocks))
+	ts.Assert().Empty(msg.NewBlocks)
+	msg, ok = (<-ts.publisher.ch).(types.Message)
+	ts.Require().True(ok)
+	ts.Assert().Empty(msg.RevertedBlocks)
+	ts.Assert().Equal(ts.hashes(1097, 1100), blockHashes(msg.NewBlocks))
+
+	pending, err := ts.keeper.PendingRewind()
+	ts.Require().NoError(err)
+	ts.Assert().Nil(pending)
+}
+
+// restart replaces the handler as after a restart, with a block keeper initialised from blocks in
+// descending order and the rewind stored before the restart.
+func (ts *ResyncTestSuite) restart(blocks []types.Block, rewind *block.Rewind) {
+	initBlocks := make([]types.Block, 0, len(blocks))
+	for i := len(blocks) - 1; i >= 0; i-- {
+		initBlocks = append(initBlocks, blocks[i])
+	}
+
+	keeper := NewBlockKeeperMock(8)
+	keeper.SetInitData(initBlocks)
+	ts.Require().NoError(keeper.SetRewind(rewind))
+
+	ts.keeper = keeper.BaseBlockKeeper
+	ts.handler = NewHandler(zap.S(), "test-topic", ts.client, keeper, ts.publisher, WithEventLogs(nil, nil))
+	ts.Require().NoError(ts.handler.Init(context.Background()))
+}
+
+func (ts *ResyncTestSuite) TestRewindRestart() {
+	blocks, err := ts.keeper.GetRecentBlocks(ts.keeper.Cap())
+	ts.Require().NoError(err)
+	rewind := block.Rewind{Target: blocks[3], Reverted: blocks[:3]}
+
+	// The process stops once the rewind is stored, before the revert is published.
+	ts.restart(blocks, &rewind)
+	msg, ok := (<-ts.publisher.ch).(types.Message)
+	ts.Require().True(ok)
+	ts.Assert().Equal(blockHashes(rewind.Reverted), blockHashes(msg.RevertedBlocks))
+	ts.Assert().Empty(ts.publisher.ch)
+	ts.assertKeeper(1092, 1096)
+	pending, err := ts.keeper.PendingRewind()
+	ts.Require().NoError(err)
+	ts.Assert().Nil(pending)
+
+	// The revert fails to publish, the head does not move back until it is published.
+	ts.restart(blocks, nil)
+	handler := NewHandler(zap.S(), "test-topic", ts.client, ts.keeper,
+		failingPublisher{err: errors.New("publisher is unavailable")}, WithEventLogs(nil, nil))
+	ts.Require().Error(handler.Rewind(context.Background(), 1096))
+	ts.assertKeeper(1092, 1099)
+	pending, err = ts.keeper.PendingRewind()
+	ts.Require().NoError(err)
+	ts.Require().NotNil(pending)
+	ts.Assert().Equal(rewind, *pending)
+
+	// The process stops once the revert is published and marked, before the head moves back.
+	// The revert is not published again.
+	published := rewind
+	published.Published = true
+	ts.restart(blocks, &published)
+	ts.Assert().Empty(ts.publisher.ch)
+	ts.assertKeeper(1092, 1096)
+	pending, err = ts.keeper.PendingRewind()
+	ts.Require().NoError(err)
+	ts.Assert().Nil(pending)
+
+	// Blocks after the rewound block are published again by the normal flow.
+	ts.client.Extend(1)
+	msg = ts.handle(ts.getBlock(1100))
+	ts.Assert().Empty(msg.RevertedBlocks)
+	ts.Assert().Equal(ts.hashes(1097, 1100), blockHashes(msg.NewBlocks))
+}
+
+func (ts *ResyncTestSuite) TestRewindRestartWithoutBlocks() {
+	blocks, err := ts.keeper.GetRecentBlocks(ts.keeper.Cap())
+	ts.Require().NoError(err)
+	rewind := block.Rewind{Target: blocks[3], Reverted: blocks[:3]}
+
+	// Blocks expired from redis before the restart, the rewind is finished from the stored target.
+	ts.restart(nil, &rewind)
+	msg, ok := (<-ts.publisher.ch).(types.Message)
+	ts.Require().True(ok)
+	ts.Assert().Equal(blockHashes(rewind.Reverted), blockHashes(msg.RevertedBlocks))
+	ts.assertKeeper(1096, 1096)
+
+	ts.client.Extend(1)
+	msg = ts.handle(ts.getBlock(1100))
+	ts.Assert().Equal(ts.hashes(1097, 1100), blockHashes(msg.NewBlocks))
+}
+
+func TestResyncTestSuite(t *testing.T) {
+	suite.Run(t, new(ResyncTestSuite))
+}
@@ -0,0 +1,172 @@
+package listener
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/KyberNetwork/evmlistener/pkg/block"
+	"github.com/KyberNetwork/evmlistener/pkg/errors"
+	"github.com/KyberNetwork/evmlistener/pkg/types"
+)
+
+// Rewind publishes a message reverting the published blocks after block number number, then moves
+// the block keeper head back to that block. Following blocks are published again as new blocks by
+// the normal flow, from the canonical chain. The block must be in the block keeper. It must not be
+// called concurrently with Handle.
+//
+// The rewind is stored into the block keeper before anything else, so that a rewind which fails
+// is finished by the next Handle, and one interrupted by a restart by Init.
+func (h *Handler) Rewind(ctx context.Context, number uint64) error {
+	head, err := h.blockKeeper.Head()
+	if err != nil {
+		h.l.Errorw("Fail to get stored block head", "error", err)
+
+		return err
+	}
+
+	if number >= head.Number.Uint64() {
+		return fmt.Errorf("%w: block %d is not before head %v", errors.ErrInvalidArgument, number, head.Number)
+	}
+
+	blocks, err := h.blockKeeper.GetRecentBlocks(h.blockKeeper.Cap())
+	if err != nil {
+		h.l.Errorw("Fail to get stored blocks", "error", err)
+
+		return err
+	}
+
+	// Stored blocks are in descending order from the head.
+	var rewind *block.Rewind
+	for i, b := range blocks {
+		if b.Number.Uint64() <= number {
+			if b.Number.Uint64() == number {
+				rewind = &block.Rewind{Target: b, Reverted: blocks[:i]}
+			}
+
+			break
+		}
+	}
+	if rewind == nil {
+		return fmt.Errorf("%w: block %d is not in block keeper", errors.ErrInvalidArgument, number)
+	}
+
+	h.l.Warnw("Rewind to block", "blockNumber", number, "headNumber", head.Number,
+		"numRevertedBlocks", len(rewind.Reverted))
+	err = h.blockKeeper.SetRewind(rewind)
+	if err != nil {
+		h.l.Errorw("Fail to store rewind", "error", err)
+
+		return err
+	}
+	h.rewindPending = true
+
+	return h.finishRewind(ctx, rewind)
+}
+
+// resumeRewind finishes a rewind which failed or was interrupted by a restart.
+func (h *Handler) resumeRewind(ctx context.Context) error {
+	rewind, err := h.blockKeeper.PendingRewind()
+	if err != nil {
+		h.l.Errorw("Fail to get pending rewind", "error", err)
+
+		return err
+	}
+
+	if rewind == nil {
+		h.rewindPending = false
+
+		return nil
+	}
+
+	h.l.Warnw("Finish pending rewind", "blockHash", rewind.Target.Hash,
+		"numRevertedBlocks", len(rewind.Reverted), "published", rewind.Published)
+
+	return h.finishRewind(ctx, rewind)
+}
+
+// finishRewind publishes a message reverting the blocks of the rewind, marks the stored rewind as
+// published, moves the block keeper head back to the target block, then clears the stored rewind.
+// Each step is skipped if it was done before a failure or a restart, so the revert is not published
+// again once marked. A crash between publishing and storing the mark still publishes it twice in a
+// row, which consumers handle like any reverted block they have already dropped.
+func (h *Handler) finishRewind(ctx context.Context, rewind *block.Rewind) error {
+	log := h.l.With("blockHash", rewind.Target.Hash)
+
+	if !rewind.Published {
+		err := h.publishRevert(ctx, rewind.Reverted)
+		if err != nil {
+			log.Errorw("Fail to publish message", "error", err)
+
+			return err
+		}
+
+		published := *rewind
+		published.Published = true
+		err = h.blockKeeper.SetRewind(&published)
+		if err != nil {
+			log.Errorw("Fail to mark rewind as published", "error", err)
+
+			return err
+		}
+	}
+
+	head, err := h.blockKeeper.Head()
+	if err != nil && !errors.Is(err, errors.ErrNotFound) {
+		log.Errorw("Fail to get stored block head", "error", err)
+
+		return err
+	}
+
+	if err != nil || head.Hash != rewind.Target.Hash {
+		err = h.resetHead(rewind.Target)
+		if err != nil {
+			log.Errorw("Fail to move block keeper head back", "error", err)
+
+			return err
+		}
+	}
+
+	err = h.blockKeeper.SetRewind(nil)
+	if err != nil {
+		log.Errorw("Fail to clear rewind", "error", err)
+
+		return err
+	}
+	h.rewindPending = false
+
+	return nil
+}
+
+// resetHead rebuilds the block keeper with stored blocks up to the target block, and saves it as
+// the head. Only the target is kept if it is not in the block keeper, e.g. when blocks after it
+// expired from redis before a restart.
+func (h *Handler) resetHead(target types.Block) error {
+	blocks, err := h.blockKeeper.GetRecentBlocks(h.blockKeeper.Cap())
+	if err != nil {
+		return err
+	}
+
+	// Stored blocks are in descending order from the head.
+	kept := []types.Block{target}
+	for i, b := range blocks {
+		if b.Hash == target.Hash {
+			kept = blocks[i:]
+
+			break
+		}
+	}
+
+	err = h.blockKeeper.Reset()
+	if err != nil {
+		return err
+	}
+
+	for i := len(kept) - 1; i >= 0; i-- {
+		err = h.blockKeeper.Add(kept[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	return h.blockKeeper.Flush()
+}
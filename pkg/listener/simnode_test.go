@@ -0,0 +1,196 @@
+package listener
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/KyberNetwork/evmlistener/pkg/block"
+	"github.com/KyberNetwork/evmlistener/pkg/evmclient"
+	"github.com/KyberNetwork/evmlistener/pkg/simnode"
+	"github.com/KyberNetwork/evmlistener/pkg/types"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+)
+
+// SimNodeTestSuite runs the listener end to end against a simulated node.
+type SimNodeTestSuite struct {
+	suite.Suite
+
+	node      *simnode.Node
+	publisher *PublisherMock
+	keeper    block.Keeper
+	cancel    context.CancelFunc
+	done      chan error
+}
+
+func (ts *SimNodeTestSuite) SetupTest() {
+	var err error
+	ts.node, err = simnode.New()
+	ts.Require().NoError(err)
+	ts.Require().NoError(ts.node.Start())
+}
+
+// start runs the listener against the node.
+func (ts *SimNodeTestSuite) start(extraOpts ...Option) {
+	// Do not reuse connections, so that dropped connections do not fail http requests.
+	httpClient := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	wsClient, err := evmclient.Dial(ts.node.WSURL(), httpClient)
+	ts.Require().NoError(err)
+	httpEVMClient, err := evmclient.Dial(ts.node.HTTPURL(), httpClient)
+	ts.Require().NoError(err)
+
+	ts.publisher = NewPublisherMock(1000)
+	ts.keeper = block.NewBaseBlockKeeper(32)
+	opts := append([]Option{WithEventLogs(nil, nil)}, extraOpts...)
+	handler := NewHandler(zap.S(), "test-topic", httpEVMClient, ts.keeper, ts.publisher, opts...)
+	l := New(zap.S(), wsClient, httpEVMClient, handler, nil, 0, opts...)
+
+	var ctx context.Context
+	ctx, ts.cancel = context.WithCancel(context.Background())
+	ts.done = make(chan error, 1)
+	go func() {
+		ts.done <- l.Run(ctx)
+	}()
+	ts.waitSubscriptions(1)
+}
+
+// waitSubscriptions waits until the listener has subscribed to new heads n times.
+func (ts *SimNodeTestSuite) waitSubscriptions(n int) {
+	ts.Require().Eventually(func() bool {
+		return ts.node.Subscriptions() >= n
+	}, 10*time.Second, 10*time.Millisecond)
+}
+
+func (ts *SimNodeTestSuite) TearDownTest() {
+	if ts.cancel != nil {
+		ts.cancel()
+		select {
+		case err := <-ts.done:
+			ts.Assert().NoError(err)
+		case <-time.After(5 * time.Second):
+			ts.Fail("timeout waiting for listener to stop")
+		}
+		ts.cancel = nil
+	}
+	ts.node.Close()
+}
+
+// waitHead returns published messages until the listener reaches the head of the node.
+func (ts *SimNodeTestSuite) waitHead() []types.Message {
+	_, head := ts.node.Head()
+
+	var msgs []types.Message
+	for {
+		select {
+		case msg := <-ts.publisher.ch:
+			m, ok := msg.(types.Message)
+			ts.Require().True(ok)
+			msgs = append(msgs, m)
+			if len(m.NewBlocks) > 0 && m.NewBlocks[len(m.NewBlocks)-1].Hash == head.Hex() {
+				return msgs
+			}
+		case <-time.After(10 * time.Second):
+			ts.FailNow("timeout waiting for head", "head", head.Hex())
+		}
+	}
+}
+
+func (ts *SimNodeTestSuite) TestNewBlocks() {
+	ts.start()
+	ts.node.Mine(3)
+	ts.waitHead()
+
+	for _, number := range []uint64{64, 65, 66} {
+		hash, ok := ts.node.BlockHash(number)
+		ts.Require().True(ok)
+		b, err := ts.keeper.Get(hash.Hex())
+		ts.Require().NoError(err)
+		ts.Assert().Len(b.Logs, 2)
+	}
+}
+
+func (ts *SimNodeTestSuite) TestReorg() {
+	ts.start()
+	ts.node.Mine(3)
+	ts.waitHead()
+
+	oldHash, _ := ts.node.BlockHash(64)
+	ts.Require().NoError(ts.node.Reorg(3, 4))
+	msgs := ts.waitHead()
+
+	var reverted []types.Block
+	for _, m := range msgs {
+		reverted = append(reverted, m.RevertedBlocks...)
+	}
+	ts.Require().Len(reverted, 3)
+	ts.Assert().Equal(oldHash.Hex(), reverted[len(reverted)-1].Hash)
+
+	head, err := ts.keeper.Head()
+	ts.Require().NoError(err)
+	ts.Assert().Equal(uint64(67), head.Number.Uint64())
+}
+
+func (ts *SimNodeTestSuite) TestDropConnections() {
+	ts.start()
+	ts.node.Mine(1)
+	ts.waitHead()
+
+	// The listener subscribes again and catches up with blocks produced meanwhile.
+	ts.node.DropConnections()
+	ts.node.Mine(5)
+	ts.waitSubscriptions(2)
+	ts.node.Mine(1)
+	ts.waitHead()
+
+	for number := uint64(64); number <= 70; number++ {
+		hash, ok := ts.node.BlockHash(number)
+		ts.Require().True(ok)
+		exists, err := ts.keeper.Exists(hash.Hex())
+		ts.Require().NoError(err)
+		ts.Assert().True(exists, "block %d", number)
+	}
+}
+
+// assertPublishedFrom asserts that published blocks start from the block and have no gap.
+func (ts *SimNodeTestSuite) assertPublishedFrom(number uint64) {
+	ts.node.Mine(1)
+	var blocks []types.Block
+	for _, m := range ts.waitHead() {
+		blocks = append(blocks, m.NewBlocks...)
+	}
+
+	ts.Require().NotEmpty(blocks)
+	ts.Assert().Equal(number, blocks[0].Number.Uint64())
+	for i := 1; i < len(blocks); i++ {
+		ts.Assert().Equal(blocks[i-1].Hash, blocks[i].ParentHash)
+	}
+}
+
+func (ts *SimNodeTestSuite) TestStartBlock() {
+	ts.start(WithStartBlock(10))
+	ts.assertPublishedFrom(10)
+}
+
+func (ts *SimNodeTestSuite) TestStartBlockHash() {
+	hash, ok := ts.node.BlockHash(20)
+	ts.Require().True(ok)
+	ts.start(WithStartBlockHash(hash.Hex()))
+	ts.assertPublishedFrom(20)
+}
+
+func (ts *SimNodeTestSuite) TestStartTime() {
+	client, err := evmclient.Dial(ts.node.HTTPURL(), http.DefaultClient)
+	ts.Require().NoError(err)
+	header, err := client.HeaderByNumber(context.Background(), big.NewInt(30))
+	ts.Require().NoError(err)
+
+	ts.start(WithStartTime(header.Time))
+	ts.assertPublishedFrom(30)
+}
+
+func TestSimNodeTestSuite(t *testing.T) {
+	suite.Run(t, new(SimNodeTestSuite))
+}
@@ -0,0 +1,82 @@
+package listener
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/KyberNetwork/evmlistener/pkg/errors"
+	"github.com/KyberNetwork/evmlistener/pkg/retry"
+)
+
+// Stage is the stage of the listener in which a failure happened.
+type Stage string
+
+// Stages of the listener.
+const (
+	StageInit         Stage = "init"
+	StageSubscribe    Stage = "subscribe"
+	StageCatchUp      Stage = "catch_up"
+	StageHandleHeader Stage = "handle_header"
+	StageHandleBlock  Stage = "handle_block"
+	StageSanityCheck  Stage = "sanity_check"
+	StageShutdown     Stage = "shutdown"
+)
+
+var (
+	// ErrSanityCheckFailed means the node is behind the sanity check node.
+	ErrSanityCheckFailed = retry.WithClass(errors.New("sanity check failed"), retry.ClassConnection)
+	// ErrTooManyRestarts means the subscription failed more often than the restart policy allows.
+	ErrTooManyRestarts = errors.New("too many restarts")
+	// ErrShutdownTimeout means blocks already fetched were not all published before the shutdown timeout.
+	ErrShutdownTimeout = errors.New("shutdown timeout")
+)
+
+// Error is a failure of the listener, returned by Run when the listener stops because of it.
+type Error struct {
+	Stage Stage
+	Err   error
+}
+
+func newError(stage Stage, err error) *Error {
+	return &Error{Stage: stage, Err: err}
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %v", e.Stage, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// RestartPolicy decides whether the subscription for new heads is restarted after a transient failure,
+// e.g. a lost connection, a head whose logs can not be fetched or a failed sanity check.
+// Other failures always stop the listener.
+type RestartPolicy struct {
+	// MaxRestarts is the maximum number of restarts within Window, the listener stops with
+	// ErrTooManyRestarts when it is exceeded. Zero means no limit.
+	MaxRestarts int
+	// Window is the period over which restarts are counted.
+	Window time.Duration
+}
+
+// restartTracker counts restarts within the window of a restart policy.
+type restartTracker struct {
+	policy   RestartPolicy
+	restarts []time.Time
+}
+
+// allow records a restart at now and reports whether it is allowed.
+func (t *restartTracker) allow(now time.Time) bool {
+	if t.policy.MaxRestarts <= 0 {
+		return true
+	}
+
+	i := 0
+	for i < len(t.restarts) && now.Sub(t.restarts[i]) > t.policy.Window {
+		i++
+	}
+	t.restarts = append(t.restarts[i:], now)
+
+	return len(t.restarts) <= t.policy.MaxRestarts
+}
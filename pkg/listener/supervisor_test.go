@@ -0,0 +1,379 @@
+package listener
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/KyberNetwork/evmlistener/pkg/block"
+	"github.com/KyberNetwork/evmlistener/pkg/errors"
+	"github.com/KyberNetwork/evmlistener/pkg/evmclient"
+	"github.com/KyberNetwork/evmlistener/pkg/retry"
+	"github.com/KyberNetwork/evmlistener/pkg/simnode"
+	"github.com/KyberNetwork/evmlistener/pkg/types"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+)
+
+type SupervisorTestSuite struct {
+	suite.Suite
+
+	node *simnode.Node
+}
+
+func (ts *SupervisorTestSuite) SetupTest() {
+	var err error
+	ts.node, err = simnode.New()
+	ts.Require().NoError(err)
+	ts.Require().NoError(ts.node.Start())
+}
+
+func (ts *SupervisorTestSuite) TearDownTest() {
+	ts.node.Close()
+}
+
+func (ts *SupervisorTestSuite) TestRestartTracker() {
+	tracker := &restartTracker{policy: RestartPolicy{MaxRestarts: 2, Window: time.Minute}}
+	now := time.Now()
+	ts.Assert().True(tracker.allow(now))
+	ts.Assert().True(tracker.allow(now.Add(time.Second)))
+	ts.Assert().False(tracker.allow(now.Add(2 * time.Second)))
+
+	// Restarts out of the window are forgotten.
+	ts.Assert().True(tracker.allow(now.Add(2 * time.Minute)))
+
+	unlimited := &restartTracker{}
+	for range 100 {
+		ts.Assert().True(unlimited.allow(now))
+	}
+}
+
+func (ts *SupervisorTestSuite) TestError() {
+	err := newError(StageSanityCheck, ErrSanityCheckFailed)
+	ts.Assert().ErrorIs(err, ErrSanityCheckFailed)
+	ts.Assert().True(retry.Classify(err).Retryable())
+	ts.Assert().Equal("sanity_check: sanity check failed", err.Error())
+}
+
+// newListener returns a listener connected to the node.
+func (ts *SupervisorTestSuite) newListener(extraOpts ...Option) (*Listener, *PublisherMock, block.Keeper) {
+	httpClient := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	wsClient, err := evmclient.Dial(ts.node.WSURL(), httpClient)
+	ts.Require().NoError(err)
+	httpEVMClient, err := evmclient.Dial(ts.node.HTTPURL(), httpClient)
+	ts.Require().NoError(err)
+
+	publisher := NewPublisherMock(1000)
+	keeper := block.NewBaseBlockKeeper(32)
+	opts := append([]Option{WithEventLogs(nil, nil)}, extraOpts...)
+	handler := NewHandler(zap.S(), "test-topic", httpEVMClient, keeper, publisher, opts...)
+
+	return New(zap.S(), wsClient, httpEVMClient, handler, nil, 0, opts...), publisher, keeper
+}
+
+func (ts *SupervisorTestSuite) TestTooManyRestarts() {
+	l, _, _ := ts.newListener(
+		WithRetryPolicy(retry.Policy{MaxAttempts: 2, InitialInterval: time.Millisecond, MaxInterval: time.Millisecond}),
+		WithRestartPolicy(RestartPolicy{MaxRestarts: 1, Window: time.Minute}),
+	)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- l.Run(context.Background())
+	}()
+
+	// Logs of new blocks can not be fetched, the subscription is restarted once then the listener stops.
+	ts.node.SetLogDelay(time.Hour)
+	var err error
+	for i := 1; ; i++ {
+		ts.Require().Eventually(func() bool {
+			return ts.node.Subscriptions() >= i
+		}, 10*time.Second, 10*time.Millisecond)
+		ts.node.Mine(1)
+
+		select {
+		case err = <-done:
+		case <-time.After(time.Second):
+			ts.Require().Less(i, 5, "listener did not stop")
+
+			continue
+		}
+
+		break
+	}
+
+	ts.Assert().ErrorIs(err, ErrTooManyRestarts)
+	ts.Assert().ErrorIs(err, errMissingLogs)
+	var listenerErr *Error
+	ts.Require().True(errors.As(err, &listenerErr))
+	ts.Assert().Equal(StageHandleHeader, listenerErr.Stage)
+	ts.Assert().Equal(2, ts.node.Subscriptions())
+}
+
+// runUntilHandlingHead runs the listener, and cancels it while a new head is being handled.
+func (ts *SupervisorTestSuite) runUntilHandlingHead(l *Listener, logDelay time.Duration) chan error {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- l.Run(ctx)
+	}()
+
+	ts.Require().Eventually(func() bool {
+		return ts.node.Subscriptions() >= 1
+	}, 10*time.Second, 10*time.Millisecond)
+	ts.node.SetLogDelay(logDelay)
+	ts.node.Mine(1)
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	return done
+}
+
+func (ts *SupervisorTestSuite) TestGracefulShutdown() {
+	l, publisher, keeper := ts.newListener(
+		WithRetryPolicy(retry.Policy{InitialInterval: 20 * time.Millisecond, MaxInterval: 20 * time.Millisecond}),
+		WithLogRetry(100, true),
+	)
+
+	// The head being handled when the listener is stopped is still published.
+	done := ts.runUntilHandlingHead(l, 500*time.Millisecond)
+	select {
+	case err := <-done:
+		ts.Require().NoError(err)
+	case <-time.After(10 * time.Second):
+		ts.FailNow("timeout waiting for listener to stop")
+	}
+
+	_, hash := ts.node.Head()
+	ts.Require().Len(publisher.ch, 1)
+	msg, ok := (<-publisher.ch).(types.Message)
+	ts.Require().True(ok)
+	ts.Require().Len(msg.NewBlocks, 1)
+	ts.Assert().Equal(hash.Hex(), msg.NewBlocks[0].Hash)
+
+	head, err := keeper.Head()
+	ts.Require().NoError(err)
+	ts.Assert().Equal(hash.Hex(), head.Hash)
+}
+
+func (ts *SupervisorTestSuite) TestShutdownTimeout() {
+	l, publisher, _ := ts.newListener(
+		WithRetryPolicy(retry.Policy{InitialInterval: 20 * time.Millisecond, MaxInterval: 20 * time.Millisecond}),
+		WithLogRetry(100, true),
+		WithShutdownTimeout(200*time.Millisecond),
+	)
+
+	done := ts.runUntilHandlingHead(l, time.Hour)
+	select {
+	case err := <-done:
+		ts.Assert().ErrorIs(err, ErrShutdownTimeout)
+	case <-time.After(10 * time.Second):
+		ts.FailNow("timeout waiting for listener to stop")
+	}
+	ts.Assert().Empty(publisher.ch)
+}
+
+func (ts *SupervisorTestSuite) TestReleaseLease() {
+	lease := &leaseMock{token: 1}
+	l, _, _ := ts.newListener(WithLeaderElection(lease))
+
+	// The lease is held while the listener runs, and released once it stops.
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- l.Run(ctx)
+	}()
+	ts.Require().Eventually(func() bool {
+		return ts.node.Subscriptions() >= 1
+	}, 10*time.Second, 10*time.Millisecond)
+	ts.Assert().Equal(int64(1), lease.Token())
+
+	cancel()
+	select {
+	case err := <-done:
+		ts.Require().NoError(err)
+	case <-time.After(10 * time.Second):
+		ts.FailNow("timeout waiting for listener to stop")
+	}
+	ts.Assert().Zero(lease.Token())
+}
+
+func (ts *SupervisorTestSuite) TestHealth() {
+	l, _, _ := ts.newListener(WithReadinessPolicy(ReadinessPolicy{MaxIdle: 500 * time.Millisecond}))
+	ts.Assert().ErrorIs(l.Healthy(), ErrNotRunning)
+	ts.Assert().ErrorIs(l.Ready(), ErrNotRunning)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- l.Run(ctx)
+	}()
+	ts.Require().Eventually(func() bool {
+		return ts.node.Subscriptions() >= 1
+	}, 10*time.Second, 10*time.Millisecond)
+	ts.Assert().NoError(l.Healthy())
+	ts.Assert().ErrorIs(l.Ready(), ErrNotReady)
+
+	ts.node.Mine(1)
+	ts.Require().Eventually(func() bool {
+		return l.Ready() == nil
+	}, 10*time.Second, 10*time.Millisecond)
+
+	// The listener is no longer ready without new blocks.
+	ts.Require().Eventually(func() bool {
+		return errors.Is(l.Ready(), ErrNotReady)
+	}, 10*time.Second, 10*time.Millisecond)
+	ts.Assert().NoError(l.Healthy())
+
+	cancel()
+	select {
+	case err := <-done:
+		ts.Require().NoError(err)
+	case <-time.After(10 * time.Second):
+		ts.FailNow("timeout waiting for listener to stop")
+	}
+	ts.Assert().ErrorIs(l.Healthy(), ErrNotRunning)
+}
+
+func (ts *SupervisorTestSuite) TestPause() {
+	l, publisher, _ := ts.newListener()
+	ts.Assert().ErrorIs(l.Resync(context.Background(), 0), ErrNotRunning)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- l.Run(ctx)
+	}()
+	ts.Require().Eventually(func() bool {
+		return ts.node.Subscriptions() >= 1
+	}, 10*time.Second, 10*time.Millisecond)
+
+	// Blocks are held while paused.
+	l.Pause()
+	ts.node.Mine(1)
+	ts.Require().Eventually(func() bool {
+		return l.Status().PendingBlocks == 1
+	}, 10*time.Second, 10*time.Millisecond)
+	ts.Assert().Empty(publisher.ch)
+	ts.Assert().True(l.Status().Paused)
+	ts.Assert().ErrorIs(l.Ready(), ErrPaused)
+	ts.Assert().ErrorIs(l.Resync(context.Background(), 0), ErrPaused)
+
+	l.Resume()
+	ts.Require().Eventually(func() bool {
+		return len(publisher.ch) == 1
+	}, 10*time.Second, 10*time.Millisecond)
+	<-publisher.ch
+
+	head, hash := ts.node.Head()
+	ts.Require().NoError(l.Resync(context.Background(), head))
+	ts.Require().Len(publisher.ch, 1)
+	msg, ok := (<-publisher.ch).(types.Message)
+	ts.Require().True(ok)
+	ts.Require().NotNil(msg.Resync)
+	ts.Require().Len(msg.NewBlocks, 1)
+	ts.Assert().Equal(hash.Hex(), msg.NewBlocks[0].Hash)
+
+	cancel()
+	select {
+	case err := <-done:
+		ts.Require().NoError(err)
+	case <-time.After(10 * time.Second):
+		ts.FailNow("timeout waiting for listener to stop")
+	}
+}
+
+func (ts *SupervisorTestSuite) TestLongPause() {
+	l, _, keeper := ts.newListener()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- l.Run(ctx)
+	}()
+	ts.Require().Eventually(func() bool {
+		return ts.node.Subscriptions() >= 1
+	}, 10*time.Second, 10*time.Millisecond)
+
+	// The subscription stops once held blocks fill the buffer.
+	l.Pause()
+	ts.Require().Eventually(func() bool {
+		if status := l.Status(); status.PendingBlocks == bufLen && status.Resuming {
+			return true
+		}
+		ts.node.Mine(1)
+
+		return false
+	}, 20*time.Second, 5*time.Millisecond)
+
+	// Blocks which were not held are fetched when catching up after resume.
+	l.Resume()
+	ts.Require().Eventually(func() bool {
+		return ts.node.Subscriptions() >= 2
+	}, 10*time.Second, 10*time.Millisecond)
+	ts.node.Mine(1)
+	head, hash := ts.node.Head()
+	ts.Require().Eventually(func() bool {
+		b, err := keeper.Head()
+
+		return err == nil && b.Number.Uint64() == head
+	}, 10*time.Second, 10*time.Millisecond)
+	b, err := keeper.Head()
+	ts.Require().NoError(err)
+	ts.Assert().Equal(hash.Hex(), b.Hash)
+
+	cancel()
+	select {
+	case err := <-done:
+		ts.Require().NoError(err)
+	case <-time.After(10 * time.Second):
+		ts.FailNow("timeout waiting for listener to stop")
+	}
+}
+
+func (ts *SupervisorTestSuite) TestGroup() {
+	l, publisher, _ := ts.newListener()
+	var attempts atomic.Int32
+	group := NewGroup(zap.S(), retry.Policy{InitialInterval: 10 * time.Millisecond, MaxInterval: 10 * time.Millisecond})
+	group.Add("failing", func() (*Listener, error) {
+		attempts.Add(1)
+
+		return nil, errors.New("no rpc")
+	})
+	group.Add("healthy", func() (*Listener, error) {
+		return l, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- group.Run(ctx)
+	}()
+
+	// A chain which can not be set up is retried without stopping the others.
+	ts.Require().Eventually(func() bool {
+		return ts.node.Subscriptions() >= 1
+	}, 10*time.Second, 10*time.Millisecond)
+	ts.node.Mine(1)
+	ts.Require().Eventually(func() bool {
+		return len(publisher.ch) == 1
+	}, 10*time.Second, 10*time.Millisecond)
+	ts.Assert().Greater(attempts.Load(), int32(1))
+	ts.Assert().NoError(group.Healthy())
+	ts.Assert().ErrorIs(group.Ready(), ErrNotRunning)
+
+	cancel()
+	select {
+	case err := <-done:
+		ts.Assert().NoError(err)
+	case <-time.After(10 * time.Second):
+		ts.FailNow("timeout waiting for group to stop")
+	}
+	ts.Assert().ErrorIs(group.Healthy(), ErrNotRunning)
+}
+
+func TestSupervisorTestSuite(t *testing.T) {
+	suite.Run(t, new(SupervisorTestSuite))
+}
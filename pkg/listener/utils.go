@@ -2,54 +2,73 @@ package listener
 
 import (
 	"context"
+	"fmt"
 	"math/big"
-	"time"
 
 	"github.com/KyberNetwork/evmlistener/pkg/errors"
 	"github.com/KyberNetwork/evmlistener/pkg/evmclient"
+	"github.com/KyberNetwork/evmlistener/pkg/retry"
 	"github.com/KyberNetwork/evmlistener/pkg/types"
-	"github.com/ethereum/go-ethereum"
+	pkgtracer "github.com/KyberNetwork/kyber-trace-go/pkg/tracer"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-const (
-	errStringUnknownBlock = "unknown block"
+var errEmptyLogs = retry.WithClass(errors.New("empty logs"), retry.ClassNotFound)
 
-	defaultRetryInterval = 500 * time.Millisecond
-)
-
-// getLogsByBlockHash returns logs by block hash, retry up to 3 times.
-func getLogsByBlockHash(ctx context.Context, evmClient evmclient.IClient, hash string,
-	contracts []string, topics [][]string,
+// getLogsByBlockHash returns logs of the block, retry up to the configured number of attempts.
+// Logs are verified against the header, an attempt returning incomplete logs is retried on another endpoint.
+func getLogsByBlockHash(ctx context.Context, evmClient evmclient.IClient, header *types.Header,
+	opts *FilterOption,
 ) (logs []types.Log, err error) {
-	for range 5 {
+	ctx = evmclient.WithEndpointTracking(ctx)
+	policy := opts.retry().WithMaxAttempts(opts.logAttempts())
+	err = policy.Do(ctx, "FilterLogs", func(ctx context.Context) error {
 		logs, err = evmClient.FilterLogs(ctx, evmclient.FilterQuery{
-			BlockHash: &hash,
-			Addresses: contracts,
-			Topics:    topics,
+			BlockHash: &header.Hash,
+			Addresses: opts.filterContracts,
+			Topics:    opts.filterTopics,
 		})
-		if err == nil {
-			if len(logs) == 0 {
-				continue
-			}
-
-			return logs, nil
+		if err != nil {
+			return err
 		}
 
-		if !errors.Is(err, ethereum.NotFound) && err.Error() != errStringUnknownBlock {
-			return nil, err
+		if err := verifyLogs(ctx, evmClient, header, logs, opts); err != nil {
+			evmclient.ExcludeServedEndpoints(ctx)
+
+			return err
 		}
 
-		time.Sleep(defaultRetryInterval)
+		return nil
+	})
+	if errors.Is(err, errEmptyLogs) {
+		// The block may have no logs indeed.
+		return logs, nil
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	return logs, err
+	return logs, nil
 }
 
 func GetBlocks(ctx context.Context, evmClient evmclient.IClient, fromBlock uint64, toBlock uint64,
 	withLogs bool, contracts []string, topics [][]string,
+) ([]types.Block, error) {
+	var opts FilterOption
+	if withLogs {
+		WithEventLogs(contracts, topics)(&opts)
+	}
+
+	return fetchBlocks(ctx, evmClient, fromBlock, toBlock, &opts)
+}
+
+// fetchBlocks returns blocks in range [fromBlock, toBlock] by walking back parent hashes from toBlock.
+func fetchBlocks(ctx context.Context, evmClient evmclient.IClient, fromBlock uint64, toBlock uint64,
+	opts *FilterOption,
 ) ([]types.Block, error) {
 	// Get latest block by number.
-	b, err := getBlockByNumber(ctx, evmClient, new(big.Int).SetUint64(toBlock), withLogs, contracts, topics)
+	b, err := getBlockByNumber(ctx, evmClient, new(big.Int).SetUint64(toBlock), opts)
 	if err != nil {
 		return nil, err
 	}
@@ -61,7 +80,7 @@ func GetBlocks(ctx context.Context, evmClient evmclient.IClient, fromBlock uint6
 
 	hash := b.ParentHash
 	for i := n - 2; i >= 0; i-- {
-		b, err = getBlockByHash(ctx, evmClient, hash, withLogs, contracts, topics)
+		b, err = getBlockByHash(ctx, evmClient, hash, opts)
 		if err != nil {
 			return nil, err
 		}
@@ -73,34 +92,33 @@ func GetBlocks(ctx context.Context, evmClient evmclient.IClient, fromBlock uint6
 }
 
 func getHeaderByHash(
-	ctx context.Context, evmClient evmclient.IClient, hash string,
+	ctx context.Context, evmClient evmclient.IClient, hash string, opts *FilterOption,
 ) (header *types.Header, err error) {
-	for range 5 {
+	err = opts.retry().Do(ctx, "HeaderByHash", func(ctx context.Context) error {
 		header, err = evmClient.HeaderByHash(ctx, hash)
-		if err == nil {
-			return header, nil
-		}
-
-		if !errors.Is(err, ethereum.NotFound) && err.Error() != errStringUnknownBlock {
-			return nil, err
-		}
 
-		time.Sleep(defaultRetryInterval)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, err
+	return header, nil
 }
 
-func getBlockByHash(ctx context.Context, evmClient evmclient.IClient, hash string, withLogs bool,
-	contracts []string, topics [][]string,
+func getBlockByHash(ctx context.Context, evmClient evmclient.IClient, hash string, opts *FilterOption,
 ) (types.Block, error) {
-	header, err := getHeaderByHash(ctx, evmClient, hash)
+	ctx, span := pkgtracer.Tracer().Start(ctx, "listener.getBlockByHash",
+		trace.WithAttributes(attribute.String("hash", hash)))
+	defer span.End()
+
+	header, err := getHeaderByHash(ctx, evmClient, hash, opts)
 	if err != nil {
 		return types.Block{}, err
 	}
 	var logs []types.Log
-	if withLogs {
-		logs, err = getLogsByBlockHash(ctx, evmClient, hash, contracts, topics)
+	if opts.withLogs {
+		logs, err = getLogsByBlockHash(ctx, evmClient, header, opts)
 		if err != nil {
 			return types.Block{}, err
 		}
@@ -110,34 +128,33 @@ func getBlockByHash(ctx context.Context, evmClient evmclient.IClient, hash strin
 }
 
 func getHeaderByNumber(
-	ctx context.Context, evmClient evmclient.IClient, num *big.Int,
+	ctx context.Context, evmClient evmclient.IClient, num *big.Int, opts *FilterOption,
 ) (header *types.Header, err error) {
-	for range 3 {
+	err = opts.retry().Do(ctx, "HeaderByNumber", func(ctx context.Context) error {
 		header, err = evmClient.HeaderByNumber(ctx, num)
-		if err == nil {
-			return header, nil
-		}
-
-		if errors.Is(err, ethereum.NotFound) && err.Error() != errStringUnknownBlock {
-			return nil, err
-		}
 
-		time.Sleep(defaultRetryInterval)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, err
+	return header, nil
 }
 
-func getBlockByNumber(ctx context.Context, evmClient evmclient.IClient, num *big.Int,
-	withLogs bool, contracts []string, topics [][]string,
+func getBlockByNumber(ctx context.Context, evmClient evmclient.IClient, num *big.Int, opts *FilterOption,
 ) (types.Block, error) {
-	header, err := getHeaderByNumber(ctx, evmClient, num)
+	ctx, span := pkgtracer.Tracer().Start(ctx, "listener.getBlockByNumber",
+		trace.WithAttributes(attribute.String("number", num.String())))
+	defer span.End()
+
+	header, err := getHeaderByNumber(ctx, evmClient, num, opts)
 	if err != nil {
 		return types.Block{}, err
 	}
 	var logs []types.Log
-	if withLogs {
-		logs, err = getLogsByBlockHash(ctx, evmClient, header.Hash, contracts, topics)
+	if opts.withLogs {
+		logs, err = getLogsByBlockHash(ctx, evmClient, header, opts)
 		if err != nil {
 			return types.Block{}, err
 		}
@@ -146,6 +163,18 @@ func getBlockByNumber(ctx context.Context, evmClient evmclient.IClient, num *big
 	return headerToBlock(header, logs), nil
 }
 
+// checkHeaders checks that the headers are consecutive blocks of the same chain.
+func checkHeaders(headers []*types.Header) error {
+	for i := 1; i < len(headers); i++ {
+		if headers[i].ParentHash != headers[i-1].Hash {
+			return fmt.Errorf("%w: parent of block %v is %s, expect %s", errInconsistentBlocks,
+				headers[i].Number, headers[i].ParentHash, headers[i-1].Hash)
+		}
+	}
+
+	return nil
+}
+
 func headerToBlock(header *types.Header, logs []types.Log) types.Block {
 	return types.Block{
 		Hash:       header.Hash,
@@ -155,3 +184,34 @@ func headerToBlock(header *types.Header, logs []types.Log) types.Block {
 		Logs:       logs,
 	}
 }
+
+// FindBlockByTimestamp returns number of the first block whose timestamp is not before ts,
+// using binary search over block headers. It returns errors.ErrNotFound if all blocks are before ts.
+func FindBlockByTimestamp(ctx context.Context, evmClient evmclient.IClient, ts uint64) (uint64, error) {
+	head, err := evmClient.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	if head.Time < ts {
+		return 0, fmt.Errorf("%w: no block at or after timestamp %d, head is at %d",
+			errors.ErrNotFound, ts, head.Time)
+	}
+
+	lo, hi := uint64(0), head.Number.Uint64()
+	for lo < hi {
+		mid := lo + (hi-lo)/2 //nolint:gomnd
+		header, err := evmClient.HeaderByNumber(ctx, new(big.Int).SetUint64(mid))
+		if err != nil {
+			return 0, err
+		}
+
+		if header.Time < ts {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+
+	return lo, nil
+}
@@ -0,0 +1,194 @@
+package listener
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/KyberNetwork/evmlistener/pkg/errors"
+	"github.com/KyberNetwork/evmlistener/pkg/evmclient"
+	"github.com/KyberNetwork/evmlistener/pkg/retry"
+	"github.com/KyberNetwork/evmlistener/pkg/types"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// LogVerification is the mode of verifying logs of a block before publishing them.
+type LogVerification string
+
+const (
+	// LogVerificationNone publishes logs returned by the node as is.
+	LogVerificationNone LogVerification = "none"
+	// LogVerificationBloom checks logs against logsBloom of the block header.
+	LogVerificationBloom LogVerification = "bloom"
+	// LogVerificationReceipts checks logs against logsBloom, and against logs of the block receipts
+	// which are verified against receiptsRoot of the block header.
+	LogVerificationReceipts LogVerification = "receipts"
+)
+
+// ParseLogVerification parses a log verification mode.
+func ParseLogVerification(s string) (LogVerification, error) {
+	switch v := LogVerification(strings.ToLower(s)); v {
+	case LogVerificationNone, LogVerificationBloom, LogVerificationReceipts:
+		return v, nil
+	default:
+		return "", fmt.Errorf("%w: unknown log verification mode %q", errors.ErrInvalidArgument, s)
+	}
+}
+
+var errMissingLogs = errors.New("missing logs")
+
+// missingLogs returns an error for logs which the node has not returned (yet), it is retried
+// as the logs may be served by another endpoint or later.
+func missingLogs(format string, args ...interface{}) error {
+	return retry.WithClass(fmt.Errorf("%w: "+format, append([]interface{}{errMissingLogs}, args...)...),
+		retry.ClassNotFound)
+}
+
+// verifyLogs checks that logs returned for the block are complete.
+func verifyLogs(ctx context.Context, evmClient evmclient.IClient, header *types.Header, logs []types.Log,
+	opts *FilterOption,
+) error {
+	if err := checkLogs(header, logs, opts); err != nil {
+		return err
+	}
+
+	if opts.verification() != LogVerificationReceipts {
+		return nil
+	}
+
+	return checkReceiptLogs(ctx, evmClient, header, logs, opts)
+}
+
+// checkLogs checks logs of the block against its header, without any request to the node.
+func checkLogs(header *types.Header, logs []types.Log, opts *FilterOption) error {
+	for _, log := range logs {
+		if log.BlockHash != header.Hash {
+			return missingLogs("log of block %v has hash %s, expect %s", header.Number, log.BlockHash, header.Hash)
+		}
+	}
+
+	if len(logs) > 0 {
+		return nil
+	}
+
+	if opts.verification() == LogVerificationNone || len(header.LogsBloom) != ethtypes.BloomByteLength {
+		// Node may return empty logs for a block it has not fully processed.
+		return emptyLogs(opts)
+	}
+
+	bloom := ethtypes.BytesToBloom(header.LogsBloom)
+	if !bloomMatches(bloom, opts.filterContracts, opts.filterTopics) {
+		return nil
+	}
+
+	// The bloom of a block is empty if and only if the block has no logs, so any logs are missing.
+	// A bloom matching a filter may be a false positive, the logs are only suspicious.
+	if len(opts.filterContracts) == 0 && len(opts.filterTopics) == 0 {
+		return missingLogs("block %v has logs in bloom but none was returned", header.Number)
+	}
+
+	return emptyLogs(opts)
+}
+
+// emptyLogs returns errEmptyLogs if suspicious empty logs should be retried. With receipts
+// verification they are not, as receipts tell whether logs are missing.
+func emptyLogs(opts *FilterOption) error {
+	if opts.noRetryOnEmpty || opts.verification() == LogVerificationReceipts {
+		return nil
+	}
+
+	return errEmptyLogs
+}
+
+// checkReceiptLogs checks that no log of the block receipts matching the filter is missing.
+func checkReceiptLogs(ctx context.Context, evmClient evmclient.IClient, header *types.Header,
+	logs []types.Log, opts *FilterOption,
+) error {
+	// Logs are not accepted unchecked, the client is validated for receipts support at setup.
+	receiptsClient, ok := evmClient.(evmclient.ReceiptsClient)
+	if !ok {
+		return evmclient.ErrReceiptsNotSupported
+	}
+
+	// A receipts root mismatch is not retried as missing logs, the node keeps serving the same receipts.
+	receiptLogs, err := receiptsClient.BlockReceiptLogs(ctx, header.Hash, header.ReceiptsRoot)
+	if err != nil {
+		return err
+	}
+
+	var expected int
+	for _, log := range receiptLogs {
+		if matchLog(log, opts.filterContracts, opts.filterTopics) {
+			expected++
+		}
+	}
+
+	if len(logs) < expected {
+		return missingLogs("block %v has %d logs in receipts, got %d", header.Number, expected, len(logs))
+	}
+
+	return nil
+}
+
+// bloomMatches reports whether the bloom may contain logs matching given filter.
+func bloomMatches(bloom ethtypes.Bloom, contracts []string, topics [][]string) bool {
+	if bloom == (ethtypes.Bloom{}) {
+		return false
+	}
+
+	if len(contracts) > 0 && !anyInBloom(bloom, contracts, func(s string) []byte {
+		return ethcommon.HexToAddress(s).Bytes()
+	}) {
+		return false
+	}
+
+	for _, ts := range topics {
+		if len(ts) > 0 && !anyInBloom(bloom, ts, func(s string) []byte {
+			return ethcommon.HexToHash(s).Bytes()
+		}) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func anyInBloom(bloom ethtypes.Bloom, values []string, toBytes func(string) []byte) bool {
+	for _, v := range values {
+		if bloom.Test(toBytes(v)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchLog reports whether the log matches given filter, with the semantic of eth_getLogs.
+func matchLog(log types.Log, contracts []string, topics [][]string) bool {
+	if len(contracts) > 0 && !containsFold(contracts, log.Address) {
+		return false
+	}
+
+	if len(topics) > len(log.Topics) {
+		return false
+	}
+
+	for i, ts := range topics {
+		if len(ts) > 0 && !containsFold(ts, log.Topics[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+
+	return false
+}
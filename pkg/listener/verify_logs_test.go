@@ -0,0 +1,161 @@
+package listener
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/KyberNetwork/evmlistener/pkg/chain"
+	"github.com/KyberNetwork/evmlistener/pkg/evmclient"
+	"github.com/KyberNetwork/evmlistener/pkg/retry"
+	"github.com/KyberNetwork/evmlistener/pkg/simnode"
+	"github.com/KyberNetwork/evmlistener/pkg/types"
+	"github.com/stretchr/testify/suite"
+)
+
+const simNodeChainID = 1337
+
+type VerifyLogsTestSuite struct {
+	suite.Suite
+
+	node   *simnode.Node
+	client *evmclient.Client
+	opts   *FilterOption
+}
+
+func (ts *VerifyLogsTestSuite) SetupTest() {
+	var err error
+	ts.node, err = simnode.New(simnode.WithChainID(simNodeChainID), simnode.WithInitialBlocks(8))
+	ts.Require().NoError(err)
+	ts.Require().NoError(ts.node.Start())
+
+	profile := chain.DefaultProfile(simNodeChainID)
+	profile.Methods.GetBlockReceipts = true
+	registry, err := chain.NewRegistry(profile)
+	ts.Require().NoError(err)
+
+	ts.client, err = evmclient.Dial(ts.node.HTTPURL(), http.DefaultClient, evmclient.WithChainRegistry(registry))
+	ts.Require().NoError(err)
+
+	ts.opts = newFilterOption(WithEventLogs(nil, nil), WithRetryPolicy(retry.Policy{
+		MaxAttempts:     3,
+		InitialInterval: 10 * time.Millisecond,
+	}))
+}
+
+func (ts *VerifyLogsTestSuite) TearDownTest() {
+	ts.node.Close()
+}
+
+func (ts *VerifyLogsTestSuite) header() *types.Header {
+	number, _ := ts.node.Head()
+	header, err := ts.client.HeaderByNumber(context.Background(), new(big.Int).SetUint64(number))
+	ts.Require().NoError(err)
+	ts.Require().NotEmpty(header.LogsBloom)
+
+	return header
+}
+
+func (ts *VerifyLogsTestSuite) TestMissingLogs() {
+	ts.node.SetLogDelay(time.Hour)
+	ts.node.Mine(1)
+	header := ts.header()
+
+	ts.Assert().ErrorIs(checkLogs(header, nil, ts.opts), errMissingLogs)
+
+	_, err := getLogsByBlockHash(context.Background(), ts.client, header, ts.opts)
+	ts.Assert().ErrorIs(err, errMissingLogs)
+}
+
+func (ts *VerifyLogsTestSuite) TestDelayedLogs() {
+	ts.node.SetLogDelay(200 * time.Millisecond)
+	ts.node.Mine(1)
+	header := ts.header()
+
+	opts := newFilterOption(WithEventLogs(nil, nil), WithRetryPolicy(retry.Policy{
+		MaxAttempts:     20,
+		InitialInterval: 50 * time.Millisecond,
+	}))
+	logs, err := getLogsByBlockHash(context.Background(), ts.client, header, opts)
+	ts.Require().NoError(err)
+	ts.Assert().Len(logs, 2)
+}
+
+func (ts *VerifyLogsTestSuite) TestFilteredBloom() {
+	header := ts.header()
+
+	// Bloom of the block does not contain the contract, so empty logs are expected.
+	opts := newFilterOption(WithEventLogs([]string{"0x0000000000000000000000000000000000000002"}, nil))
+	ts.Assert().NoError(checkLogs(header, nil, opts))
+
+	// Bloom of the block contains the contract, empty logs are suspicious.
+	opts = newFilterOption(WithEventLogs([]string{"0x0000000000000000000000000000000000000001"}, nil))
+	ts.Assert().ErrorIs(checkLogs(header, nil, opts), errEmptyLogs)
+}
+
+func (ts *VerifyLogsTestSuite) TestReceipts() {
+	header := ts.header()
+	logs, err := ts.client.FilterLogs(context.Background(), evmclient.FilterQuery{BlockHash: &header.Hash})
+	ts.Require().NoError(err)
+	ts.Require().Len(logs, 2)
+
+	WithLogVerification(LogVerificationReceipts)(ts.opts)
+	ts.Assert().NoError(verifyLogs(context.Background(), ts.client, header, logs, ts.opts))
+
+	// Logs bloom can not tell a log is missing, but receipts can.
+	ts.Assert().NoError(checkLogs(header, logs[:1], ts.opts))
+	ts.Assert().ErrorIs(verifyLogs(context.Background(), ts.client, header, logs[:1], ts.opts), errMissingLogs)
+
+	_, err = ts.client.BlockReceiptLogs(context.Background(), header.Hash, header.ParentHash)
+	ts.Assert().ErrorIs(err, evmclient.ErrReceiptsRootMismatch)
+
+	// Receipts not matching the root are not retried as missing logs.
+	mismatched := *header
+	mismatched.ReceiptsRoot = header.ParentHash
+	err = verifyLogs(context.Background(), ts.client, &mismatched, logs, ts.opts)
+	ts.Assert().ErrorIs(err, evmclient.ErrReceiptsRootMismatch)
+	ts.Assert().NotErrorIs(err, errMissingLogs)
+	ts.Assert().False(retry.Classify(err).Retryable())
+
+	// Logs are not accepted unchecked from a client which can not serve receipts.
+	noReceiptsClient := struct{ evmclient.IClient }{ts.client}
+	ts.Assert().ErrorIs(verifyLogs(context.Background(), noReceiptsClient, header, logs, ts.opts),
+		evmclient.ErrReceiptsNotSupported)
+}
+
+func (ts *VerifyLogsTestSuite) TestFilteredReceipts() {
+	ts.node.SetLogDelay(time.Hour)
+	ts.node.Mine(1)
+	header := ts.header()
+
+	// Bloom of the block contains the contract, receipts tell the empty logs are missing.
+	opts := newFilterOption(WithEventLogs([]string{"0x0000000000000000000000000000000000000001"}, nil),
+		WithLogVerification(LogVerificationReceipts), WithRetryPolicy(retry.Policy{
+			MaxAttempts:     3,
+			InitialInterval: 10 * time.Millisecond,
+		}))
+	ts.Assert().NoError(checkLogs(header, nil, opts))
+	ts.Assert().ErrorIs(verifyLogs(context.Background(), ts.client, header, nil, opts), errMissingLogs)
+
+	_, err := getLogsByBlockHash(context.Background(), ts.client, header, opts)
+	ts.Assert().ErrorIs(err, errMissingLogs)
+}
+
+func (ts *VerifyLogsTestSuite) TestMatchLog() {
+	log := types.Log{
+		Address: "0x0000000000000000000000000000000000000001",
+		Topics:  []string{"0xaa", "0xbb"},
+	}
+
+	ts.Assert().True(matchLog(log, nil, nil))
+	ts.Assert().True(matchLog(log, []string{"0x0000000000000000000000000000000000000001"}, [][]string{nil, {"0xBB"}}))
+	ts.Assert().False(matchLog(log, []string{"0x0000000000000000000000000000000000000002"}, nil))
+	ts.Assert().False(matchLog(log, nil, [][]string{{"0xbb"}}))
+	ts.Assert().False(matchLog(log, nil, [][]string{nil, nil, {"0xcc"}}))
+}
+
+func TestVerifyLogsTestSuite(t *testing.T) {
+	suite.Run(t, new(VerifyLogsTestSuite))
+}
@@ -0,0 +1,41 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/KyberNetwork/evmlistener/pkg/errors"
+)
+
+// Checkpoint stores progress of a backfill as the next block number under a key.
+type Checkpoint struct {
+	key string
+
+	client *Client
+}
+
+// NewCheckpoint returns a new Checkpoint object.
+func NewCheckpoint(client *Client, key string) *Checkpoint {
+	return &Checkpoint{
+		key:    key,
+		client: client,
+	}
+}
+
+// Load returns the saved next block number, ok is false if nothing was saved.
+func (c *Checkpoint) Load(ctx context.Context) (uint64, bool, error) {
+	var next uint64
+	err := c.client.Get(ctx, c.key, &next)
+	if errors.Is(err, errors.ErrNotFound) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	return next, true, nil
+}
+
+// Save saves the next block number.
+func (c *Checkpoint) Save(ctx context.Context, next uint64) error {
+	return c.client.Set(ctx, c.key, next, 0)
+}
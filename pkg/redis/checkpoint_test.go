@@ -0,0 +1,45 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type CheckpointTestSuite struct {
+	suite.Suite
+
+	c *Checkpoint
+}
+
+func (ts *CheckpointTestSuite) SetupTest() {
+	client, err := New(Config{
+		Addrs:     []string{":6379"},
+		KeyPrefix: "test:",
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	ts.c = NewCheckpoint(client, fmt.Sprintf("test-checkpoint-%d", rand.Int())) // nolint
+}
+
+func (ts *CheckpointTestSuite) TestSaveLoad() {
+	_, ok, err := ts.c.Load(context.Background())
+	ts.Require().NoError(err)
+	ts.Assert().False(ok)
+
+	ts.Require().NoError(ts.c.Save(context.Background(), 100))
+
+	next, ok, err := ts.c.Load(context.Background())
+	ts.Require().NoError(err)
+	ts.Assert().True(ok)
+	ts.Assert().Equal(uint64(100), next)
+}
+
+func TestCheckpointTestSuite(t *testing.T) {
+	suite.Run(t, new(CheckpointTestSuite))
+}
@@ -0,0 +1,179 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KyberNetwork/evmlistener/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+// acquireScript acquires the lease for ARGV[1], or extends it if ARGV[1] already holds it. The lease
+// value is the fencing token and the holder, a new token is taken from KEYS[2] on each acquisition.
+var acquireScript = redis.NewScript(`
+local value = redis.call('GET', KEYS[1])
+if value then
+	local sep = string.find(value, ':', 1, true)
+	if string.sub(value, sep + 1) ~= ARGV[1] then
+		return 0
+	end
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+	return tonumber(string.sub(value, 1, sep - 1))
+end
+local token = redis.call('INCR', KEYS[2])
+redis.call('SET', KEYS[1], token .. ':' .. ARGV[1], 'PX', ARGV[2])
+return token
+`)
+
+// releaseScript releases the lease if ARGV[1] still holds it with the same token.
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// fencedScript runs the command in ARGV[2:] only if the lease value is still ARGV[1].
+var fencedScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return redis.error_reply('FENCED')
+end
+return redis.call(unpack(ARGV, 2))
+`)
+
+// Lease is a lease on a redis key for leader election. Each acquisition gets a new, increasing
+// fencing token, and fenced writes are only applied by redis while the lease is held with the
+// same token, so that a former holder which was paused past the expiry of its lease can not write.
+// The scripts access the lease and written keys together, so a lease can not be used on a redis cluster.
+type Lease struct {
+	key string
+	id  string
+	ttl time.Duration
+
+	client *Client
+
+	mu     sync.Mutex
+	token  int64
+	expiry time.Time
+}
+
+// NewLease returns a new Lease object on key for holder id, which expires after ttl unless renewed.
+func NewLease(client *Client, key, id string, ttl time.Duration) *Lease {
+	return &Lease{
+		key:    FormatKey(client.config.KeyPrefix, key),
+		id:     id,
+		ttl:    ttl,
+		client: client,
+	}
+}
+
+// TTL returns the duration of the lease.
+func (l *Lease) TTL() time.Duration {
+	return l.ttl
+}
+
+// Acquire acquires the lease, or renews it if it is already held. It returns whether the lease is held.
+func (l *Lease) Acquire(ctx context.Context) (bool, error) {
+	start := time.Now()
+	token, err := acquireScript.Run(ctx, l.client, []string{l.key, l.key + ":token"},
+		l.id, l.ttl.Milliseconds()).Int64()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err != nil {
+		return l.held(), err
+	}
+
+	l.token = token
+	if token == 0 {
+		l.expiry = time.Time{}
+
+		return false, nil
+	}
+
+	// The lease may expire on redis as soon as ttl after the request was sent.
+	l.expiry = start.Add(l.ttl)
+
+	return true, nil
+}
+
+// Release releases the lease if it is held, so that another holder can acquire it without waiting for expiry.
+func (l *Lease) Release(ctx context.Context) error {
+	l.mu.Lock()
+	value := l.value()
+	l.token = 0
+	l.expiry = time.Time{}
+	l.mu.Unlock()
+
+	if value == "" {
+		return nil
+	}
+
+	return releaseScript.Run(ctx, l.client, []string{l.key}, value).Err()
+}
+
+func (l *Lease) held() bool {
+	return l.token > 0 && time.Now().Before(l.expiry)
+}
+
+func (l *Lease) value() string {
+	if !l.held() {
+		return ""
+	}
+
+	return fmt.Sprintf("%d:%s", l.token, l.id)
+}
+
+// Token returns the fencing token of the lease, zero if the lease is not held or may have expired.
+func (l *Lease) Token() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.held() {
+		return 0
+	}
+
+	return l.token
+}
+
+// do runs a redis command on key if the lease is still held, it returns errors.ErrFenced otherwise.
+func (l *Lease) do(ctx context.Context, key string, args ...interface{}) error {
+	l.mu.Lock()
+	value := l.value()
+	l.mu.Unlock()
+
+	if value == "" {
+		return fmt.Errorf("lease %s is not held: %w", l.key, errors.ErrFenced)
+	}
+
+	err := fencedScript.Run(ctx, l.client, []string{l.key, key}, append([]interface{}{value}, args...)...).Err()
+	if err != nil && strings.Contains(err.Error(), "FENCED") {
+		return fmt.Errorf("lease %s was taken over: %w", l.key, errors.ErrFenced)
+	}
+	if errors.Is(err, redis.Nil) {
+		return nil
+	}
+
+	return err
+}
+
+// Set stores value of key like Client.Set, only if the lease is still held.
+func (l *Lease) Set(ctx context.Context, key string, v interface{}, exp time.Duration) error {
+	k := FormatKey(l.client.config.KeyPrefix, key)
+
+	data, err := Encode(v)
+	if err != nil {
+		return err
+	}
+
+	args := []interface{}{"SET", k, data}
+	if exp > 0 {
+		args = append(args, "PX", exp.Milliseconds())
+	}
+
+	return l.do(ctx, k, args...)
+}
@@ -0,0 +1,103 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/KyberNetwork/evmlistener/pkg/errors"
+	"github.com/stretchr/testify/suite"
+)
+
+type LeaseTestSuite struct {
+	suite.Suite
+
+	client *Client
+	key    string
+}
+
+func (ts *LeaseTestSuite) SetupTest() {
+	client, err := New(Config{
+		Addrs:     []string{":6379"},
+		KeyPrefix: "test:",
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	ts.client = client
+	ts.key = fmt.Sprintf("test-lease-%d", rand.Int()) // nolint
+}
+
+func (ts *LeaseTestSuite) TestAcquire() {
+	ctx := context.Background()
+	a := NewLease(ts.client, ts.key, "a", 200*time.Millisecond)
+	b := NewLease(ts.client, ts.key, "b", 200*time.Millisecond)
+
+	ok, err := a.Acquire(ctx)
+	ts.Require().NoError(err)
+	ts.Assert().True(ok)
+	token := a.Token()
+	ts.Assert().Positive(token)
+
+	ok, err = b.Acquire(ctx)
+	ts.Require().NoError(err)
+	ts.Assert().False(ok)
+	ts.Assert().Zero(b.Token())
+
+	// Renewing keeps the token.
+	ok, err = a.Acquire(ctx)
+	ts.Require().NoError(err)
+	ts.Assert().True(ok)
+	ts.Assert().Equal(token, a.Token())
+
+	// The lease is taken over with a new token after expiry.
+	time.Sleep(300 * time.Millisecond)
+	ts.Assert().Zero(a.Token())
+	ok, err = b.Acquire(ctx)
+	ts.Require().NoError(err)
+	ts.Assert().True(ok)
+	ts.Assert().Greater(b.Token(), token)
+
+	ts.Require().NoError(b.Release(ctx))
+	ok, err = a.Acquire(ctx)
+	ts.Require().NoError(err)
+	ts.Assert().True(ok)
+}
+
+func (ts *LeaseTestSuite) TestFenced() {
+	ctx := context.Background()
+	a := NewLease(ts.client, ts.key, "a", time.Second)
+	b := NewLease(ts.client, ts.key, "b", time.Second)
+	key := ts.key + ":value"
+
+	ts.Assert().ErrorIs(a.Set(ctx, key, "a", 0), errors.ErrFenced)
+
+	ok, err := a.Acquire(ctx)
+	ts.Require().NoError(err)
+	ts.Require().True(ok)
+	ts.Require().NoError(a.Set(ctx, key, "a", 0))
+
+	// A paused holder can not write once the lease was taken over.
+	ts.Require().NoError(ts.client.Del(ctx, FormatKey("test:", ts.key)).Err())
+	ok, err = b.Acquire(ctx)
+	ts.Require().NoError(err)
+	ts.Require().True(ok)
+	ts.Assert().ErrorIs(a.Set(ctx, key, "stale", 0), errors.ErrFenced)
+	ts.Require().NoError(b.Set(ctx, key, "b", 0))
+
+	var value string
+	ts.Require().NoError(ts.client.Get(ctx, key, &value))
+	ts.Assert().Equal("b", value)
+
+	stream := NewFencedStream(ts.client, 10, a)
+	ts.Assert().ErrorIs(stream.Publish(ctx, key+":stream", "msg"), errors.ErrFenced)
+	stream = NewFencedStream(ts.client, 10, b)
+	ts.Require().NoError(stream.Publish(ctx, key+":stream", "msg"))
+}
+
+func TestLeaseTestSuite(t *testing.T) {
+	suite.Run(t, new(LeaseTestSuite))
+}
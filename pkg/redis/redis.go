@@ -54,6 +54,29 @@ func New(cfg Config) (*Client, error) {
 	}, nil
 }
 
+// WithKeyPrefix returns a client with a different key prefix, sharing the connection pool of c.
+func (c *Client) WithKeyPrefix(prefix string) *Client {
+	config := c.config
+	config.KeyPrefix = prefix
+
+	return &Client{
+		config:          config,
+		UniversalClient: c.UniversalClient,
+	}
+}
+
+// IsCluster returns whether the client is connected to a redis cluster.
+func (c *Client) IsCluster() bool {
+	_, ok := c.UniversalClient.(*redis.ClusterClient)
+
+	return ok
+}
+
+// KeyPrefix returns the prefix of keys of the client.
+func (c *Client) KeyPrefix() string {
+	return c.config.KeyPrefix
+}
+
 // Set ...
 func (c *Client) Set(ctx context.Context, key string, v interface{}, exp time.Duration) error {
 	k := FormatKey(c.config.KeyPrefix, key)
@@ -15,6 +15,7 @@ type Stream struct {
 	maxLen int64
 
 	client *Client
+	lease  *Lease
 }
 
 // NewStream returns a new Stream object.
@@ -25,6 +26,15 @@ func NewStream(client *Client, maxLen int64) *Stream {
 	}
 }
 
+// NewFencedStream returns a new Stream object which publishes messages only while the lease is held.
+func NewFencedStream(client *Client, maxLen int64, lease *Lease) *Stream {
+	return &Stream{
+		maxLen: maxLen,
+		client: client,
+		lease:  lease,
+	}
+}
+
 // Publish publishs a message to given topic.
 func (s *Stream) Publish(ctx context.Context, topic string, msg interface{}) error {
 	data, err := Encode(msg)
@@ -32,6 +42,16 @@ func (s *Stream) Publish(ctx context.Context, topic string, msg interface{}) err
 		return err
 	}
 
+	if s.lease != nil {
+		args := []interface{}{"XADD", topic}
+		if s.maxLen > 0 {
+			args = append(args, "MAXLEN", "~", s.maxLen)
+		}
+		args = append(args, "*", MessageKey, string(data))
+
+		return s.lease.do(ctx, topic, args...)
+	}
+
 	return s.client.XAdd(ctx, &redis.XAddArgs{
 		Stream: topic,
 		MaxLen: s.maxLen,
@@ -0,0 +1,100 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/gorilla/websocket"
+)
+
+// Class is the class of an error, which decides whether a request should be retried.
+type Class string
+
+// Error classes.
+const (
+	ClassNotFound    Class = "not_found"
+	ClassRateLimited Class = "rate_limited"
+	ClassTimeout     Class = "timeout"
+	ClassConnection  Class = "connection"
+	ClassCanceled    Class = "canceled"
+	ClassFatal       Class = "fatal"
+)
+
+// Retryable reports whether errors of the class are transient.
+func (c Class) Retryable() bool {
+	switch c { //nolint:exhaustive
+	case ClassNotFound, ClassRateLimited, ClassTimeout, ClassConnection:
+		return true
+	default:
+		return false
+	}
+}
+
+//nolint:gochecknoglobals
+var (
+	notFoundMessages    = []string{"unknown block", "header not found", "block not found"}
+	rateLimitedMessages = []string{"rate limit", "too many requests", "request limit"}
+)
+
+type classifiedError struct {
+	error
+	class Class
+}
+
+func (e *classifiedError) Unwrap() error {
+	return e.error
+}
+
+// WithClass returns an error which is classified as class.
+func WithClass(err error, class Class) error {
+	return &classifiedError{error: err, class: class}
+}
+
+func containsAny(s string, subs []string) bool {
+	for _, sub := range subs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Classify returns class of an error returned by a node rpc request.
+//
+//nolint:cyclop
+func Classify(err error) Class {
+	var classified *classifiedError
+	var httpErr rpc.HTTPError
+	var netErr net.Error
+	var closeErr *websocket.CloseError
+
+	msg := strings.ToLower(err.Error())
+	switch {
+	case errors.As(err, &classified):
+		return classified.class
+	case errors.Is(err, ethereum.NotFound), containsAny(msg, notFoundMessages):
+		return ClassNotFound
+	case errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusTooManyRequests,
+		containsAny(msg, rateLimitedMessages):
+		return ClassRateLimited
+	case errors.Is(err, context.Canceled):
+		return ClassCanceled
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ClassTimeout
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.EPIPE), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, net.ErrClosed), errors.As(err, &closeErr),
+		errors.As(err, &httpErr) && httpErr.StatusCode >= http.StatusInternalServerError:
+		return ClassConnection
+	default:
+		return ClassFatal
+	}
+}
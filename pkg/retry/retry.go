@@ -0,0 +1,109 @@
+// Package retry provides a retry policy with exponential backoff and jitter,
+// and a classifier deciding which errors of node rpc requests are transient.
+package retry
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	defaultMaxAttempts     = 5
+	defaultInitialInterval = 500 * time.Millisecond
+	defaultMaxInterval     = 5 * time.Second
+	defaultMultiplier      = 2
+	defaultJitter          = 0.2
+)
+
+// Policy describes how a request is retried.
+type Policy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one. Zero means no limit.
+	MaxAttempts int
+	// InitialInterval is the delay before the first retry.
+	InitialInterval time.Duration
+	// MaxInterval caps the delay between two attempts.
+	MaxInterval time.Duration
+	// Multiplier is the factor applied to the delay after each retry.
+	Multiplier float64
+	// Jitter is the fraction of the delay which is randomized, in range [0, 1].
+	Jitter float64
+}
+
+// DefaultPolicy returns the default retry policy.
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxAttempts:     defaultMaxAttempts,
+		InitialInterval: defaultInitialInterval,
+		MaxInterval:     defaultMaxInterval,
+		Multiplier:      defaultMultiplier,
+		Jitter:          defaultJitter,
+	}
+}
+
+// WithMaxAttempts returns a copy of the policy with given maximum number of attempts.
+func (p Policy) WithMaxAttempts(n int) Policy {
+	p.MaxAttempts = n
+
+	return p
+}
+
+// Backoff returns the delay before the retry following the given attempt, starting from 0.
+func (p Policy) Backoff(attempt int) time.Duration {
+	multiplier := max(p.Multiplier, 1)
+	d := float64(p.InitialInterval) * math.Pow(multiplier, float64(attempt))
+	if p.MaxInterval > 0 {
+		d = min(d, float64(p.MaxInterval))
+	}
+
+	if p.Jitter > 0 {
+		// Randomize the delay in range [d*(1-jitter), d] so that clients do not retry in lockstep.
+		d -= d * min(p.Jitter, 1) * rand.Float64() //nolint:gosec
+	}
+
+	return time.Duration(d)
+}
+
+// Sleep waits for d or until the context is done.
+func Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Do calls fn until it succeeds, returns a non-retryable error, or the attempts are exhausted.
+// Each failed attempt is recorded as an event of the span in context. The last error is returned.
+func (p Policy) Do(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+
+		class := Classify(err)
+		trace.SpanFromContext(ctx).AddEvent("retry", trace.WithAttributes(
+			attribute.String("name", name),
+			attribute.Int("attempt", attempt+1),
+			attribute.String("class", string(class)),
+			attribute.String("error", err.Error()),
+		))
+
+		if !class.Retryable() || (p.MaxAttempts > 0 && attempt+1 >= p.MaxAttempts) {
+			return err
+		}
+
+		if sleepErr := Sleep(ctx, p.Backoff(attempt)); sleepErr != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/suite"
+)
+
+type RetryTestSuite struct {
+	suite.Suite
+
+	policy Policy
+}
+
+func (ts *RetryTestSuite) SetupTest() {
+	ts.policy = Policy{
+		MaxAttempts:     3,
+		InitialInterval: time.Millisecond,
+		MaxInterval:     4 * time.Millisecond,
+		Multiplier:      2,
+	}
+}
+
+func (ts *RetryTestSuite) TestClassify() {
+	tests := []struct {
+		err   error
+		class Class
+	}{
+		{ethereum.NotFound, ClassNotFound},
+		{errors.New("unknown block"), ClassNotFound},
+		{rpc.HTTPError{StatusCode: 429, Status: "429 Too Many Requests"}, ClassRateLimited},
+		{errors.New("daily request limit reached, rate limit exceeded"), ClassRateLimited},
+		{context.DeadlineExceeded, ClassTimeout},
+		{context.Canceled, ClassCanceled},
+		{fmt.Errorf("read: %w", syscall.ECONNRESET), ClassConnection},
+		{io.ErrUnexpectedEOF, ClassConnection},
+		{rpc.HTTPError{StatusCode: 502, Status: "502 Bad Gateway"}, ClassConnection},
+		{WithClass(errors.New("stale subscription"), ClassConnection), ClassConnection},
+		{errors.New("invalid argument"), ClassFatal},
+	}
+
+	for _, test := range tests {
+		ts.Assert().Equal(test.class, Classify(test.err), test.err.Error())
+	}
+}
+
+func (ts *RetryTestSuite) TestBackoff() {
+	ts.Assert().Equal(time.Millisecond, ts.policy.Backoff(0))
+	ts.Assert().Equal(2*time.Millisecond, ts.policy.Backoff(1))
+	ts.Assert().Equal(4*time.Millisecond, ts.policy.Backoff(5))
+
+	ts.policy.Jitter = 0.5
+	for range 100 {
+		d := ts.policy.Backoff(2)
+		ts.Assert().GreaterOrEqual(d, 2*time.Millisecond)
+		ts.Assert().LessOrEqual(d, 4*time.Millisecond)
+	}
+}
+
+func (ts *RetryTestSuite) TestDo() {
+	var calls int
+	err := ts.policy.Do(context.Background(), "test", func(context.Context) error {
+		calls++
+		if calls < 2 {
+			return ethereum.NotFound
+		}
+
+		return nil
+	})
+	ts.Require().NoError(err)
+	ts.Assert().Equal(2, calls)
+
+	calls = 0
+	err = ts.policy.Do(context.Background(), "test", func(context.Context) error {
+		calls++
+
+		return ethereum.NotFound
+	})
+	ts.Assert().ErrorIs(err, ethereum.NotFound)
+	ts.Assert().Equal(3, calls)
+}
+
+func (ts *RetryTestSuite) TestDoFatal() {
+	errFatal := errors.New("invalid argument")
+
+	var calls int
+	err := ts.policy.Do(context.Background(), "test", func(context.Context) error {
+		calls++
+
+		return errFatal
+	})
+	ts.Assert().ErrorIs(err, errFatal)
+	ts.Assert().Equal(1, calls)
+}
+
+func (ts *RetryTestSuite) TestDoCanceled() {
+	ctx, cancel := context.WithCancel(context.Background())
+	ts.policy.MaxAttempts = 0
+	ts.policy.InitialInterval = time.Hour
+	ts.policy.MaxInterval = time.Hour
+
+	var calls int
+	err := ts.policy.Do(ctx, "test", func(context.Context) error {
+		calls++
+		cancel()
+
+		return ethereum.NotFound
+	})
+	ts.Assert().ErrorIs(err, ethereum.NotFound)
+	ts.Assert().Equal(1, calls)
+}
+
+func TestRetryTestSuite(t *testing.T) {
+	suite.Run(t, new(RetryTestSuite))
+}
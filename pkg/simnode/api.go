@@ -0,0 +1,246 @@
+package simnode
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var errUnknownBlock = errors.New("unknown block")
+
+// filterCriteria is the argument of eth_getLogs.
+type filterCriteria struct {
+	BlockHash *common.Hash      `json:"blockHash"`
+	FromBlock *rpc.BlockNumber  `json:"fromBlock"`
+	ToBlock   *rpc.BlockNumber  `json:"toBlock"`
+	Address   json.RawMessage   `json:"address"`
+	Topics    []json.RawMessage `json:"topics"`
+}
+
+// addresses returns the addresses to filter, the address field is either a single address or a list.
+func (f *filterCriteria) addresses() ([]common.Address, error) {
+	if len(f.Address) == 0 || string(f.Address) == "null" {
+		return nil, nil
+	}
+
+	var addresses []common.Address
+	if f.Address[0] == '"' {
+		var address common.Address
+		if err := json.Unmarshal(f.Address, &address); err != nil {
+			return nil, err
+		}
+
+		return []common.Address{address}, nil
+	}
+
+	if err := json.Unmarshal(f.Address, &addresses); err != nil {
+		return nil, err
+	}
+
+	return addresses, nil
+}
+
+// topics returns the topics to filter, each position is either null, a single topic or a list.
+func (f *filterCriteria) topics() ([][]common.Hash, error) {
+	topics := make([][]common.Hash, 0, len(f.Topics))
+	for _, raw := range f.Topics {
+		switch {
+		case len(raw) == 0 || string(raw) == "null":
+			topics = append(topics, nil)
+		case raw[0] == '"':
+			var topic common.Hash
+			if err := json.Unmarshal(raw, &topic); err != nil {
+				return nil, err
+			}
+			topics = append(topics, []common.Hash{topic})
+		default:
+			var ts []common.Hash
+			if err := json.Unmarshal(raw, &ts); err != nil {
+				return nil, err
+			}
+			topics = append(topics, ts)
+		}
+	}
+
+	return topics, nil
+}
+
+func matchLog(log *ethtypes.Log, addresses []common.Address, topics [][]common.Hash) bool {
+	if len(addresses) > 0 {
+		found := false
+		for _, address := range addresses {
+			found = found || address == log.Address
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if len(topics) > len(log.Topics) {
+		return false
+	}
+
+	for i, ts := range topics {
+		if len(ts) == 0 {
+			continue
+		}
+
+		found := false
+		for _, topic := range ts {
+			found = found || topic == log.Topics[i]
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ethAPI serves the eth namespace of the node.
+type ethAPI struct {
+	cfg   *config
+	chain *chain
+}
+
+// ChainId returns the chain id.
+//
+//nolint:revive,stylecheck
+func (api *ethAPI) ChainId() *hexutil.Big {
+	return (*hexutil.Big)(api.cfg.chainID)
+}
+
+// BlockNumber returns number of the latest block.
+func (api *ethAPI) BlockNumber() hexutil.Uint64 {
+	return hexutil.Uint64(api.chain.head().header.Number.Uint64())
+}
+
+func (api *ethAPI) resolveNumber(number rpc.BlockNumber) uint64 {
+	if number < 0 {
+		// Latest, pending, safe and finalized blocks are all the head block.
+		return api.chain.head().header.Number.Uint64()
+	}
+
+	return uint64(number.Int64())
+}
+
+// GetBlockByNumber returns header of the canonical block by number, transactions are not served.
+func (api *ethAPI) GetBlockByNumber(number rpc.BlockNumber, _ bool) (*ethtypes.Header, error) {
+	b := api.chain.blockByNumber(api.resolveNumber(number))
+	if b == nil {
+		return nil, nil //nolint:nilnil
+	}
+
+	return b.header, nil
+}
+
+// GetBlockByHash returns header of a block by hash, including blocks which were re-organized.
+func (api *ethAPI) GetBlockByHash(hash common.Hash, _ bool) (*ethtypes.Header, error) {
+	b := api.chain.blockByHash(hash)
+	if b == nil {
+		return nil, nil //nolint:nilnil
+	}
+
+	return b.header, nil
+}
+
+// GetLogs returns logs matching the filter, logs of a block are not served before its log delay.
+func (api *ethAPI) GetLogs(crit filterCriteria) ([]*ethtypes.Log, error) {
+	addresses, err := crit.addresses()
+	if err != nil {
+		return nil, err
+	}
+
+	topics, err := crit.topics()
+	if err != nil {
+		return nil, err
+	}
+
+	var blocks []*block
+	if crit.BlockHash != nil {
+		b := api.chain.blockByHash(*crit.BlockHash)
+		if b == nil {
+			return nil, errUnknownBlock
+		}
+		blocks = []*block{b}
+	} else {
+		from, to := rpc.LatestBlockNumber, rpc.LatestBlockNumber
+		if crit.FromBlock != nil {
+			from = *crit.FromBlock
+		}
+		if crit.ToBlock != nil {
+			to = *crit.ToBlock
+		}
+		blocks = api.chain.canonicalRange(api.resolveNumber(from), api.resolveNumber(to))
+	}
+
+	now := time.Now()
+	logs := []*ethtypes.Log{}
+	for _, b := range blocks {
+		if now.Before(b.logsAt) {
+			continue
+		}
+
+		for _, log := range b.logs {
+			if matchLog(log, addresses, topics) {
+				logs = append(logs, log)
+			}
+		}
+
+		if api.cfg.maxLogResults > 0 && len(logs) > api.cfg.maxLogResults {
+			return nil, fmt.Errorf("query returned more than %d results", api.cfg.maxLogResults)
+		}
+	}
+
+	return logs, nil
+}
+
+// GetBlockReceipts returns receipts of a block by number or hash, each log of the block is emitted
+// by its own transaction.
+func (api *ethAPI) GetBlockReceipts(blockNrOrHash rpc.BlockNumberOrHash) ([]*ethtypes.Receipt, error) {
+	var b *block
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		b = api.chain.blockByHash(hash)
+	} else if number, ok := blockNrOrHash.Number(); ok {
+		b = api.chain.blockByNumber(api.resolveNumber(number))
+	}
+	if b == nil {
+		return nil, nil
+	}
+
+	return b.receipts, nil
+}
+
+// NewHeads subscribes to new heads of the canonical chain.
+func (api *ethAPI) NewHeads(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, ok := rpc.NotifierFromContext(ctx)
+	if !ok {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+
+	sub := notifier.CreateSubscription()
+	id, ch := api.chain.subscribe()
+	go func() {
+		defer api.chain.unsubscribe(id)
+
+		for {
+			select {
+			case header := <-ch:
+				_ = notifier.Notify(sub.ID, header)
+			case <-sub.Err():
+				return
+			}
+		}
+	}()
+
+	return sub, nil
+}
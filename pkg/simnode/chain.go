@@ -0,0 +1,247 @@
+package simnode
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+const (
+	defaultGasLimit = 30_000_000
+	txGas           = 21_000
+	subBufLen       = 1024
+)
+
+// transferTopic is topic of Transfer(address,address,uint256) event, used for generated logs.
+//
+//nolint:gochecknoglobals
+var transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+type block struct {
+	header   *ethtypes.Header
+	logs     []*ethtypes.Log
+	receipts []*ethtypes.Receipt
+	// logsAt is the time from which logs of the block are served.
+	logsAt time.Time
+}
+
+// chain is an in-memory chain, blocks which were re-organized are kept to be served by hash.
+type chain struct {
+	mu        sync.RWMutex
+	cfg       *config
+	blocks    []*block
+	byHash    map[common.Hash]*block
+	fork      uint64
+	logDelay  time.Duration
+	subs      map[int]chan *ethtypes.Header
+	nextSubID int
+}
+
+func newChain(cfg *config) *chain {
+	c := &chain{
+		cfg:      cfg,
+		byHash:   make(map[common.Hash]*block),
+		logDelay: cfg.logDelay,
+		subs:     make(map[int]chan *ethtypes.Header),
+	}
+
+	genesisTime := uint64(time.Now().Add(-time.Duration(cfg.initialBlocks) * cfg.blockStep()).Unix())
+	c.appendBlock(&ethtypes.Header{
+		Number:     new(big.Int),
+		Time:       genesisTime,
+		Difficulty: big.NewInt(1),
+		GasLimit:   defaultGasLimit,
+		Extra:      []byte("simnode genesis"),
+	}, nil, nil)
+	c.mine(cfg.initialBlocks - 1)
+
+	return c
+}
+
+func (c *chain) appendBlock(header *ethtypes.Header, logs []*ethtypes.Log, receipts []*ethtypes.Receipt) *block {
+	hash := header.Hash()
+	for i, log := range logs {
+		log.BlockHash = hash
+		log.TxHash = crypto.Keccak256Hash(hash.Bytes(), big.NewInt(int64(i)).Bytes())
+	}
+	for _, receipt := range receipts {
+		receipt.BlockHash = hash
+		receipt.TxHash = receipt.Logs[0].TxHash
+	}
+
+	b := &block{header: header, logs: logs, receipts: receipts, logsAt: time.Now().Add(c.logDelay)}
+	c.blocks = append(c.blocks, b)
+	c.byHash[hash] = b
+
+	return b
+}
+
+// mine appends n blocks to the canonical chain, it must be called with lock held.
+func (c *chain) mine(n int) []*ethtypes.Header {
+	headers := make([]*ethtypes.Header, 0, n)
+	for range n {
+		parent := c.blocks[len(c.blocks)-1].header
+		number := new(big.Int).Add(parent.Number, big.NewInt(1))
+
+		// Each log is emitted by its own transaction.
+		var bloom ethtypes.Bloom
+		logs := make([]*ethtypes.Log, 0, c.cfg.logsPerBlock)
+		receipts := make([]*ethtypes.Receipt, 0, c.cfg.logsPerBlock)
+		for i := range c.cfg.logsPerBlock {
+			log := &ethtypes.Log{
+				Address:     c.cfg.logAddress,
+				Topics:      []common.Hash{transferTopic},
+				Data:        common.BigToHash(number).Bytes(),
+				BlockNumber: number.Uint64(),
+				TxIndex:     uint(i),
+				Index:       uint(i),
+			}
+			logs = append(logs, log)
+
+			receipt := &ethtypes.Receipt{
+				Status:            ethtypes.ReceiptStatusSuccessful,
+				CumulativeGasUsed: uint64(i+1) * txGas,
+				GasUsed:           txGas,
+				Logs:              []*ethtypes.Log{log},
+				BlockNumber:       number,
+				TransactionIndex:  uint(i),
+			}
+			receipt.Bloom = ethtypes.CreateBloom(receipt)
+			bloom.Add(log.Address.Bytes())
+			for _, topic := range log.Topics {
+				bloom.Add(topic.Bytes())
+			}
+			receipts = append(receipts, receipt)
+		}
+
+		header := &ethtypes.Header{
+			ParentHash:  parent.Hash(),
+			Number:      number,
+			Time:        parent.Time + uint64(max(c.cfg.blockStep()/time.Second, 1)),
+			Difficulty:  big.NewInt(1),
+			GasLimit:    defaultGasLimit,
+			GasUsed:     uint64(len(receipts)) * txGas,
+			Bloom:       bloom,
+			ReceiptHash: ethtypes.DeriveSha(ethtypes.Receipts(receipts), trie.NewStackTrie(nil)),
+			Extra:       []byte(fmt.Sprintf("simnode fork %d", c.fork)),
+		}
+		c.appendBlock(header, logs, receipts)
+		headers = append(headers, header)
+	}
+
+	return headers
+}
+
+func (c *chain) notify(headers []*ethtypes.Header) {
+	for _, header := range headers {
+		for _, ch := range c.subs {
+			select {
+			case ch <- header:
+			default:
+				// Drop the header for slow subscribers, as a node would do.
+			}
+		}
+	}
+}
+
+// Mine appends n blocks to the chain and notifies subscribers.
+func (c *chain) Mine(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.notify(c.mine(n))
+}
+
+// Reorg replaces the last depth blocks with n new blocks and notifies subscribers of the new blocks.
+func (c *chain) Reorg(depth, n int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if depth <= 0 || depth >= len(c.blocks) {
+		return fmt.Errorf("invalid reorg depth %d for chain of %d blocks", depth, len(c.blocks))
+	}
+
+	c.blocks = c.blocks[:len(c.blocks)-depth]
+	c.fork++
+	c.notify(c.mine(n))
+
+	return nil
+}
+
+// SetLogDelay sets the delay before logs of new blocks are served.
+func (c *chain) SetLogDelay(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.logDelay = d
+}
+
+func (c *chain) head() *block {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.blocks[len(c.blocks)-1]
+}
+
+func (c *chain) blockByNumber(number uint64) *block {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if number >= uint64(len(c.blocks)) {
+		return nil
+	}
+
+	return c.blocks[number]
+}
+
+func (c *chain) blockByHash(hash common.Hash) *block {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.byHash[hash]
+}
+
+// canonicalRange returns canonical blocks in range [from, to].
+func (c *chain) canonicalRange(from, to uint64) []*block {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	to = min(to, uint64(len(c.blocks)-1))
+	if from > to {
+		return nil
+	}
+
+	return append([]*block(nil), c.blocks[from:to+1]...)
+}
+
+func (c *chain) subscribe() (int, <-chan *ethtypes.Header) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.nextSubID++
+	ch := make(chan *ethtypes.Header, subBufLen)
+	c.subs[c.nextSubID] = ch
+
+	return c.nextSubID, ch
+}
+
+func (c *chain) unsubscribe(id int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.subs, id)
+}
+
+// Subscriptions returns the number of new head subscriptions created so far.
+func (c *chain) Subscriptions() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.nextSubID
+}
@@ -0,0 +1,275 @@
+// Package simnode provides an in-process simulated EVM node serving JSON-RPC over HTTP and websocket.
+// It produces blocks with logs, either on a timer or on demand, and supports scripted re-organizations,
+// delayed logs and dropped connections for testing.
+package simnode
+
+import (
+	"context"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	defaultChainID       = 1337
+	defaultInitialBlocks = 64
+	defaultLogsPerBlock  = 2
+)
+
+type config struct {
+	chainID       *big.Int
+	blockTime     time.Duration
+	initialBlocks int
+	logsPerBlock  int
+	logAddress    common.Address
+	logDelay      time.Duration
+	maxLogResults int
+}
+
+// blockStep returns the time between timestamps of two consecutive blocks.
+func (c *config) blockStep() time.Duration {
+	if c.blockTime <= 0 {
+		return time.Second
+	}
+
+	return c.blockTime
+}
+
+// Option configures a Node.
+type Option func(cfg *config)
+
+// WithChainID sets the chain id of the node.
+func WithChainID(chainID uint64) Option {
+	return func(cfg *config) {
+		cfg.chainID = new(big.Int).SetUint64(chainID)
+	}
+}
+
+// WithBlockTime makes the node produce a new block on every interval, blocks are only produced
+// by calling Mine if it is not set.
+func WithBlockTime(interval time.Duration) Option {
+	return func(cfg *config) {
+		cfg.blockTime = interval
+	}
+}
+
+// WithInitialBlocks sets the number of blocks of the chain when the node starts, including genesis.
+func WithInitialBlocks(n int) Option {
+	return func(cfg *config) {
+		cfg.initialBlocks = n
+	}
+}
+
+// WithLogs sets the number of logs per block and the address emitting them.
+func WithLogs(logsPerBlock int, address common.Address) Option {
+	return func(cfg *config) {
+		cfg.logsPerBlock = logsPerBlock
+		cfg.logAddress = address
+	}
+}
+
+// WithLogDelay delays serving logs of a new block, as a node which has not indexed them yet.
+func WithLogDelay(d time.Duration) Option {
+	return func(cfg *config) {
+		cfg.logDelay = d
+	}
+}
+
+// WithMaxLogResults makes eth_getLogs fail when a query has more than n results.
+func WithMaxLogResults(n int) Option {
+	return func(cfg *config) {
+		cfg.maxLogResults = n
+	}
+}
+
+// Node is a simulated EVM node.
+type Node struct {
+	cfg    *config
+	chain  *chain
+	server *rpc.Server
+	http   *http.Server
+	ln     net.Listener
+
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New returns a new node, it does not serve requests until started.
+func New(opts ...Option) (*Node, error) {
+	cfg := &config{
+		chainID:       big.NewInt(defaultChainID),
+		initialBlocks: defaultInitialBlocks,
+		logsPerBlock:  defaultLogsPerBlock,
+		logAddress:    common.HexToAddress("0x0000000000000000000000000000000000000001"),
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	cfg.initialBlocks = max(cfg.initialBlocks, 1)
+
+	n := &Node{
+		cfg:    cfg,
+		chain:  newChain(cfg),
+		server: rpc.NewServer(),
+		conns:  make(map[net.Conn]struct{}),
+	}
+
+	if err := n.server.RegisterName("eth", &ethAPI{cfg: cfg, chain: n.chain}); err != nil {
+		return nil, err
+	}
+
+	return n, nil
+}
+
+// Start serves JSON-RPC on a random local port, and starts producing blocks if block time is set.
+func (n *Node) Start() error {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+
+	wsHandler := n.server.WebsocketHandler([]string{"*"})
+	n.ln = ln
+	n.http = &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+				wsHandler.ServeHTTP(w, r)
+
+				return
+			}
+
+			n.server.ServeHTTP(w, r)
+		}),
+		ConnState:         n.trackConn,
+		ReadHeaderTimeout: time.Second,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	n.cancel = cancel
+
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+
+		_ = n.http.Serve(ln)
+	}()
+
+	if n.cfg.blockTime > 0 {
+		n.wg.Add(1)
+		go func() {
+			defer n.wg.Done()
+
+			n.produce(ctx)
+		}()
+	}
+
+	return nil
+}
+
+func (n *Node) produce(ctx context.Context) {
+	ticker := time.NewTicker(n.cfg.blockTime)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n.Mine(1)
+		}
+	}
+}
+
+func (n *Node) trackConn(conn net.Conn, state http.ConnState) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	switch state { //nolint:exhaustive
+	case http.StateNew:
+		n.conns[conn] = struct{}{}
+	case http.StateClosed:
+		delete(n.conns, conn)
+	}
+}
+
+// Close stops the node and closes all connections.
+func (n *Node) Close() {
+	if n.cancel != nil {
+		n.cancel()
+	}
+
+	if n.http != nil {
+		_ = n.http.Close()
+	}
+	n.DropConnections()
+	n.server.Stop()
+	n.wg.Wait()
+}
+
+// HTTPURL returns url for JSON-RPC over HTTP.
+func (n *Node) HTTPURL() string {
+	return "http://" + n.ln.Addr().String()
+}
+
+// WSURL returns url for JSON-RPC over websocket.
+func (n *Node) WSURL() string {
+	return "ws://" + n.ln.Addr().String()
+}
+
+// DropConnections closes all open connections, including websocket ones, as a node restart would do.
+func (n *Node) DropConnections() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for conn := range n.conns {
+		_ = conn.Close()
+		delete(n.conns, conn)
+	}
+}
+
+// Mine produces n new blocks.
+func (n *Node) Mine(num int) {
+	n.chain.Mine(num)
+}
+
+// Reorg replaces the last depth blocks of the chain with num new blocks.
+func (n *Node) Reorg(depth, num int) error {
+	return n.chain.Reorg(depth, num)
+}
+
+// SetLogDelay sets the delay before logs of new blocks are served.
+func (n *Node) SetLogDelay(d time.Duration) {
+	n.chain.SetLogDelay(d)
+}
+
+// Subscriptions returns the number of new head subscriptions created so far,
+// which allows waiting for a client to subscribe before producing blocks.
+func (n *Node) Subscriptions() int {
+	return n.chain.Subscriptions()
+}
+
+// Head returns number and hash of the latest block.
+func (n *Node) Head() (uint64, common.Hash) {
+	header := n.chain.head().header
+
+	return header.Number.Uint64(), header.Hash()
+}
+
+// BlockHash returns hash of the canonical block by number.
+func (n *Node) BlockHash(number uint64) (common.Hash, bool) {
+	b := n.chain.blockByNumber(number)
+	if b == nil {
+		return common.Hash{}, false
+	}
+
+	return b.header.Hash(), true
+}
@@ -0,0 +1,162 @@
+package simnode
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/KyberNetwork/evmlistener/pkg/evmclient"
+	"github.com/KyberNetwork/evmlistener/pkg/types"
+	"github.com/ethereum/go-ethereum"
+	"github.com/stretchr/testify/suite"
+)
+
+type NodeTestSuite struct {
+	suite.Suite
+
+	node *Node
+	http *evmclient.Client
+	ws   *evmclient.Client
+}
+
+func (ts *NodeTestSuite) SetupTest() {
+	var err error
+	ts.node, err = New(WithInitialBlocks(10), WithMaxLogResults(10))
+	ts.Require().NoError(err)
+	ts.Require().NoError(ts.node.Start())
+
+	ts.http, err = evmclient.Dial(ts.node.HTTPURL(), http.DefaultClient)
+	ts.Require().NoError(err)
+	ts.ws, err = evmclient.Dial(ts.node.WSURL(), http.DefaultClient)
+	ts.Require().NoError(err)
+}
+
+func (ts *NodeTestSuite) TearDownTest() {
+	ts.node.Close()
+}
+
+func (ts *NodeTestSuite) receive(ch <-chan *types.Header) *types.Header {
+	select {
+	case header := <-ch:
+		return header
+	case <-time.After(5 * time.Second):
+		ts.FailNow("timeout waiting for new head")
+	}
+
+	return nil
+}
+
+func (ts *NodeTestSuite) TestRequests() {
+	ctx := context.Background()
+	number, err := ts.http.BlockNumber(ctx)
+	ts.Require().NoError(err)
+	ts.Assert().Equal(uint64(9), number)
+
+	header, err := ts.http.HeaderByNumber(ctx, big.NewInt(5))
+	ts.Require().NoError(err)
+	hash, _ := ts.node.BlockHash(5)
+	ts.Assert().Equal(hash.Hex(), header.Hash)
+
+	parent, err := ts.http.HeaderByHash(ctx, header.ParentHash)
+	ts.Require().NoError(err)
+	ts.Assert().Equal(uint64(4), parent.Number.Uint64())
+
+	_, err = ts.http.HeaderByNumber(ctx, big.NewInt(100))
+	ts.Assert().ErrorIs(err, ethereum.NotFound)
+
+	logs, err := ts.http.FilterLogs(ctx, evmclient.FilterQuery{BlockHash: &header.Hash})
+	ts.Require().NoError(err)
+	ts.Require().Len(logs, 2)
+	ts.Assert().Equal(header.Hash, logs[0].BlockHash)
+
+	logs, err = ts.http.FilterLogs(ctx, evmclient.FilterQuery{FromBlock: big.NewInt(1), ToBlock: big.NewInt(5)})
+	ts.Require().NoError(err)
+	ts.Assert().Len(logs, 10)
+
+	_, err = ts.http.FilterLogs(ctx, evmclient.FilterQuery{FromBlock: big.NewInt(1), ToBlock: big.NewInt(9)})
+	ts.Assert().ErrorContains(err, "query returned more than 10 results")
+
+	logs, err = ts.http.FilterLogs(ctx, evmclient.FilterQuery{
+		FromBlock: big.NewInt(1),
+		ToBlock:   big.NewInt(2),
+		Topics:    [][]string{{"0x0000000000000000000000000000000000000000000000000000000000000001"}},
+	})
+	ts.Require().NoError(err)
+	ts.Assert().Empty(logs)
+}
+
+func (ts *NodeTestSuite) TestReorg() {
+	ctx := context.Background()
+	ch := make(chan *types.Header, 16)
+	sub, err := ts.ws.SubscribeNewHead(ctx, ch)
+	ts.Require().NoError(err)
+	defer sub.Unsubscribe()
+
+	ts.node.Mine(2)
+	ts.Assert().Equal(uint64(10), ts.receive(ch).Number.Uint64())
+	ts.Assert().Equal(uint64(11), ts.receive(ch).Number.Uint64())
+
+	oldHash, _ := ts.node.BlockHash(10)
+	ts.Require().NoError(ts.node.Reorg(2, 3))
+	header := ts.receive(ch)
+	ts.Assert().Equal(uint64(10), header.Number.Uint64())
+	ts.Assert().NotEqual(oldHash.Hex(), header.Hash)
+	ts.Assert().Equal(uint64(11), ts.receive(ch).Number.Uint64())
+	ts.Assert().Equal(uint64(12), ts.receive(ch).Number.Uint64())
+
+	// Re-organized blocks are still served by hash.
+	old, err := ts.http.HeaderByHash(ctx, oldHash.Hex())
+	ts.Require().NoError(err)
+	ts.Assert().Equal(uint64(10), old.Number.Uint64())
+}
+
+func (ts *NodeTestSuite) TestLogDelay() {
+	ctx := context.Background()
+	ts.node.SetLogDelay(200 * time.Millisecond)
+	ts.node.Mine(1)
+
+	_, hash := ts.node.Head()
+	blockHash := hash.Hex()
+	logs, err := ts.http.FilterLogs(ctx, evmclient.FilterQuery{BlockHash: &blockHash})
+	ts.Require().NoError(err)
+	ts.Assert().Empty(logs)
+
+	time.Sleep(250 * time.Millisecond)
+	logs, err = ts.http.FilterLogs(ctx, evmclient.FilterQuery{BlockHash: &blockHash})
+	ts.Require().NoError(err)
+	ts.Assert().Len(logs, 2)
+}
+
+func (ts *NodeTestSuite) TestDropConnections() {
+	ch := make(chan *types.Header, 16)
+	sub, err := ts.ws.SubscribeNewHead(context.Background(), ch)
+	ts.Require().NoError(err)
+	defer sub.Unsubscribe()
+
+	ts.node.DropConnections()
+	select {
+	case err = <-sub.Err():
+		ts.Assert().Error(err)
+	case <-time.After(5 * time.Second):
+		ts.FailNow("timeout waiting for subscription error")
+	}
+}
+
+func (ts *NodeTestSuite) TestBlockTime() {
+	node, err := New(WithBlockTime(20 * time.Millisecond))
+	ts.Require().NoError(err)
+	ts.Require().NoError(node.Start())
+	defer node.Close()
+
+	ts.Require().Eventually(func() bool {
+		number, _ := node.Head()
+
+		return number >= defaultInitialBlocks+2
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestNodeTestSuite(t *testing.T) {
+	suite.Run(t, new(NodeTestSuite))
+}
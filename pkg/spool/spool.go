@@ -0,0 +1,388 @@
+package spool
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/KyberNetwork/evmlistener/pkg/errors"
+	"github.com/KyberNetwork/evmlistener/pkg/pubsub"
+	"github.com/KyberNetwork/evmlistener/pkg/retry"
+	pkgmetric "github.com/KyberNetwork/kyber-trace-go/pkg/metric"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+)
+
+const (
+	logFileName    = "spool.log"
+	offsetFileName = "spool.offset"
+
+	// A record is the length and the checksum of its payload, followed by the payload.
+	recordHeaderSize = 8
+
+	metricNameSpoolDepth = "evmlistener_spool_depth"
+	metricNameSpoolBytes = "evmlistener_spool_bytes"
+)
+
+// ErrSpoolFull means a message could not be published nor spooled because the spool is full.
+var ErrSpoolFull = errors.New("spool is full")
+
+type record struct {
+	Topic   string          `json:"topic"`
+	Message json.RawMessage `json:"message"`
+}
+
+// Spool is a publisher which appends messages to a write-ahead log on disk when the underlying
+// publisher fails with a transient error, and publishes them in the background, in order.
+// Once a message is spooled, following messages are spooled too until the spool is empty,
+// so that messages are published in order.
+type Spool struct {
+	l       *zap.SugaredLogger
+	next    pubsub.Publisher
+	dir     string
+	maxSize int64
+	policy  retry.Policy
+	attrs   metric.MeasurementOption
+
+	// publishMu serializes Publish calls to keep messages in order, while mu guards the state
+	// of the spool and is not held during network calls.
+	publishMu sync.Mutex
+
+	mu      sync.Mutex
+	file    *os.File
+	offset  int64
+	size    int64
+	pending int
+	notify  chan struct{}
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New opens the spool in dir, messages left by a previous run are published again. The spool holds at most
+// maxSize bytes of messages, Publish returns ErrSpoolFull beyond it. Metrics of the spool are labeled with attrs.
+func New(
+	l *zap.SugaredLogger, next pubsub.Publisher, dir string, maxSize int64, attrs ...attribute.KeyValue,
+) (*Spool, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil { //nolint:gomnd
+		return nil, err
+	}
+
+	file, err := os.OpenFile(filepath.Join(dir, logFileName), os.O_RDWR|os.O_CREATE, 0o644) //nolint:gomnd
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Spool{
+		l:       l,
+		next:    next,
+		dir:     dir,
+		maxSize: maxSize,
+		policy:  retry.DefaultPolicy(),
+		attrs:   metric.WithAttributes(attrs...),
+		file:    file,
+		notify:  make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+
+	if err = s.load(); err != nil {
+		_ = file.Close()
+
+		return nil, err
+	}
+
+	if s.pending > 0 {
+		l.Infow("Publish messages left in spool", "numMessages", s.pending, "size", s.size)
+	}
+
+	if err = s.registerMetrics(); err != nil {
+		_ = file.Close()
+
+		return nil, err
+	}
+
+	var ctx context.Context
+	ctx, s.cancel = context.WithCancel(context.Background())
+	go s.run(ctx)
+
+	return s, nil
+}
+
+// load reads the offset of the first message to publish and counts messages after it. A record
+// partially written by a crash is dropped.
+func (s *Spool) load() error {
+	data, err := os.ReadFile(filepath.Join(s.dir, offsetFileName))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return err
+	case len(data) == 8: //nolint:gomnd
+		s.offset = int64(binary.BigEndian.Uint64(data)) //nolint:gosec
+	default:
+		return fmt.Errorf("invalid spool offset file of %d bytes", len(data))
+	}
+
+	info, err := s.file.Stat()
+	if err != nil {
+		return err
+	}
+
+	// The log was truncated once empty, but the offset was not saved.
+	if s.offset > info.Size() {
+		s.offset = 0
+	}
+
+	end := s.offset
+	for end < info.Size() {
+		_, n, err := s.readRecord(end)
+		if err != nil {
+			s.l.Warnw("Drop incomplete record at the end of spool", "offset", end, "error", err)
+
+			break
+		}
+		end += n
+		s.pending++
+	}
+
+	if err = s.file.Truncate(end); err != nil {
+		return err
+	}
+	s.size = end - s.offset
+
+	return nil
+}
+
+func (s *Spool) registerMetrics() error {
+	_, err := pkgmetric.Meter().Int64ObservableGauge(metricNameSpoolDepth,
+		metric.WithDescription("Number of messages in the spool waiting to be published"),
+		metric.WithInt64Callback(func(_ context.Context, obsrv metric.Int64Observer) error {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+
+			obsrv.Observe(int64(s.pending), s.attrs)
+
+			return nil
+		}))
+	if err != nil {
+		return err
+	}
+
+	_, err = pkgmetric.Meter().Int64ObservableGauge(metricNameSpoolBytes,
+		metric.WithDescription("Size of messages in the spool waiting to be published"),
+		metric.WithUnit("By"),
+		metric.WithInt64Callback(func(_ context.Context, obsrv metric.Int64Observer) error {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+
+			obsrv.Observe(s.size, s.attrs)
+
+			return nil
+		}))
+
+	return err
+}
+
+// Publish publishes the message, or spools it if the publisher fails with a transient error
+// or messages are already spooled.
+func (s *Spool) Publish(ctx context.Context, topic string, msg interface{}) error {
+	s.publishMu.Lock()
+	defer s.publishMu.Unlock()
+
+	// Only Publish adds messages to the spool, so it stays empty while the message is published.
+	s.mu.Lock()
+	empty := s.pending == 0
+	s.mu.Unlock()
+
+	if empty {
+		err := s.next.Publish(ctx, topic, msg)
+		if err == nil || !retry.Classify(err).Retryable() {
+			return err
+		}
+
+		s.l.Warnw("Fail to publish message, append it to spool", "topic", topic, "error", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.append(topic, msg)
+}
+
+func (s *Spool) append(topic string, msg interface{}) error {
+	message, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(record{Topic: topic, Message: message})
+	if err != nil {
+		return err
+	}
+
+	n := int64(recordHeaderSize + len(payload))
+	if s.size+n > s.maxSize {
+		return fmt.Errorf("%w: %d bytes of %d messages", ErrSpoolFull, s.size, s.pending)
+	}
+
+	buf := make([]byte, n)
+	binary.BigEndian.PutUint32(buf, uint32(len(payload))) //nolint:gosec
+	binary.BigEndian.PutUint32(buf[4:], crc32.ChecksumIEEE(payload))
+	copy(buf[recordHeaderSize:], payload)
+
+	end := s.offset + s.size
+	if _, err = s.file.WriteAt(buf, end); err != nil {
+		_ = s.file.Truncate(end)
+
+		return err
+	}
+	if err = s.file.Sync(); err != nil {
+		return err
+	}
+
+	s.size += n
+	s.pending++
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// readRecord returns the record at offset and its size in the log.
+func (s *Spool) readRecord(offset int64) (record, int64, error) {
+	header := make([]byte, recordHeaderSize)
+	if _, err := s.file.ReadAt(header, offset); err != nil {
+		return record{}, 0, err
+	}
+
+	payload := make([]byte, binary.BigEndian.Uint32(header))
+	if _, err := s.file.ReadAt(payload, offset+recordHeaderSize); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+
+		return record{}, 0, err
+	}
+
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:]) {
+		return record{}, 0, errors.New("checksum mismatch")
+	}
+
+	var rec record
+	if err := json.Unmarshal(payload, &rec); err != nil {
+		return record{}, 0, err
+	}
+
+	return rec, int64(recordHeaderSize + len(payload)), nil
+}
+
+// run publishes spooled messages in order until ctx is done.
+func (s *Spool) run(ctx context.Context) {
+	defer close(s.done)
+
+	for attempt := 0; ; {
+		s.mu.Lock()
+		pending, offset := s.pending, s.offset
+		s.mu.Unlock()
+
+		if pending == 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.notify:
+			}
+
+			continue
+		}
+
+		rec, n, err := s.readRecord(offset)
+		if err == nil {
+			err = s.next.Publish(ctx, rec.Topic, rec.Message)
+		}
+		if err != nil {
+			delay := s.policy.Backoff(attempt)
+			s.l.Warnw("Fail to publish spooled message", "offset", offset, "delay", delay, "error", err)
+			if retry.Sleep(ctx, delay) != nil {
+				return
+			}
+			attempt++
+
+			continue
+		}
+
+		attempt = 0
+		if err = s.advance(n); err != nil {
+			s.l.Errorw("Fail to save spool offset", "error", err)
+		}
+	}
+}
+
+// advance removes the first message of the spool, the log is truncated once it is empty.
+func (s *Spool) advance(n int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.offset += n
+	s.size -= n
+	s.pending--
+	if s.pending == 0 {
+		s.l.Infow("Published all spooled messages")
+		if err := s.file.Truncate(0); err != nil {
+			return err
+		}
+		s.offset = 0
+	}
+
+	return s.saveOffset()
+}
+
+func (s *Spool) saveOffset() error {
+	buf := make([]byte, 8)                            //nolint:gomnd
+	binary.BigEndian.PutUint64(buf, uint64(s.offset)) //nolint:gosec
+
+	path := filepath.Join(s.dir, offsetFileName)
+	tmp := path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644) //nolint:gomnd
+	if err != nil {
+		return err
+	}
+
+	if _, err = file.Write(buf); err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// Len returns the number of messages waiting to be published.
+func (s *Spool) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.pending
+}
+
+// Close stops publishing spooled messages, they are published again when the spool is opened.
+func (s *Spool) Close() error {
+	s.cancel()
+	<-s.done
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.file.Close()
+}
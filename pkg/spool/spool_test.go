@@ -0,0 +1,190 @@
+package spool
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/KyberNetwork/evmlistener/pkg/errors"
+	"github.com/KyberNetwork/evmlistener/pkg/retry"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+)
+
+type publisherMock struct {
+	mu       sync.Mutex
+	err      error
+	messages []string
+	started  chan struct{}
+	block    chan struct{}
+}
+
+func (p *publisherMock) Publish(_ context.Context, topic string, msg interface{}) error {
+	if p.block != nil {
+		p.started <- struct{}{}
+		<-p.block
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err != nil {
+		return p.err
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	p.messages = append(p.messages, topic+":"+string(data))
+
+	return nil
+}
+
+func (p *publisherMock) setError(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.err = err
+}
+
+func (p *publisherMock) published() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]string(nil), p.messages...)
+}
+
+type SpoolTestSuite struct {
+	suite.Suite
+
+	dir       string
+	publisher *publisherMock
+	spool     *Spool
+}
+
+func (ts *SpoolTestSuite) SetupTest() {
+	ts.dir = ts.T().TempDir()
+	ts.publisher = &publisherMock{}
+	ts.open()
+}
+
+func (ts *SpoolTestSuite) TearDownTest() {
+	ts.Assert().NoError(ts.spool.Close())
+}
+
+func (ts *SpoolTestSuite) open() {
+	var err error
+	ts.spool, err = New(zap.S(), ts.publisher, ts.dir, 1024)
+	ts.Require().NoError(err)
+	ts.spool.policy = retry.Policy{InitialInterval: 10 * time.Millisecond, MaxInterval: 10 * time.Millisecond}
+}
+
+func (ts *SpoolTestSuite) publish(msgs ...int) {
+	for _, msg := range msgs {
+		ts.Require().NoError(ts.spool.Publish(context.Background(), "topic", msg))
+	}
+}
+
+func (ts *SpoolTestSuite) waitPublished(expected ...string) {
+	ts.Require().Eventually(func() bool {
+		return len(ts.publisher.published()) >= len(expected)
+	}, 5*time.Second, 10*time.Millisecond)
+	ts.Assert().Equal(expected, ts.publisher.published())
+}
+
+func (ts *SpoolTestSuite) TestPublish() {
+	ts.publish(1, 2)
+	ts.Assert().Equal([]string{"topic:1", "topic:2"}, ts.publisher.published())
+	ts.Assert().Zero(ts.spool.Len())
+}
+
+func (ts *SpoolTestSuite) TestSpoolInOrder() {
+	ts.publish(1)
+	ts.publisher.setError(syscall.ECONNREFUSED)
+	ts.publish(2, 3)
+	ts.Assert().Equal(2, ts.spool.Len())
+
+	// Messages are spooled while the spool is not empty, even if the publisher is available.
+	ts.publisher.setError(nil)
+	ts.publish(4)
+	ts.waitPublished("topic:1", "topic:2", "topic:3", "topic:4")
+
+	ts.Require().Eventually(func() bool {
+		return ts.spool.Len() == 0
+	}, time.Second, 10*time.Millisecond)
+	info, err := os.Stat(filepath.Join(ts.dir, logFileName))
+	ts.Require().NoError(err)
+	ts.Assert().Zero(info.Size())
+
+	ts.publish(5)
+	ts.Assert().Equal("topic:5", ts.publisher.published()[4])
+}
+
+func (ts *SpoolTestSuite) TestSlowPublish() {
+	ts.publisher.started = make(chan struct{})
+	ts.publisher.block = make(chan struct{})
+	errCh := make(chan error)
+	go func() {
+		errCh <- ts.spool.Publish(context.Background(), "topic", 1)
+	}()
+	<-ts.publisher.started
+
+	// State of the spool is available while a message is being published.
+	lenCh := make(chan int)
+	go func() {
+		lenCh <- ts.spool.Len()
+	}()
+	select {
+	case n := <-lenCh:
+		ts.Assert().Zero(n)
+	case <-time.After(time.Second):
+		ts.Fail("Len is blocked by publishing")
+	}
+
+	close(ts.publisher.block)
+	ts.Require().NoError(<-errCh)
+	ts.Assert().Equal([]string{"topic:1"}, ts.publisher.published())
+}
+
+func (ts *SpoolTestSuite) TestFatalError() {
+	ts.publisher.setError(errors.ErrInvalidArgument)
+	ts.Assert().ErrorIs(ts.spool.Publish(context.Background(), "topic", 1), errors.ErrInvalidArgument)
+	ts.Assert().Zero(ts.spool.Len())
+}
+
+func (ts *SpoolTestSuite) TestFull() {
+	ts.publisher.setError(syscall.ECONNREFUSED)
+	var err error
+	for i := 0; err == nil; i++ {
+		err = ts.spool.Publish(context.Background(), "topic", i)
+	}
+	ts.Assert().ErrorIs(err, ErrSpoolFull)
+	ts.Assert().LessOrEqual(ts.spool.size, int64(1024))
+}
+
+func (ts *SpoolTestSuite) TestReopen() {
+	ts.publisher.setError(syscall.ECONNREFUSED)
+	ts.publish(1, 2, 3)
+	ts.Require().NoError(ts.spool.Close())
+
+	// A record partially written before a crash is dropped.
+	file, err := os.OpenFile(filepath.Join(ts.dir, logFileName), os.O_APPEND|os.O_WRONLY, 0o644)
+	ts.Require().NoError(err)
+	_, err = file.Write([]byte{0, 0, 0, 10, 1, 2})
+	ts.Require().NoError(err)
+	ts.Require().NoError(file.Close())
+
+	ts.publisher.setError(nil)
+	ts.open()
+	ts.waitPublished("topic:1", "topic:2", "topic:3")
+}
+
+func TestSpoolTestSuite(t *testing.T) {
+	suite.Run(t, new(SpoolTestSuite))
+}
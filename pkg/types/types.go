@@ -2,6 +2,8 @@ package types
 
 import (
 	"math/big"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
 )
 
 // Header contains block header information.
@@ -10,6 +12,9 @@ type Header struct {
 	ParentHash string   `json:"parentHash"`
 	Number     *big.Int `json:"number"`
 	Time       uint64   `json:"timestamp"`
+	// LogsBloom and ReceiptsRoot are used for verifying logs of the block, they are empty if unknown.
+	LogsBloom    hexutil.Bytes `json:"logsBloom,omitempty"`
+	ReceiptsRoot string        `json:"receiptsRoot,omitempty"`
 }
 
 // Block contains information of block.
@@ -22,8 +27,19 @@ type Block struct {
 	Logs        []Log    `json:"logs"`
 }
 
+// Resync is sent along with a re-organization deeper than the blocks known to the listener,
+// its common ancestor with the stored chain may be unknown.
+type Resync struct {
+	// FromBlock is the number of the first block of the canonical chain in the message, consumers
+	// should drop data of blocks from it which are not in NewBlocks.
+	FromBlock *big.Int `json:"fromBlock"`
+	// RevertedBlockHashes are hashes of blocks known to the listener which are not in the canonical chain.
+	RevertedBlockHashes []string `json:"revertedBlockHashes"`
+}
+
 // Message ...
 type Message struct {
 	RevertedBlocks []Block `json:"revertedBlocks"`
 	NewBlocks      []Block `json:"newBlocks"`
+	Resync         *Resync `json:"resync,omitempty"`
 }